@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"github.com/bgrewell/usage"
+	"github.com/rstms/iso-kit"
+	"github.com/rstms/iso-kit/pkg/iso9660/scan"
+	"github.com/rstms/iso-kit/pkg/version"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// printCandidates lists the filesystems found in the dump with their validation results.
+func printCandidates(candidates []*scan.Candidate, verbose bool) {
+	fmt.Printf("%-14s  %-14s  %-32s  %s\n", "Offset", "Size", "Volume", "Status")
+	for _, c := range candidates {
+		status := "recoverable"
+		if !c.Recoverable() {
+			status = "damaged"
+		}
+		if c.Truncated {
+			status += ", truncated"
+		}
+		fmt.Printf("%-14d  %-14d  %-32s  %s\n", c.Offset, c.Size(), c.VolumeIdentifier, status)
+		if verbose {
+			fmt.Printf("    logical block size %d, %d volume descriptors\n", c.LogicalBlockSize, c.Descriptors)
+			for _, problem := range c.Problems {
+				fmt.Printf("    %s\n", problem)
+			}
+		}
+	}
+}
+
+// writeImage copies the bytes of a filesystem out of the dump into a standalone image file.
+func writeImage(dump io.ReaderAt, size int64, c *scan.Candidate, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err = io.Copy(f, c.Section(dump, size)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// extractFiles extracts the files of a filesystem in the dump through the ISO9660 parser, reading it from the already
+// opened dump.
+func extractFiles(dump io.ReaderAt, size int64, c *scan.Candidate, dir string) error {
+	sr := c.Section(dump, size)
+	img, err := iso.OpenReader(sr, sr.Size())
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+	return img.Extract(dir)
+}
+
+func main() {
+	// Initialize usage handler
+	u := usage.NewUsage(
+		usage.WithApplicationVersion(version.Version()),
+		usage.WithApplicationBranch(version.Branch()),
+		usage.WithApplicationBuildDate(version.Date()),
+		usage.WithApplicationCommitHash(version.Revision()),
+		usage.WithApplicationName("isocarve"),
+		usage.WithApplicationDescription("isocarve is a command-line tool for finding ISO9660 filesystems embedded in raw disk dumps. Every volume descriptor set found is validated and reported with its offset and size, and recoverable filesystems can be written out as images or have their files extracted."),
+	)
+
+	// Define CLI options
+	help := u.AddBooleanOption("h", "help", false, "Show this help message", "optional", nil)
+	verbose := u.AddBooleanOption("v", "verbose", false, "Print the validation details of each filesystem", "", nil)
+	write := u.AddBooleanOption("w", "write", false, "Write each recoverable filesystem out as an image file", "", nil)
+	extract := u.AddBooleanOption("x", "extract", false, "Extract the files of each recoverable filesystem", "", nil)
+	outputDir := u.AddStringOption("o", "output", "./carved", "Output directory for carved images and files", "", nil)
+
+	// Dump file path argument
+	dumpPath := u.AddArgument(1, "dump-path", "Path to the raw disk dump", "")
+
+	// Parse arguments
+	parsed := u.Parse()
+	if !parsed {
+		u.PrintError(fmt.Errorf("failed to parse arguments"))
+		os.Exit(1)
+	}
+
+	// Handle help flag
+	if *help {
+		u.PrintUsage()
+		os.Exit(0)
+	}
+
+	// Ensure a dump path was provided
+	if dumpPath == nil || *dumpPath == "" {
+		u.PrintError(fmt.Errorf("path to the disk dump must be provided"))
+		os.Exit(1)
+	}
+
+	dump, err := os.Open(*dumpPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open dump: %v\n", err)
+		os.Exit(1)
+	}
+	defer dump.Close()
+	fileInfo, err := dump.Stat()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to stat dump: %v\n", err)
+		os.Exit(1)
+	}
+
+	candidates, err := scan.Scan(dump, fileInfo.Size())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to scan dump: %v\n", err)
+		os.Exit(1)
+	}
+	if len(candidates) == 0 {
+		fmt.Println("No ISO9660 filesystems found")
+		return
+	}
+	printCandidates(candidates, *verbose)
+
+	if !*write && !*extract {
+		return
+	}
+	if err = os.MkdirAll(*outputDir, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create output directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, c := range candidates {
+		if !c.Recoverable() {
+			continue
+		}
+		name := fmt.Sprintf("%012x", c.Offset)
+		if volume := strings.TrimSpace(c.VolumeIdentifier); volume != "" {
+			name += "-" + strings.ReplaceAll(volume, string(filepath.Separator), "_")
+		}
+
+		if *write {
+			path := filepath.Join(*outputDir, name+".iso")
+			if err = writeImage(dump, fileInfo.Size(), c, path); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write image at offset %d: %v\n", c.Offset, err)
+				failed = true
+			} else {
+				fmt.Printf("Wrote %s\n", path)
+			}
+		}
+
+		if *extract {
+			dir := filepath.Join(*outputDir, name)
+			if err = extractFiles(dump, fileInfo.Size(), c, dir); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to extract files at offset %d: %v\n", c.Offset, err)
+				failed = true
+			} else {
+				fmt.Printf("Extracted %s\n", dir)
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
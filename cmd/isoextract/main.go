@@ -3,12 +3,15 @@ package main
 import (
 	"fmt"
 	"github.com/rstms/iso-kit"
+	"github.com/rstms/iso-kit/pkg/cdimage"
 	"github.com/rstms/iso-kit/pkg/option"
+	"github.com/rstms/iso-kit/pkg/remote"
 	"github.com/rstms/iso-kit/pkg/version"
 	"github.com/bgrewell/usage"
 	"github.com/theckman/yacspin"
 	"golang.org/x/term"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -90,6 +93,47 @@ func InitializeSpinner() (*yacspin.Spinner, error) {
 	return spinner, nil
 }
 
+// extractEntry extracts a file, or every file below a directory, of an image to outputDir and returns the number of
+// files extracted.
+func extractEntry(img iso.ISO, path string, outputDir string) (int, error) {
+	entry, err := iso.FindEntry(img, path)
+	if err != nil {
+		return 0, err
+	}
+	if !entry.IsDir {
+		return 1, entry.ExtractToDisk(outputDir)
+	}
+
+	files, err := img.ListFiles()
+	if err != nil {
+		return 0, err
+	}
+	prefix := strings.TrimSuffix(entry.FullPath, "/") + "/"
+	count := 0
+	for _, file := range files {
+		if !strings.HasPrefix(file.FullPath, prefix) {
+			continue
+		}
+		if err = file.ExtractToDisk(outputDir); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// printTracks lists the tracks of a CD image with their INDEX and pregap timing.
+func printTracks(cd *cdimage.Image) {
+	for _, track := range cd.Tracks {
+		fmt.Printf("Track %02d  %-10s  Start: %s  Length: %s  Pregap: %s\n",
+			track.Number, track.Mode, cdimage.FramesToMSF(track.Start), cdimage.FramesToMSF(track.Length),
+			cdimage.FramesToMSF(track.PregapLength()))
+		for _, index := range track.Indexes {
+			fmt.Printf("    INDEX %02d %s\n", index.Number, cdimage.FramesToMSF(index.Position))
+		}
+	}
+}
+
 func main() {
 	// Initialize usage handler
 	u := usage.NewUsage(
@@ -109,13 +153,15 @@ func main() {
 	rockRidge := u.AddBooleanOption("rr", "rockridge", true, "Enable Rock Ridge support", "", nil)
 	enhancedVol := u.AddBooleanOption("eh", "enhanced", true, "Use Enhanced Volume Descriptors", "", nil)
 	stripVer := u.AddBooleanOption("s", "strip", true, "Strip version info from filenames", "", nil)
+	audio := u.AddBooleanOption("a", "audio", false, "Extract CD-DA audio tracks of BIN/CUE images as WAV files", "", nil)
+	listTracks := u.AddBooleanOption("t", "tracks", false, "List the tracks of BIN/CUE images and exit", "", nil)
 
 	// Output directories
 	outputDir := u.AddStringOption("o", "output", "./extracted", "Output directory for extracted files", "", nil)
 	bootDir := u.AddStringOption("bd", "bootdir", "[BOOT]", "Output directory for boot images", "", nil)
 
 	// ISO file path argument
-	isoPath := u.AddArgument(1, "iso-path", "Path to the ISO file", "")
+	isoPath := u.AddArgument(1, "iso-path", "Path or http(s) URL of the ISO file, files in nested images are addressed as outer.iso!/inner.iso!/file", "")
 
 	// Parse arguments
 	parsed := u.Parse()
@@ -143,6 +189,42 @@ func main() {
 		fmt.Println("Verbose logging enabled")
 	}
 
+	// Audio tracks are only visible in the track layout of CD images
+	extractFiles := true
+	if (*audio || *listTracks) && remote.IsURL(*isoPath) {
+		fmt.Fprintf(os.Stderr, "Audio tracks can only be read from local CD images\n")
+		os.Exit(1)
+	}
+	if *audio || *listTracks {
+		cd, err := cdimage.Open(*isoPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open CD image: %v\n", err)
+			os.Exit(1)
+		}
+
+		if *listTracks {
+			printTracks(cd)
+			cd.Close()
+			os.Exit(0)
+		}
+
+		paths, err := cd.ExtractAudio(*outputDir)
+		if err != nil {
+			cd.Close()
+			fmt.Fprintf(os.Stderr, "Failed to extract audio tracks: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Extracted %d audio tracks to %s\n", len(paths), *outputDir)
+
+		// Pure audio discs have no filesystem to extract
+		_, err = cd.DataTrack()
+		extractFiles = err == nil
+		cd.Close()
+	}
+	if !extractFiles {
+		return
+	}
+
 	// Setup callback for progress updates
 	spinner, err := InitializeSpinner()
 	if err != nil {
@@ -153,8 +235,9 @@ func main() {
 	// Create progress callback
 	progressCallback := CreateProgressCallback(spinner)
 
-	// Open the ISO image with the specified flags
-	img, err := iso.Open(
+	// Open the ISO image with the specified flags. Images on web servers are read with range requests and nested
+	// images are addressed as outer.iso!/images/inner.iso!/file.
+	img, innerPath, err := iso.OpenNested(
 		*isoPath,
 		option.WithElToritoEnabled(*bootImages),
 		option.WithRockRidgeEnabled(*rockRidge),
@@ -165,11 +248,25 @@ func main() {
 		option.WithExtractionProgress(progressCallback),
 	)
 	if err != nil {
+		spinner.StopFail()
 		fmt.Fprintf(os.Stderr, "Failed to open ISO: %v\n", err)
 		os.Exit(1)
 	}
 	defer img.Close()
 
+	// A path inside the image selects the file or directory to extract
+	if strings.Trim(innerPath, "/") != "" {
+		count, err := extractEntry(img, innerPath, *outputDir)
+		if err != nil {
+			spinner.StopFailMessage(fmt.Sprintf("Failed to extract %s: %v", innerPath, err))
+			spinner.StopFail()
+			os.Exit(1)
+		}
+		spinner.StopMessage(fmt.Sprintf(" Extracted %d files to %s", count, *outputDir))
+		spinner.Stop()
+		return
+	}
+
 	// Extract the contents
 	running := true
 	go func() {
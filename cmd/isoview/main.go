@@ -3,9 +3,11 @@ package main
 import (
 	"fmt"
 	"github.com/rstms/iso-kit"
+	"github.com/rstms/iso-kit/pkg/filesystem"
 	"github.com/rstms/iso-kit/pkg/version"
 	"github.com/bgrewell/usage"
 	"os"
+	"strings"
 )
 
 // DisplayISOInfo prints general information about the ISO file.
@@ -45,7 +47,10 @@ func DisplayISOInfo(i iso.ISO, verbose bool) {
 	// Counters
 	rrEnabled := 0
 	symlinks := 0
+	compressed := 0
 	totalSize := uint64(0)
+	storedSize := uint64(0)
+	var interleaved, form2 []*filesystem.FileSystemEntry
 
 	// Get file system entries
 	files, err := i.ListFiles()
@@ -65,8 +70,18 @@ func DisplayISOInfo(i iso.ISO, verbose bool) {
 		if entry.DirectoryRecord().RockRidge != nil && entry.DirectoryRecord().RockRidge.SymlinkTarget != nil {
 			symlinks++
 		}
+		if entry.Zisofs != nil {
+			compressed++
+		}
+		if record := entry.DirectoryRecord(); record != nil && record.IsInterleaved() {
+			interleaved = append(interleaved, entry)
+		}
+		if entry.XA != nil && entry.XA.IsForm2() {
+			form2 = append(form2, entry)
+		}
 		if !entry.IsDir {
 			totalSize += uint64(entry.Size)
+			storedSize += uint64(entry.StoredSize)
 		}
 	}
 
@@ -89,27 +104,59 @@ func DisplayISOInfo(i iso.ISO, verbose bool) {
 	fmt.Printf("Total Files: %d\n", len(files))
 	fmt.Printf("Total Directories: %d\n", len(dirs))
 	fmt.Printf("Total Size: %d bytes (%.2f MB)\n", totalSize, float64(totalSize)/1024/1024)
+	if compressed > 0 {
+		fmt.Printf("Stored Size: %d bytes (%.2f MB)\n", storedSize, float64(storedSize)/1024/1024)
+	}
+	if len(interleaved) > 0 {
+		fmt.Printf("Interleaved Files: %d\n", len(interleaved))
+	}
+	if len(form2) > 0 {
+		fmt.Printf("Mode 2 Form 2 Files: %d\n", len(form2))
+	}
 
 	if verbose {
 		// Verbose output with additional metadata
 		fmt.Println("\n=== Verbose Information ===")
 		fmt.Printf("System Identifier: %s\n", i.GetSystemID())
-		fmt.Printf("Volume Set Size: %d\n", -1)
-		fmt.Printf("Volume Sequence Number: %d\n", -1)
+		fmt.Printf("Volume Set Size: %d\n", i.GetVolumeSetSize())
+		fmt.Printf("Volume Sequence Number: %d\n", i.GetVolumeSequenceNumber())
 		fmt.Printf("Logical Block Size: %d bytes\n", -1)
 		fmt.Printf("Number of Hard Links: %d\n", -1)
 		fmt.Printf("Symbolic Links: %d\n", symlinks)
+		fmt.Printf("Compressed Files (zisofs): %d\n", compressed)
 		fmt.Printf("Root Directory Location: %d (LBA)\n", i.RootDirectoryLocation())
 
 		// Rock Ridge Support
 		if i.HasRockRidge() {
 			fmt.Println("\n--- Rock Ridge Extensions ---")
 			fmt.Println("Rock Ridge Enabled: YES")
+			fmt.Printf("  Rock Ridge Version: %s\n", i.RockRidgeVersion())
 			fmt.Printf("  Number of Entries with Extended Attributes: %d\n", rrEnabled)
 		} else {
 			fmt.Println("\nRock Ridge Extensions: NOT PRESENT")
 		}
 
+		// Files recorded in interleaved mode
+		if len(interleaved) > 0 {
+			fmt.Println("\n--- Interleaved Files ---")
+			for _, entry := range interleaved {
+				record := entry.DirectoryRecord()
+				fmt.Printf("  %s (file unit %d, gap %d blocks)\n", entry.FullPath, record.FileUnitSize, record.InterleaveGapSize)
+			}
+		}
+
+		// CD-ROM XA files recorded in Form 2 sectors
+		if len(form2) > 0 {
+			fmt.Println("\n--- Mode 2 Form 2 Files ---")
+			for _, entry := range form2 {
+				fmt.Printf("  %s (file number %d, attributes 0x%04X)\n", entry.FullPath, entry.XA.FileNumber, entry.XA.Attributes)
+			}
+		}
+
+		if i.HasEnhanced() {
+			fmt.Println("\nISO 9660:1999 Enhanced Volume Descriptor: PRESENT")
+		}
+
 		// El Torito Boot Support
 		if i.HasElTorito() {
 			fmt.Println("\n--- El Torito Boot Extensions ---")
@@ -149,7 +196,7 @@ func main() {
 
 	help := u.AddBooleanOption("h", "help", false, "Show this help message", "optional", nil)
 	verbose := u.AddBooleanOption("v", "verbose", false, "Print verbose output", "", nil)
-	path := u.AddArgument(1, "iso-path", "Path to the file within the ISO to read", "")
+	path := u.AddArgument(1, "iso-path", "Path or http(s) URL of the ISO file, nested images are addressed as outer.iso!/inner.iso", "")
 	parsed := u.Parse()
 
 	if !parsed {
@@ -169,7 +216,12 @@ func main() {
 
 	_ = verbose
 
-	i, err := iso.Open(*path)
+	// A path ending inside an image, like outer.iso!/images/inner.iso, names a nested image to inspect. Images on web
+	// servers are read with range requests instead of being downloaded.
+	if parts := iso.SplitNestedPath(*path); len(parts) > 1 && strings.Trim(parts[len(parts)-1], "/") != "" {
+		*path += iso.NESTED_SEPARATOR
+	}
+	i, _, err := iso.OpenNested(*path)
 	if err != nil {
 		u.PrintError(err)
 		os.Exit(1)
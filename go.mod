@@ -6,8 +6,11 @@ require (
 	github.com/bgrewell/usage v0.0.0-20250206192743-f8477581f61e
 	github.com/fatih/color v1.18.0
 	github.com/go-logr/logr v1.4.3
+	github.com/klauspost/compress v1.18.0
 	github.com/stretchr/testify v1.11.1
 	github.com/theckman/yacspin v0.13.12
+	github.com/ulikunitz/xz v0.5.15
+	golang.org/x/sys v0.36.0
 	golang.org/x/term v0.35.0
 )
 
@@ -18,6 +21,5 @@ require (
 	github.com/mattn/go-runewidth v0.0.13 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	golang.org/x/sys v0.36.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
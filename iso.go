@@ -2,15 +2,22 @@ package iso
 
 import (
 	"errors"
+	"fmt"
+	"github.com/rstms/iso-kit/pkg/cdimage"
+	"github.com/rstms/iso-kit/pkg/compressed"
 	"github.com/rstms/iso-kit/pkg/consts"
 	"github.com/rstms/iso-kit/pkg/filesystem"
 	"github.com/rstms/iso-kit/pkg/iso9660"
 	"github.com/rstms/iso-kit/pkg/iso9660/info"
+	"github.com/rstms/iso-kit/pkg/iso9660/scan"
 	"github.com/rstms/iso-kit/pkg/logging"
 	"github.com/rstms/iso-kit/pkg/option"
+	"github.com/rstms/iso-kit/pkg/remote"
 	"github.com/rstms/iso-kit/pkg/udf"
 	"io"
 	"os"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -31,19 +38,26 @@ type ISO interface {
 	GetEffectiveDateTime() time.Time
 
 	GetVolumeSize() uint32
+	GetVolumeSetSize() uint16
+	GetVolumeSequenceNumber() uint16
 	RootDirectoryLocation() uint32
 
 	ListBootEntries() ([]*filesystem.FileSystemEntry, error)
 	ListFiles() ([]*filesystem.FileSystemEntry, error)
 	ListDirectories() ([]*filesystem.FileSystemEntry, error)
 	ReadFile(path string) ([]byte, error)
+	OpenFile(path string) (io.ReadSeekCloser, error)
+	FS() (*filesystem.FS, error)
 	AddFile(path string, data []byte) error
 	RemoveFile(path string) error
 	CreateDirectories(path string) error
+	AddPartition(systemID, partitionID string, location, size uint32, systemUse []byte) error
 	Extract(path string) error
 
 	HasJoliet() bool
+	HasEnhanced() bool
 	HasRockRidge() bool
+	RockRidgeVersion() string
 	HasElTorito() bool
 
 	SetLogger(*logging.Logger)
@@ -56,53 +70,407 @@ type ISO interface {
 }
 
 func Open(filename string, opts ...option.OpenOption) (ISO, error) {
+	openOptions := &option.OpenOptions{}
+	for _, opt := range opts {
+		opt(openOptions)
+	}
 
-	// Get file info
-	fileInfo, err := os.Stat(filename)
+	// CD image containers and raw CD images are opened through the user data of their data track
+	if cdimage.Detect(filename) != cdimage.FORMAT_NONE {
+		return openCDImage(filename, openOptions, opts...)
+	}
+
+	r, size, err := openReader(filename)
 	if err != nil {
 		return nil, err
 	}
+	return openImageReader(r, size, filename, openOptions, opts...)
+}
+
+// OpenURL opens an image served over HTTP or HTTPS without downloading it. The server must support Range requests,
+// the parts of the image that are read are fetched in blocks that are cached, see option.WithHTTPBlockSize.
+func OpenURL(url string, opts ...option.OpenOption) (ISO, error) {
+	openOptions := &option.OpenOptions{HTTPReadAhead: remote.DEFAULT_READ_AHEAD}
+	for _, opt := range opts {
+		opt(openOptions)
+	}
 
-	// Open file
-	f, err := os.Open(filename)
+	hr, err := remote.NewHTTPReader(url, openOptions.HTTPClient, openOptions.HTTPBlockSize, openOptions.HTTPReadAhead, openOptions.HTTPCacheBlocks)
 	if err != nil {
 		return nil, err
 	}
+	r, size, err := newImageReader(hr, hr.Size(), hr, url)
+	if err != nil {
+		return nil, err
+	}
+	return openImageReader(r, size, url, openOptions, opts...)
+}
 
-	// Check if file is large enough to be a valid ISO
-	if fileInfo.Size() < 16*consts.ISO9660_SECTOR_SIZE {
+// OpenReader opens an image read from r, such as an image held in memory, stored in an embed.FS or contained in another
+// archive, with the same detection as Open. size is the size of the image in bytes. Closing the image only closes what
+// was opened to read it, r and any volumes given with option.WithVolumeSet are left open. CD image containers and raw
+// CD images are not detected, their data tracks are only read when opened with Open.
+func OpenReader(r io.ReaderAt, size int64, opts ...option.OpenOption) (ISO, error) {
+	openOptions := &option.OpenOptions{}
+	for _, opt := range opts {
+		opt(openOptions)
+	}
+
+	// The volumes of the caller are read through readers that do not close them
+	if len(openOptions.VolumeSet) > 0 {
+		volumes := make([]io.ReaderAt, len(openOptions.VolumeSet))
+		for i, volume := range openOptions.VolumeSet {
+			volumes[i] = &imageReader{ReaderAt: volume}
+		}
+		opts = append(slices.Clip(opts), func(o *option.OpenOptions) {
+			o.VolumeSet = volumes
+		})
+	}
+
+	ir, size, err := newImageReader(r, size, nil, "image")
+	if err != nil {
+		return nil, err
+	}
+	return openImageReader(ir, size, "image", openOptions, opts...)
+}
+
+// NESTED_SEPARATOR separates the path of an image from the path of a file inside it when it is followed by a slash or
+// ends the path, as in "outer.iso!/images/inner.iso!/file".
+const NESTED_SEPARATOR = "!"
+
+// OpenEntry opens an image stored as a file in another image. The containing image must stay open while the nested
+// image is used, closing the nested image leaves it open.
+func OpenEntry(entry *filesystem.FileSystemEntry, opts ...option.OpenOption) (ISO, error) {
+	sr, err := entry.SectionReader()
+	if err != nil {
+		return nil, err
+	}
+	return OpenReader(sr, sr.Size(), opts...)
+}
+
+// OpenNested opens the innermost image of a path of nested images such as "outer.iso!/images/inner.iso!/file". The
+// first component is the file path or URL of the outer image and every following component that ends with
+// NESTED_SEPARATOR is an image opened from the image before it. The innermost image is returned along with the path
+// after the last separator, which is empty when the path ends with an image. Closing the returned image closes the
+// images containing it.
+func OpenNested(path string, opts ...option.OpenOption) (ISO, string, error) {
+	parts := SplitNestedPath(path)
+
+	open := Open
+	if remote.IsURL(parts[0]) {
+		open = OpenURL
+	}
+	img, err := open(parts[0], opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(parts) == 1 {
+		return img, "", nil
+	}
+
+	for _, part := range parts[1 : len(parts)-1] {
+		entry, err := FindEntry(img, part)
+		if err != nil {
+			img.Close()
+			return nil, "", err
+		}
+		inner, err := openNestedEntry(img, entry, opts...)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open %s: %w", part, err)
+		}
+		img = inner
+	}
+	return img, parts[len(parts)-1], nil
+}
+
+// SplitNestedPath splits a path of nested images at every NESTED_SEPARATOR that is followed by a slash or ends the
+// path. A path without separators is returned as its only component.
+func SplitNestedPath(path string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(path); i++ {
+		if !strings.HasPrefix(path[i:], NESTED_SEPARATOR) {
+			continue
+		}
+		next := i + len(NESTED_SEPARATOR)
+		if next == len(path) || path[next] == '/' {
+			parts = append(parts, path[start:i])
+			start = next
+		}
+	}
+	if start == 0 {
+		return []string{path}
+	}
+	return append(parts, path[start:])
+}
+
+// FindEntry returns the file or directory of an image at the given path, with or without a leading slash.
+func FindEntry(img ISO, path string) (*filesystem.FileSystemEntry, error) {
+	path = strings.Trim(path, "/")
+	files, err := img.ListFiles()
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := img.ListDirectories()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range append(files, dirs...) {
+		if strings.Trim(entry.FullPath, "/") == path {
+			return entry, nil
+		}
+	}
+	return nil, fmt.Errorf("file not found: %s", path)
+}
+
+// openNestedEntry opens an image stored as a file in parent, closing the nested image closes parent. parent is closed
+// if the image cannot be opened.
+func openNestedEntry(parent ISO, entry *filesystem.FileSystemEntry, opts ...option.OpenOption) (ISO, error) {
+	sr, err := entry.SectionReader()
+	if err != nil {
+		parent.Close()
+		return nil, err
+	}
+	openOptions := &option.OpenOptions{}
+	for _, opt := range opts {
+		opt(openOptions)
+	}
+	// The base offset applies to the outer image only
+	openOptions.BaseOffset = 0
+	r, size, err := newImageReader(sr, sr.Size(), parent, entry.FullPath)
+	if err != nil {
+		return nil, err
+	}
+	return openImageReader(r, size, entry.FullPath, openOptions, opts...)
+}
+
+// openImageReader opens the filesystem of an image at the base offset, scanning for it when requested. The reader is
+// closed if no filesystem can be opened.
+func openImageReader(r *imageReader, size int64, name string, openOptions *option.OpenOptions, opts ...option.OpenOption) (ISO, error) {
+	// The filesystem may be embedded in a larger image, e.g. a disk image partition
+	if openOptions.BaseOffset < 0 || openOptions.BaseOffset >= size {
+		r.Close()
+		return nil, fmt.Errorf("base offset %d is outside of %s", openOptions.BaseOffset, name)
+	}
+	if openOptions.BaseOffset > 0 {
+		r, size = r.section(openOptions.BaseOffset, size-openOptions.BaseOffset), size-openOptions.BaseOffset
+	}
+
+	img, err := openImage(r, size, opts...)
+	if err != nil && openOptions.AutoScan {
+		img, err = openScanned(r, size, openOptions, opts...)
+	}
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return img, nil
+}
+
+// OpenAt opens an image whose filesystem starts at the given byte offset, such as an ISO9660 filesystem in a disk image
+// partition or following a firmware header.
+func OpenAt(filename string, offset int64, opts ...option.OpenOption) (ISO, error) {
+	return Open(filename, append(slices.Clip(opts), option.WithBaseOffset(offset))...)
+}
+
+// Scan searches an image for ISO9660 filesystems at 512 byte boundaries and returns every candidate found. Compressed
+// images are searched in their decompressed contents.
+func Scan(filename string) ([]*scan.Candidate, error) {
+	r, size, err := openReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return scan.Scan(r, size)
+}
+
+// openScanned opens the first filesystem found by scanning the image.
+func openScanned(r *imageReader, size int64, openOptions *option.OpenOptions, opts ...option.OpenOption) (ISO, error) {
+	candidates, err := scan.Scan(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan image: %w", err)
+	}
+	if len(candidates) == 0 {
+		return nil, errors.New("no ISO9660 filesystem found")
+	}
+	if openOptions.Logger != nil {
+		for _, candidate := range candidates {
+			openOptions.Logger.Info("Found ISO9660 filesystem", "offset", candidate.Offset, "volume", candidate.VolumeIdentifier, "size", candidate.Size(), "recoverable", candidate.Recoverable())
+		}
+	}
+
+	// Prefer the first filesystem that passed validation
+	candidate := candidates[0]
+	for _, c := range candidates {
+		if c.Recoverable() {
+			candidate = c
+			break
+		}
+	}
+	return openImage(r.section(candidate.Offset, size-candidate.Offset), size-candidate.Offset, opts...)
+}
+
+// imageReader is the reader over an image, closing it closes the file or connection and any decompressor reading from
+// it.
+type imageReader struct {
+	io.ReaderAt
+	closers []io.Closer
+}
+
+// section returns a reader over part of the image sharing its closers.
+func (r *imageReader) section(offset, size int64) *imageReader {
+	return &imageReader{ReaderAt: io.NewSectionReader(r.ReaderAt, offset, size), closers: r.closers}
+}
+
+// Form2Reader returns a reader over the Form 2 payloads of sectors of a CD image data track read from its start, nil
+// when the image is not a CD image or is read at an offset, see filesystem.FileSystemEntry.Form2Reader.
+func (r *imageReader) Form2Reader(start, count int64) *cdimage.Form2Reader {
+	if dr, ok := r.ReaderAt.(*cdimage.DataReader); ok {
+		return dr.Form2Reader(start, count)
+	}
+	return nil
+}
+
+func (r *imageReader) Close() error {
+	var errs []error
+	for _, c := range r.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// openReader opens an image file and returns a reader over its contents and their size. Compressed images are read
+// through a random access decompressor.
+func openReader(filename string) (*imageReader, int64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, err
+	}
+	fileInfo, err := f.Stat()
+	if err != nil {
 		f.Close()
+		return nil, 0, err
+	}
+	return newImageReader(f, fileInfo.Size(), f, filename)
+}
+
+// newImageReader returns a reader over the contents of an image of the given size read from r, closing it closes c
+// unless it is nil. Compressed images are read through a random access decompressor. c is closed if the image cannot be
+// read.
+func newImageReader(r io.ReaderAt, size int64, c io.Closer, name string) (*imageReader, int64, error) {
+	var closers []io.Closer
+	if c != nil {
+		closers = append(closers, c)
+	}
+
+	// Compressed images are opened through a random access reader over their decompressed contents
+	if format := compressed.Detect(r, size); format != compressed.FORMAT_NONE {
+		cr, err := compressed.NewReader(r, size, format)
+		if err != nil {
+			for _, c := range closers {
+				c.Close()
+			}
+			return nil, 0, fmt.Errorf("failed to open %s image %s: %w", format, name, err)
+		}
+		return &imageReader{ReaderAt: cr, closers: append([]io.Closer{cr}, closers...)}, cr.Size(), nil
+	}
+
+	return &imageReader{ReaderAt: r, closers: closers}, size, nil
+}
+
+// openImage detects the filesystem of an image of the given size and opens it.
+func openImage(r io.ReaderAt, size int64, opts ...option.OpenOption) (ISO, error) {
+	// Check if file is large enough to be a valid ISO
+	if size < 16*consts.ISO9660_SECTOR_SIZE {
 		return nil, errors.New("file is too small to be a valid ISO9660 ISO")
 	}
 
 	// Read PVD header at sector 16 (offset 32768)
 	var header [6]byte
-	if _, err = f.ReadAt(header[:], 16*consts.ISO9660_SECTOR_SIZE); err != nil {
-		f.Close()
+	if _, err := r.ReadAt(header[:], 16*consts.ISO9660_SECTOR_SIZE); err != nil {
 		return nil, err
 	}
 
 	// Detect ISO9660
 	if string(header[1:6]) == consts.ISO9660_STD_IDENTIFIER {
-		return iso9660.Open(f, opts...)
+		img, err := iso9660.Open(r, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return img, nil
 	}
 
 	// Check if file is large enough to be a valid UDF ISO
-	if fileInfo.Size() < 256*consts.UDF_SECTOR_SIZE {
-		f.Close()
+	if size < 256*consts.UDF_SECTOR_SIZE {
 		return nil, errors.New("file is too small to be a valid ISO9660 or UDF ISO")
 	}
 
 	// Read UDF anchor volume descriptor at sector 256 (offset 524288)
-	if _, err = f.ReadAt(header[:], 256*consts.UDF_SECTOR_SIZE); err == nil {
+	if _, err := r.ReadAt(header[:], 256*consts.UDF_SECTOR_SIZE); err == nil {
 		if string(header[1:5]) == consts.UDF_STD_IDENTIFIER {
-			return udf.Open(f, opts...)
+			img, err := udf.Open(r, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return img, nil
 		}
 	}
 
 	return nil, errors.New("unsupported ISO format")
 }
 
+// openCDImage opens the filesystem of the data track of a CD image container or raw CD image. The user data of the
+// track is opened like an image file, so base offsets, scanning and compressed or UDF contents apply to it. Containers
+// reference the files holding their tracks by name, so CD images can only be opened from the filesystem.
+func openCDImage(filename string, openOptions *option.OpenOptions, opts ...option.OpenOption) (ISO, error) {
+	img, err := cdimage.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := img.DataReader()
+	if err != nil {
+		img.Close()
+		return nil, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+
+	r, size, err := newImageReader(reader, reader.Size(), reader, filename)
+	if err != nil {
+		return nil, err
+	}
+	iso, err := openImageReader(r, size, filename, openOptions, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data track %d of %s: %w", reader.Track().Number, filename, err)
+	}
+	return iso, nil
+}
+
+// OpenVolumeSet opens the image files of a multi-volume ISO9660 volume set, given in any order.
+func OpenVolumeSet(filenames []string, opts ...option.OpenOption) (ISO, error) {
+	var volumes []io.ReaderAt
+	closeAll := func() {
+		for _, volume := range volumes {
+			volume.(*os.File).Close()
+		}
+	}
+
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		volumes = append(volumes, f)
+	}
+
+	i, err := iso9660.OpenVolumeSet(volumes, opts...)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	return i, nil
+}
+
 func Create(name string, opts ...option.CreateOption) (ISO, error) {
 	// Set default option(s)
 	options := option.CreateOptions{
@@ -0,0 +1,206 @@
+package iso
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rstms/iso-kit/pkg/compressed"
+	"github.com/rstms/iso-kit/pkg/iso9660"
+	"github.com/rstms/iso-kit/pkg/option"
+	"github.com/stretchr/testify/require"
+)
+
+// createImage creates an image holding the given files and returns its saved bytes.
+func createImage(t *testing.T, files map[string][]byte, opts ...option.CreateOption) []byte {
+	img, err := Create("TEST", append([]option.CreateOption{option.WithCreateRockRidgeEnabled(true)}, opts...)...)
+	require.NoError(t, err)
+	for name, data := range files {
+		require.NoError(t, img.AddFile(name, data))
+	}
+
+	path := filepath.Join(t.TempDir(), "image.iso")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, img.Save(file))
+	require.NoError(t, file.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+func TestCreateCompressed_OpenReader(t *testing.T) {
+	files := map[string][]byte{
+		"readme.txt":   []byte("compressed image"),
+		"data/big.bin": bytes.Repeat([]byte("0123456789abcdef"), 20000),
+	}
+	for _, format := range []compressed.Format{compressed.FORMAT_CSO, compressed.FORMAT_ZSTD} {
+		data := createImage(t, files, option.WithCompression(format))
+		require.Equal(t, format, compressed.Detect(bytes.NewReader(data), int64(len(data))))
+
+		img, err := OpenReader(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		for name, want := range files {
+			got, err := img.ReadFile(name)
+			require.NoError(t, err)
+			require.Equal(t, want, got, "%s %s", format, name)
+		}
+		require.NoError(t, img.Close())
+	}
+}
+
+func TestOpenCDImage_Options(t *testing.T) {
+	files := map[string][]byte{"readme.txt": []byte("embedded in a data track")}
+	data := createImage(t, files)
+
+	// The filesystem follows a 64 KiB header in the data track
+	dir := t.TempDir()
+	header := make([]byte, 32*2048)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "disc.bin"), append(header, data...), 0o644))
+	cue := filepath.Join(dir, "disc.cue")
+	require.NoError(t, os.WriteFile(cue, []byte("FILE \"disc.bin\" BINARY\n  TRACK 01 MODE1/2048\n    INDEX 01 00:00:00\n"), 0o644))
+
+	_, err := Open(cue)
+	require.Error(t, err)
+
+	for _, opt := range []option.OpenOption{option.WithBaseOffset(int64(len(header))), option.WithAutoScan(true)} {
+		img, err := Open(cue, opt)
+		require.NoError(t, err)
+		got, err := img.ReadFile("readme.txt")
+		require.NoError(t, err)
+		require.Equal(t, files["readme.txt"], got)
+		require.NoError(t, img.Close())
+	}
+}
+
+// closeRecorder is an in-memory image that counts how often it is closed.
+type closeRecorder struct {
+	*bytes.Reader
+	closed int
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed++
+	return nil
+}
+
+func TestOpenReader(t *testing.T) {
+	files := map[string][]byte{"readme.txt": []byte("read from memory")}
+	data := createImage(t, files)
+
+	r := &closeRecorder{Reader: bytes.NewReader(data)}
+	img, err := OpenReader(r, r.Size())
+	require.NoError(t, err)
+	got, err := img.ReadFile("readme.txt")
+	require.NoError(t, err)
+	require.Equal(t, files["readme.txt"], got)
+	require.NoError(t, img.Close())
+	require.Zero(t, r.closed)
+
+	_, err = OpenReader(bytes.NewReader(data[:1024]), 1024)
+	require.Error(t, err)
+}
+
+func TestOpenReader_VolumeSet(t *testing.T) {
+	created, err := Create("VOLUMES", option.WithCreateRockRidgeEnabled(true), option.WithMaxVolumeSize(64*2048))
+	require.NoError(t, err)
+	files := make(map[string][]byte)
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprintf("file%d.bin", i)
+		files[name] = bytes.Repeat([]byte{byte('a' + i)}, 20*2048)
+		require.NoError(t, created.AddFile(name, files[name]))
+	}
+	require.Greater(t, created.GetVolumeSetSize(), uint16(1))
+
+	dir := t.TempDir()
+	var writers []io.WriterAt
+	var paths []string
+	for i := 0; i < int(created.GetVolumeSetSize()); i++ {
+		paths = append(paths, filepath.Join(dir, fmt.Sprintf("volume%d.iso", i+1)))
+		file, err := os.Create(paths[i])
+		require.NoError(t, err)
+		defer file.Close()
+		writers = append(writers, file)
+	}
+	require.NoError(t, created.(*iso9660.ISO9660).SaveVolumeSet(writers))
+
+	var volumes []*closeRecorder
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		volumes = append(volumes, &closeRecorder{Reader: bytes.NewReader(data)})
+	}
+
+	// The last volume records the hierarchy, the others are given as the rest of the set
+	last := volumes[len(volumes)-1]
+	var others []io.ReaderAt
+	for _, volume := range volumes[:len(volumes)-1] {
+		others = append(others, volume)
+	}
+	img, err := OpenReader(last, last.Size(), option.WithVolumeSet(others...))
+	require.NoError(t, err)
+	for name, want := range files {
+		got, err := img.ReadFile(name)
+		require.NoError(t, err)
+		require.Equal(t, want, got, name)
+	}
+	require.NoError(t, img.Close())
+	for i, volume := range volumes {
+		require.Zero(t, volume.closed, "volume %d", i+1)
+	}
+}
+
+func TestSplitNestedPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"image.iso", []string{"image.iso"}},
+		{"outer.iso!/inner.iso!/file", []string{"outer.iso", "/inner.iso", "/file"}},
+		{"outer.iso!/inner.iso!", []string{"outer.iso", "/inner.iso", ""}},
+		{"wow!.iso", []string{"wow!.iso"}},
+		{"wow!.iso!/dir/a!b", []string{"wow!.iso", "/dir/a!b"}},
+		{"https://example.com/a.iso!/file", []string{"https://example.com/a.iso", "/file"}},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, SplitNestedPath(tt.path), tt.path)
+	}
+}
+
+func TestOpenEntry_Nested(t *testing.T) {
+	files := map[string][]byte{"readme.txt": []byte("inside the inner image")}
+	inner := createImage(t, files)
+	outer := createImage(t, map[string][]byte{"images/inner.iso": inner})
+
+	img, err := OpenReader(bytes.NewReader(outer), int64(len(outer)))
+	require.NoError(t, err)
+	defer img.Close()
+	entry, err := FindEntry(img, "/images/inner.iso")
+	require.NoError(t, err)
+
+	nested, err := OpenEntry(entry)
+	require.NoError(t, err)
+	got, err := nested.ReadFile("readme.txt")
+	require.NoError(t, err)
+	require.Equal(t, files["readme.txt"], got)
+	require.NoError(t, nested.Close())
+
+	// The outer image stays open after the nested one is closed
+	got, err = img.ReadFile("images/inner.iso")
+	require.NoError(t, err)
+	require.Equal(t, inner, got)
+
+	path := filepath.Join(t.TempDir(), "outer.iso")
+	require.NoError(t, os.WriteFile(path, outer, 0o644))
+	opened, rest, err := OpenNested(path + "!/images/inner.iso!/readme.txt")
+	require.NoError(t, err)
+	require.Equal(t, "/readme.txt", rest)
+	got, err = opened.ReadFile(rest)
+	require.NoError(t, err)
+	require.Equal(t, files["readme.txt"], got)
+	require.NoError(t, opened.Close())
+}
@@ -0,0 +1,175 @@
+package cdimage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CloneCD images describe the disc in an INI style .ccd file. The .img file holds every sector of the disc as 2352
+// raw bytes starting at LBA 0, the .sub file holds the matching 96 byte subchannel data and is not read. The TOC
+// entries ([Entry n]) give the session of each track and the lead-out of each session, the [TRACK n] sections give
+// the MODE (0 audio, 1 or 2) and the INDEX points as LBAs.
+const CCD_SIGNATURE = "[CloneCD]"
+
+// IsCCD returns true if the data starts with the signature of a CloneCD control file.
+func IsCCD(data []byte) bool {
+	return strings.HasPrefix(strings.TrimSpace(string(data)), CCD_SIGNATURE)
+}
+
+// ccdSections parses the sections of a CloneCD control file into lower case keys.
+func ccdSections(r io.Reader) (map[string]map[string]string, error) {
+	sections := make(map[string]map[string]string)
+	var current map[string]string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			current = make(map[string]string)
+			sections[strings.ToLower(line[1:len(line)-1])] = current
+		case current != nil:
+			if key, value, ok := strings.Cut(line, "="); ok {
+				current[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+			}
+		}
+	}
+	return sections, scanner.Err()
+}
+
+// ccdInt parses a decimal or 0x prefixed hexadecimal value.
+func ccdInt(value string) (int64, error) {
+	return strconv.ParseInt(value, 0, 64)
+}
+
+// ParseCCD parses a CloneCD control file. The tracks are read from the .img file named file, which is not opened, so
+// the length of the last track is not set.
+func ParseCCD(r io.Reader, file string) (*Image, error) {
+	sections, err := ccdSections(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := sections["clonecd"]; !ok {
+		return nil, errors.New("not a CloneCD control file")
+	}
+
+	// The TOC entries give the session of each track and the lead-out of each session
+	trackSessions := make(map[int]int)
+	leadouts := make(map[int]int64)
+	for name, entry := range sections {
+		if !strings.HasPrefix(name, "entry ") {
+			continue
+		}
+		point, err1 := ccdInt(entry["point"])
+		session, err2 := ccdInt(entry["session"])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid TOC entry [%s]", name)
+		}
+		switch {
+		case point >= 1 && point <= 99:
+			trackSessions[int(point)] = int(session)
+		case point == 0xA2:
+			if plba, err := ccdInt(entry["plba"]); err == nil {
+				leadouts[int(session)] = plba
+			}
+		}
+	}
+
+	img := &Image{}
+	for name, section := range sections {
+		var number int
+		if _, err := fmt.Sscanf(name, "track %d", &number); err != nil {
+			continue
+		}
+
+		track := &Track{Number: number, File: file, SectorSize: RAW_SECTOR_SIZE, Session: trackSessions[number]}
+		if track.Session == 0 {
+			track.Session = 1
+		}
+		switch section["mode"] {
+		case "0":
+			track.Mode = MODE_AUDIO
+		case "1":
+			track.Mode = MODE1_2352
+		case "2":
+			track.Mode = MODE2_2352
+		default:
+			return nil, fmt.Errorf("track %d has an unsupported mode %q", number, section["mode"])
+		}
+		for key, value := range section {
+			var index int
+			if _, err := fmt.Sscanf(key, "index %d", &index); err != nil {
+				continue
+			}
+			lba, err := ccdInt(value)
+			if err != nil {
+				return nil, fmt.Errorf("track %d has an invalid INDEX %d", number, index)
+			}
+			track.Indexes = append(track.Indexes, Index{Number: index, Position: lba})
+		}
+		sort.Slice(track.Indexes, func(i, j int) bool { return track.Indexes[i].Number < track.Indexes[j].Number })
+
+		index1, ok := track.Index(1)
+		if !ok {
+			return nil, fmt.Errorf("track %d has no INDEX 1", number)
+		}
+		track.Start = index1
+		track.Offset = index1 * RAW_SECTOR_SIZE
+		img.Tracks = append(img.Tracks, track)
+	}
+	if len(img.Tracks) == 0 {
+		return nil, errors.New("no tracks found in control file")
+	}
+	sort.Slice(img.Tracks, func(i, j int) bool { return img.Tracks[i].Number < img.Tracks[j].Number })
+
+	// Tracks end where the next one starts or at the lead-out of their session
+	for i, track := range img.Tracks {
+		if leadout, ok := leadouts[track.Session]; ok {
+			track.Length = leadout - track.Start
+		}
+		if i+1 < len(img.Tracks) {
+			next := img.Tracks[i+1].Indexes[0].Position
+			if track.Length == 0 || next-track.Start < track.Length {
+				track.Length = next - track.Start
+			}
+		}
+		if track.Length < 0 {
+			return nil, fmt.Errorf("track %d overlaps the next track", track.Number)
+		}
+	}
+	return img, nil
+}
+
+// OpenCCD opens a CloneCD control file and the .img file beside it.
+func OpenCCD(path string) (*Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, err := ParseCCD(f, strings.TrimSuffix(path, filepath.Ext(path))+".img")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	sizes, err := img.openFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// The image file bounds the last track when no lead-out is recorded
+	for _, track := range img.Tracks {
+		sectors := sizes[track.File]/RAW_SECTOR_SIZE - track.Start
+		if track.Length == 0 || track.Length > sectors {
+			track.Length = max(sectors, 0)
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,234 @@
+package cdimage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A raw CD sector is 2352 bytes. Data sectors start with a 12 byte sync pattern and a 4 byte header holding the
+// sector address and the mode. Mode 1 sectors carry 2048 bytes of user data followed by an EDC and ECC, Mode 2 XA
+// sectors carry an 8 byte subheader followed by 2048 bytes of user data with EDC and ECC (Form 1) or 2324 bytes of
+// user data with an optional EDC (Form 2). Audio sectors are 2352 bytes of 16-bit stereo samples.
+const (
+	RAW_SECTOR_SIZE     = 2352
+	COOKED_SECTOR_SIZE  = 2048
+	FORM2_SECTOR_SIZE   = 2324
+	SUBCHANNEL_SIZE     = 96
+	SYNC_SIZE           = 12
+	HEADER_SIZE         = 4
+	XA_SUBHEADER_SIZE   = 8
+	FRAMES_PER_SECOND   = 75
+	SECONDS_PER_MINUTE  = 60
+	MODE1_DATA_OFFSET   = SYNC_SIZE + HEADER_SIZE
+	MODE2_DATA_OFFSET   = SYNC_SIZE + HEADER_SIZE + XA_SUBHEADER_SIZE
+	XA_SUBMODE_FORM2    = 0x20
+	xaSubmodeOffset     = SYNC_SIZE + HEADER_SIZE + 2
+	mode1EDCOffset      = MODE1_DATA_OFFSET + COOKED_SECTOR_SIZE
+	mode2Form1EDCOffset = MODE2_DATA_OFFSET + COOKED_SECTOR_SIZE
+	mode2Form2EDCOffset = MODE2_DATA_OFFSET + FORM2_SECTOR_SIZE
+)
+
+// Sessions after the first start after the lead-out of the previous session, the lead-in of the new session and the
+// pregap of its first track. CUE sheets do not record these sectors, they are added to the addresses of the tracks.
+const (
+	SESSION_LEADOUT_SECTORS = 6750 // 90 seconds
+	SESSION_LEADIN_SECTORS  = 4500 // 60 seconds
+	SESSION_PREGAP_SECTORS  = 150  // 2 seconds
+	SESSION_GAP_SECTORS     = SESSION_LEADOUT_SECTORS + SESSION_LEADIN_SECTORS + SESSION_PREGAP_SECTORS
+)
+
+// syncPattern starts every raw data sector.
+var syncPattern = []byte{0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00}
+
+// TrackMode is the sector layout of a track as named in CUE sheets.
+type TrackMode string
+
+const (
+	MODE_AUDIO    TrackMode = "AUDIO"
+	MODE_CDG      TrackMode = "CDG"
+	MODE1_2048    TrackMode = "MODE1/2048"
+	MODE1_2352    TrackMode = "MODE1/2352"
+	MODE2_2048    TrackMode = "MODE2/2048"
+	MODE2_2324    TrackMode = "MODE2/2324"
+	MODE2_2336    TrackMode = "MODE2/2336"
+	MODE2_2352    TrackMode = "MODE2/2352"
+	MODE_CDI_2336 TrackMode = "CDI/2336"
+	MODE_CDI_2352 TrackMode = "CDI/2352"
+	MODE_UNKNOWN  TrackMode = ""
+)
+
+// SectorSize returns the number of bytes each sector of the mode occupies in an image without subchannel data.
+func (m TrackMode) SectorSize() int {
+	switch m {
+	case MODE1_2048, MODE2_2048:
+		return COOKED_SECTOR_SIZE
+	case MODE2_2324:
+		return FORM2_SECTOR_SIZE
+	case MODE2_2336, MODE_CDI_2336:
+		return RAW_SECTOR_SIZE - MODE1_DATA_OFFSET
+	case MODE_CDG:
+		return RAW_SECTOR_SIZE + SUBCHANNEL_SIZE
+	default:
+		return RAW_SECTOR_SIZE
+	}
+}
+
+// IsAudio returns true for CD-DA tracks.
+func (m TrackMode) IsAudio() bool {
+	return m == MODE_AUDIO || m == MODE_CDG
+}
+
+// IsMode2 returns true for Mode 2 (CD-ROM XA and CD-i) tracks.
+func (m TrackMode) IsMode2() bool {
+	switch m {
+	case MODE2_2048, MODE2_2324, MODE2_2336, MODE2_2352, MODE_CDI_2336, MODE_CDI_2352:
+		return true
+	}
+	return false
+}
+
+// Index is an INDEX point of a track, Position is in sectors relative to the start of the track's data file.
+type Index struct {
+	Number   int   `json:"number"`
+	Position int64 `json:"position"`
+}
+
+// Track is a track of a CD image.
+type Track struct {
+	// Number is the track number, 1 to 99
+	Number int `json:"number"`
+	// Mode is the sector layout of the track
+	Mode TrackMode `json:"mode"`
+	// SectorSize is the number of bytes per sector in the data file, including any subchannel data
+	SectorSize int `json:"sector_size"`
+	// Session is the session the track belongs to, 1 for single session discs
+	Session int `json:"session"`
+	// File is the name of the data file holding the track
+	File string `json:"file"`
+	// Indexes are the INDEX points of the track, INDEX 01 marks the start of the track
+	Indexes []Index `json:"indexes"`
+	// Pregap and Postgap are the number of silent sectors not stored in the data file (PREGAP and POSTGAP)
+	Pregap  int64 `json:"pregap"`
+	Postgap int64 `json:"postgap"`
+	// Start is the absolute sector address (LBA) of INDEX 01 on the disc
+	Start int64 `json:"start"`
+	// Length is the number of sectors from INDEX 01 to the end of the track
+	Length int64 `json:"length"`
+	// Offset is the byte offset of INDEX 01 within the data file
+	Offset int64 `json:"offset"`
+	// SwapBytes is set for audio tracks stored with big-endian samples (MOTOROLA files)
+	SwapBytes bool `json:"swap_bytes"`
+
+	reader io.ReaderAt
+}
+
+// Index returns the position of the given index in sectors relative to the start of the data file, false if the track
+// has no such index.
+func (t *Track) Index(number int) (int64, bool) {
+	for _, index := range t.Indexes {
+		if index.Number == number {
+			return index.Position, true
+		}
+	}
+	return 0, false
+}
+
+// PregapLength returns the number of sectors before INDEX 01, stored between INDEX 00 and INDEX 01 or unstored as
+// recorded by PREGAP.
+func (t *Track) PregapLength() int64 {
+	length := t.Pregap
+	if index0, ok := t.Index(0); ok {
+		if index1, ok := t.Index(1); ok && index1 > index0 {
+			length += index1 - index0
+		}
+	}
+	return length
+}
+
+// ReadSector reads the stored sector at the given sector number relative to INDEX 01. The buffer must hold SectorSize
+// bytes.
+func (t *Track) ReadSector(sector int64, buf []byte) error {
+	if t.reader == nil {
+		return fmt.Errorf("track %d has no data file", t.Number)
+	}
+	if sector < 0 || sector >= t.Length {
+		return fmt.Errorf("sector %d is outside of track %d", sector, t.Number)
+	}
+	n, err := t.reader.ReadAt(buf[:t.SectorSize], t.Offset+sector*int64(t.SectorSize))
+	if n == t.SectorSize {
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("failed to read sector %d of track %d: %w", sector, t.Number, err)
+}
+
+// Image is a CD image made up of tracks.
+type Image struct {
+	// Tracks of the image in disc order
+	Tracks []*Track `json:"tracks"`
+
+	closers []io.Closer
+}
+
+// DataTrack returns the first data track of the last session with data tracks, the one holding the ISO9660 filesystem.
+// That is the first data track of single session and mixed mode discs, the data session of CD-Extra discs and the
+// last session of multi-session discs, whose volume descriptors describe the files of all sessions.
+func (img *Image) DataTrack() (*Track, error) {
+	var data *Track
+	for _, track := range img.Tracks {
+		if !track.Mode.IsAudio() && (data == nil || track.Session > data.Session) {
+			data = track
+		}
+	}
+	if data == nil {
+		return nil, errors.New("image has no data track")
+	}
+	return data, nil
+}
+
+// AudioTracks returns the CD-DA tracks of the image.
+func (img *Image) AudioTracks() []*Track {
+	var tracks []*Track
+	for _, track := range img.Tracks {
+		if track.Mode.IsAudio() {
+			tracks = append(tracks, track)
+		}
+	}
+	return tracks
+}
+
+// Close closes the data files of the image.
+func (img *Image) Close() error {
+	var errs []error
+	for _, c := range img.closers {
+		errs = append(errs, c.Close())
+	}
+	img.closers = nil
+	return errors.Join(errs...)
+}
+
+// IsRawSector returns true if the data starts with the sync pattern of a raw data sector.
+func IsRawSector(data []byte) bool {
+	return len(data) >= SYNC_SIZE && bytes.Equal(data[:SYNC_SIZE], syncPattern)
+}
+
+// FramesToMSF formats a sector count as a "mm:ss:ff" CUE sheet time.
+func FramesToMSF(frames int64) string {
+	return fmt.Sprintf("%02d:%02d:%02d", frames/(FRAMES_PER_SECOND*SECONDS_PER_MINUTE), frames/FRAMES_PER_SECOND%SECONDS_PER_MINUTE, frames%FRAMES_PER_SECOND)
+}
+
+// ParseMSF parses a "mm:ss:ff" CUE sheet time into a sector count.
+func ParseMSF(msf string) (int64, error) {
+	var m, s, f int64
+	if _, err := fmt.Sscanf(msf, "%d:%d:%d", &m, &s, &f); err != nil {
+		return 0, fmt.Errorf("invalid time %q: %w", msf, err)
+	}
+	if s >= SECONDS_PER_MINUTE || f >= FRAMES_PER_SECOND || m < 0 || s < 0 || f < 0 {
+		return 0, fmt.Errorf("invalid time %q", msf)
+	}
+	return (m*SECONDS_PER_MINUTE+s)*FRAMES_PER_SECOND + f, nil
+}
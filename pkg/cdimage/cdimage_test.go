@@ -0,0 +1,230 @@
+package cdimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mode1Sector builds a raw Mode 1 sector holding the user data with a valid EDC, the ECC is left zero.
+func mode1Sector(lba int64, data []byte) []byte {
+	sector := make([]byte, RAW_SECTOR_SIZE)
+	copy(sector, syncPattern)
+	msf := lba + 2*FRAMES_PER_SECOND
+	sector[12] = byte(msf / (FRAMES_PER_SECOND * SECONDS_PER_MINUTE))
+	sector[13] = byte(msf / FRAMES_PER_SECOND % SECONDS_PER_MINUTE)
+	sector[14] = byte(msf % FRAMES_PER_SECOND)
+	sector[15] = 1
+	copy(sector[MODE1_DATA_OFFSET:], data)
+	binary.LittleEndian.PutUint32(sector[mode1EDCOffset:], EDC(sector[:mode1EDCOffset]))
+	return sector
+}
+
+func TestOpenCue(t *testing.T) {
+	dir := t.TempDir()
+
+	// A mixed mode disc, 20 data sectors followed by an audio track with a two sector stored pregap
+	var bin, want bytes.Buffer
+	for lba := int64(0); lba < 20; lba++ {
+		data := bytes.Repeat([]byte{byte(lba)}, COOKED_SECTOR_SIZE)
+		want.Write(data)
+		bin.Write(mode1Sector(lba, data))
+	}
+	bin.Write(make([]byte, 12*RAW_SECTOR_SIZE))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "disc image.bin"), bin.Bytes(), 0644))
+
+	cue := strings.Join([]string{
+		`FILE "disc image.bin" BINARY`,
+		`  TRACK 01 MODE1/2352`,
+		`    INDEX 01 00:00:00`,
+		`  TRACK 02 AUDIO`,
+		`    INDEX 00 00:00:20`,
+		`    INDEX 01 00:00:22`,
+	}, "\n")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "disc.cue"), []byte(cue), 0644))
+
+	img, err := Open(filepath.Join(dir, "disc.cue"))
+	require.NoError(t, err)
+	defer img.Close()
+
+	require.Len(t, img.Tracks, 2)
+	data, audio := img.Tracks[0], img.Tracks[1]
+	require.Equal(t, int64(20), data.Length)
+	require.Equal(t, int64(0), data.Start)
+	require.Equal(t, int64(10), audio.Length)
+	require.Equal(t, int64(22), audio.Start)
+	require.Equal(t, int64(22*RAW_SECTOR_SIZE), audio.Offset)
+	require.Equal(t, int64(2), audio.PregapLength())
+	require.Equal(t, []*Track{audio}, img.AudioTracks())
+
+	reader, err := img.DataReader()
+	require.NoError(t, err)
+	require.Equal(t, data, reader.Track())
+	got, err := io.ReadAll(io.NewSectionReader(reader, 0, reader.Size()))
+	require.NoError(t, err)
+	require.Equal(t, want.Bytes(), got)
+
+	// Reads spanning sectors
+	buf := make([]byte, 4)
+	_, err = reader.ReadAt(buf, COOKED_SECTOR_SIZE-2)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 0, 1, 1}, buf)
+
+	sector := make([]byte, RAW_SECTOR_SIZE)
+	require.NoError(t, data.ReadSector(5, sector))
+	require.NoError(t, VerifySector(sector))
+	sector[100] ^= 0xFF
+	require.Error(t, VerifySector(sector))
+}
+
+func TestParseCue_Invalid(t *testing.T) {
+	_, err := ParseCue(strings.NewReader("TRACK 01 MODE1/2352\nINDEX 01 00:00:00\n"), "")
+	require.Error(t, err)
+
+	_, err = ParseCue(strings.NewReader("FILE a.bin BINARY\nTRACK 01 MODE1/2352\nINDEX 01 00:00:75\n"), "")
+	require.Error(t, err)
+
+	_, err = ParseCue(strings.NewReader("FILE a.bin BINARY\nTRACK 01 MODE1/2352\n"), "")
+	require.Error(t, err)
+}
+
+func TestMSF(t *testing.T) {
+	frames, err := ParseMSF("01:02:03")
+	require.NoError(t, err)
+	require.Equal(t, int64((60+2)*75+3), frames)
+	require.Equal(t, "01:02:03", FramesToMSF(frames))
+}
+
+func TestWriteWAV(t *testing.T) {
+	dir := t.TempDir()
+	samples := make([]byte, 2*RAW_SECTOR_SIZE)
+	for i := range samples {
+		samples[i] = byte(i)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "audio.bin"), samples, 0644))
+	cue := "FILE audio.bin MOTOROLA\nTRACK 01 AUDIO\nPREGAP 00:02:00\nINDEX 01 00:00:00\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "audio.cue"), []byte(cue), 0644))
+
+	img, err := OpenCue(filepath.Join(dir, "audio.cue"))
+	require.NoError(t, err)
+	defer img.Close()
+
+	_, err = img.DataTrack()
+	require.Error(t, err)
+	track := img.Tracks[0]
+	require.Equal(t, int64(2*FRAMES_PER_SECOND), track.PregapLength())
+	require.Equal(t, int64(2*FRAMES_PER_SECOND), track.Start)
+
+	paths, err := img.ExtractAudio(filepath.Join(dir, "out"))
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "out", "track01.wav")}, paths)
+
+	wav, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	require.Len(t, wav, WAV_HEADER_SIZE+len(samples))
+	require.Equal(t, "RIFF", string(wav[0:4]))
+	require.Equal(t, uint32(len(wav)-8), binary.LittleEndian.Uint32(wav[4:8]))
+	require.Equal(t, uint32(len(samples)), binary.LittleEndian.Uint32(wav[40:44]))
+	require.Equal(t, []byte{1, 0, 3, 2}, wav[WAV_HEADER_SIZE:WAV_HEADER_SIZE+4])
+}
+
+func TestForm2Reader(t *testing.T) {
+	dir := t.TempDir()
+
+	// Two Mode 2 sectors, a Form 1 sector followed by a Form 2 sector
+	var bin bytes.Buffer
+	for i, submode := range []byte{0x08, 0x08 | XA_SUBMODE_FORM2} {
+		sector := make([]byte, RAW_SECTOR_SIZE)
+		copy(sector, syncPattern)
+		sector[15] = 2
+		sector[18], sector[22] = submode, submode
+		for j := MODE2_DATA_OFFSET; j < RAW_SECTOR_SIZE; j++ {
+			sector[j] = byte(i + 1)
+		}
+		bin.Write(sector)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "xa.bin"), bin.Bytes(), 0644))
+
+	img, err := Open(filepath.Join(dir, "xa.bin"))
+	require.NoError(t, err)
+	defer img.Close()
+	require.Equal(t, MODE2_2352, img.Tracks[0].Mode)
+
+	reader, err := img.DataReader()
+	require.NoError(t, err)
+	require.True(t, reader.HasForm2())
+
+	form2 := reader.Form2Reader(1, 1)
+	require.Equal(t, int64(FORM2_SECTOR_SIZE), form2.Size())
+	got, err := io.ReadAll(io.NewSectionReader(form2, 0, form2.Size()))
+	require.NoError(t, err)
+	require.Equal(t, bytes.Repeat([]byte{2}, FORM2_SECTOR_SIZE), got)
+
+	// The cooked view serves the first 2048 bytes of each sector
+	buf := make([]byte, 2)
+	_, err = reader.ReadAt(buf, COOKED_SECTOR_SIZE-1)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2}, buf)
+}
+
+func TestDataReader_CDExtra(t *testing.T) {
+	dir := t.TempDir()
+
+	// An audio session followed by a data session whose filesystem addresses sectors from the start of the disc
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "audio.bin"), make([]byte, 30*RAW_SECTOR_SIZE), 0644))
+	data := make([]byte, 40*COOKED_SECTOR_SIZE)
+	copy(data[16*COOKED_SECTOR_SIZE:], "\x01CD001")
+	copy(data[17*COOKED_SECTOR_SIZE:], "\xffCD001")
+	copy(data[25*COOKED_SECTOR_SIZE:], "file data")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data.iso"), data, 0644))
+
+	cue := strings.Join([]string{
+		`REM SESSION 01`,
+		`FILE "audio.bin" BINARY`,
+		`  TRACK 01 AUDIO`,
+		`    INDEX 01 00:00:00`,
+		`REM SESSION 02`,
+		`FILE "data.iso" BINARY`,
+		`  TRACK 02 MODE1/2048`,
+		`    INDEX 01 00:00:00`,
+	}, "\n")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "disc.cue"), []byte(cue), 0644))
+
+	img, err := Open(filepath.Join(dir, "disc.cue"))
+	require.NoError(t, err)
+	defer img.Close()
+
+	track, err := img.DataTrack()
+	require.NoError(t, err)
+	require.Equal(t, 2, track.Number)
+	// The data session starts after the lead-out and lead-in between the sessions and its first pregap
+	require.Equal(t, int64(30+SESSION_GAP_SECTORS), track.Start)
+
+	reader, err := img.DataReader()
+	require.NoError(t, err)
+	require.Equal(t, (track.Start+40)*COOKED_SECTOR_SIZE, reader.Size())
+
+	// The volume descriptors are read from the start of the data track
+	buf := make([]byte, 6)
+	_, err = reader.ReadAt(buf, 16*COOKED_SECTOR_SIZE)
+	require.NoError(t, err)
+	require.Equal(t, "\x01CD001", string(buf))
+
+	// Other sectors are addressed from the start of the disc
+	buf = make([]byte, 9)
+	_, err = reader.ReadAt(buf, (track.Start+25)*COOKED_SECTOR_SIZE)
+	require.NoError(t, err)
+	require.Equal(t, "file data", string(buf))
+
+	// Sectors of the audio session and of the gap between the sessions are not readable
+	_, err = reader.ReadAt(buf, 20*COOKED_SECTOR_SIZE)
+	require.Error(t, err)
+	_, err = reader.ReadAt(buf, (track.Start-1)*COOKED_SECTOR_SIZE)
+	require.Error(t, err)
+}
@@ -0,0 +1,190 @@
+package cdimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// cookedData returns sectors of 2048 bytes, each filled with its sector number.
+func cookedData(sectors int) []byte {
+	var data bytes.Buffer
+	for i := 0; i < sectors; i++ {
+		data.Write(bytes.Repeat([]byte{byte(i)}, COOKED_SECTOR_SIZE))
+	}
+	return data.Bytes()
+}
+
+// requireUserData checks that the data track of the image serves the expected user data.
+func requireUserData(t *testing.T, img *Image, want []byte) {
+	reader, err := img.DataReader()
+	require.NoError(t, err)
+	got, err := io.ReadAll(io.NewSectionReader(reader, 0, reader.Size()))
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestOpenNRG(t *testing.T) {
+	// A Mode 1 track with a two sector pregap followed by a one sector audio track
+	data := append(cookedData(2), cookedData(4)...)
+	audio := bytes.Repeat([]byte{0xAA}, RAW_SECTOR_SIZE)
+
+	var image bytes.Buffer
+	image.Write(data)
+	image.Write(audio)
+	chunkOffset := image.Len()
+
+	dao := make([]byte, nrgDAOHeaderSize)
+	dao[20], dao[21] = 1, 2
+	for _, track := range []struct {
+		mode                byte
+		size                uint16
+		index0, index1, end uint64
+	}{
+		{0x00, COOKED_SECTOR_SIZE, 0, 2 * COOKED_SECTOR_SIZE, uint64(len(data))},
+		{0x07, RAW_SECTOR_SIZE, uint64(len(data)), uint64(len(data)), uint64(len(data) + len(audio))},
+	} {
+		entry := make([]byte, 42)
+		binary.BigEndian.PutUint16(entry[12:14], track.size)
+		entry[14] = track.mode
+		binary.BigEndian.PutUint64(entry[18:26], track.index0)
+		binary.BigEndian.PutUint64(entry[26:34], track.index1)
+		binary.BigEndian.PutUint64(entry[34:42], track.end)
+		dao = append(dao, entry...)
+	}
+	image.WriteString("DAOX")
+	binary.Write(&image, binary.BigEndian, uint32(len(dao)))
+	image.Write(dao)
+	image.WriteString("END!")
+	binary.Write(&image, binary.BigEndian, uint32(0))
+	image.WriteString(NRG_FOOTER_V2)
+	binary.Write(&image, binary.BigEndian, uint64(chunkOffset))
+
+	path := filepath.Join(t.TempDir(), "disc.bin")
+	require.NoError(t, os.WriteFile(path, image.Bytes(), 0644))
+	require.Equal(t, FORMAT_NRG, Detect(path))
+
+	img, err := Open(path)
+	require.NoError(t, err)
+	defer img.Close()
+
+	require.Len(t, img.Tracks, 2)
+	require.Equal(t, MODE1_2048, img.Tracks[0].Mode)
+	require.Equal(t, int64(2), img.Tracks[0].PregapLength())
+	require.Equal(t, int64(4), img.Tracks[0].Length)
+	require.Equal(t, MODE_AUDIO, img.Tracks[1].Mode)
+	require.Equal(t, int64(1), img.Tracks[1].Length)
+	requireUserData(t, img, data[2*COOKED_SECTOR_SIZE:])
+}
+
+func TestOpenMDS(t *testing.T) {
+	dir := t.TempDir()
+	data := cookedData(3)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "disc.mdf"), data, 0644))
+
+	// Header, one session, a track block, a lead-out TOC entry, the extra block, the footer and the file name
+	mds := make([]byte, mdsHeaderSize+mdsSessionSize+2*mdsTrackSize+8+16)
+	copy(mds, MDS_SIGNATURE)
+	binary.LittleEndian.PutUint16(mds[20:22], 1)
+	binary.LittleEndian.PutUint32(mds[80:84], mdsHeaderSize)
+
+	session := mds[mdsHeaderSize:]
+	binary.LittleEndian.PutUint16(session[8:10], 1)
+	session[10] = 2
+	binary.LittleEndian.PutUint32(session[20:24], mdsHeaderSize+mdsSessionSize)
+
+	extra := mdsHeaderSize + mdsSessionSize + 2*mdsTrackSize
+	footer := extra + 8
+	track := mds[mdsHeaderSize+mdsSessionSize:]
+	track[0], track[4] = 0xAA, 1
+	binary.LittleEndian.PutUint32(track[12:16], uint32(extra))
+	binary.LittleEndian.PutUint16(track[16:18], COOKED_SECTOR_SIZE)
+	binary.LittleEndian.PutUint32(track[48:52], 1)
+	binary.LittleEndian.PutUint32(track[52:56], uint32(footer))
+	track[mdsTrackSize+4] = 0xA2
+
+	binary.LittleEndian.PutUint32(mds[extra+4:extra+8], 3)
+	binary.LittleEndian.PutUint32(mds[footer:footer+4], uint32(len(mds)))
+	mds = append(mds, "*.mdf\x00"...)
+
+	path := filepath.Join(dir, "disc.mds")
+	require.NoError(t, os.WriteFile(path, mds, 0644))
+
+	img, err := Open(path)
+	require.NoError(t, err)
+	defer img.Close()
+
+	require.Len(t, img.Tracks, 1)
+	require.Equal(t, MODE1_2048, img.Tracks[0].Mode)
+	require.Equal(t, filepath.Join(dir, "disc.mdf"), img.Tracks[0].File)
+	requireUserData(t, img, data)
+}
+
+func TestParseMDS_Invalid(t *testing.T) {
+	mds := make([]byte, mdsHeaderSize+mdsSessionSize)
+	copy(mds, MDS_SIGNATURE)
+	binary.LittleEndian.PutUint16(mds[20:22], 1)
+
+	// Session blocks that start outside the descriptor or run past its end
+	for _, offset := range []uint32{0, 0xFFFFFFFF, mdsHeaderSize + 1} {
+		binary.LittleEndian.PutUint32(mds[80:84], offset)
+		_, err := ParseMDS(mds, "", "disc.mds")
+		require.Error(t, err, "offset %d", offset)
+	}
+
+	binary.LittleEndian.PutUint32(mds[80:84], mdsHeaderSize)
+	binary.LittleEndian.PutUint16(mds[20:22], 2)
+	_, err := ParseMDS(mds, "", "disc.mds")
+	require.Error(t, err)
+}
+
+func TestOpenCCD(t *testing.T) {
+	dir := t.TempDir()
+	data := cookedData(3)
+	var raw bytes.Buffer
+	for lba := 0; lba < 3; lba++ {
+		raw.Write(mode1Sector(int64(lba), data[lba*COOKED_SECTOR_SIZE:(lba+1)*COOKED_SECTOR_SIZE]))
+	}
+	raw.Write(make([]byte, 2*RAW_SECTOR_SIZE))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "disc.img"), raw.Bytes(), 0644))
+
+	ccd := `[CloneCD]
+Version=3
+[Disc]
+TocEntries=2
+Sessions=1
+[Entry 0]
+Session=1
+Point=0x01
+PLBA=0
+[Entry 1]
+Session=1
+Point=0xa2
+PLBA=5
+[TRACK 1]
+MODE=1
+INDEX 1=0
+[TRACK 2]
+MODE=0
+INDEX 0=3
+INDEX 1=4
+`
+	path := filepath.Join(dir, "disc.ccd")
+	require.NoError(t, os.WriteFile(path, []byte(ccd), 0644))
+
+	img, err := Open(path)
+	require.NoError(t, err)
+	defer img.Close()
+
+	require.Len(t, img.Tracks, 2)
+	require.Equal(t, int64(3), img.Tracks[0].Length)
+	require.Equal(t, MODE_AUDIO, img.Tracks[1].Mode)
+	require.Equal(t, int64(1), img.Tracks[1].Length)
+	require.Equal(t, int64(1), img.Tracks[1].PregapLength())
+	requireUserData(t, img, data)
+}
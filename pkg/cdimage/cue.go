@@ -0,0 +1,283 @@
+package cdimage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Data file types of the CUE sheet FILE command. WAVE, AIFF and MP3 files hold encoded audio and are not supported.
+const (
+	FILE_BINARY   = "BINARY"
+	FILE_MOTOROLA = "MOTOROLA"
+)
+
+// ParseCue parses a CUE sheet. Data file names are resolved relative to dir, the files are not opened so the Start,
+// Length and Offset of the tracks are not set.
+func ParseCue(r io.Reader, dir string) (*Image, error) {
+	img := &Image{}
+	var file, fileType string
+	var track *Track
+	session := 1
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := cueFields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+		switch strings.ToUpper(fields[0]) {
+		case "FILE":
+			if len(fields) < 3 {
+				err = errors.New("FILE requires a name and a type")
+				break
+			}
+			file, fileType = fields[1], strings.ToUpper(fields[2])
+			if fileType != FILE_BINARY && fileType != FILE_MOTOROLA {
+				err = fmt.Errorf("unsupported file type %s", fileType)
+				break
+			}
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(dir, file)
+			}
+		case "TRACK":
+			if len(fields) < 3 {
+				err = errors.New("TRACK requires a number and a mode")
+				break
+			}
+			if file == "" {
+				err = errors.New("TRACK before FILE")
+				break
+			}
+			track, err = newCueTrack(fields[1], fields[2], file, session)
+			if err != nil {
+				break
+			}
+			track.SwapBytes = fileType == FILE_MOTOROLA && track.Mode.IsAudio()
+			img.Tracks = append(img.Tracks, track)
+		case "INDEX":
+			if track == nil || len(fields) < 3 {
+				err = errors.New("INDEX requires a track, a number and a time")
+				break
+			}
+			var index Index
+			if index.Number, err = strconv.Atoi(fields[1]); err != nil {
+				break
+			}
+			if index.Position, err = ParseMSF(fields[2]); err != nil {
+				break
+			}
+			track.Indexes = append(track.Indexes, index)
+		case "PREGAP", "POSTGAP":
+			if track == nil || len(fields) < 2 {
+				err = fmt.Errorf("%s requires a track and a time", fields[0])
+				break
+			}
+			var length int64
+			if length, err = ParseMSF(fields[1]); err != nil {
+				break
+			}
+			if strings.EqualFold(fields[0], "PREGAP") {
+				track.Pregap = length
+			} else {
+				track.Postgap = length
+			}
+		case "REM":
+			// Multi-session sheets written by EAC and others mark sessions with REM SESSION nn
+			if len(fields) >= 3 && strings.EqualFold(fields[1], "SESSION") {
+				session, err = strconv.Atoi(fields[2])
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cue sheet line %d: %w", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(img.Tracks) == 0 {
+		return nil, errors.New("cue sheet has no tracks")
+	}
+	for _, track := range img.Tracks {
+		if _, ok := track.Index(1); !ok {
+			return nil, fmt.Errorf("track %d has no INDEX 01", track.Number)
+		}
+	}
+	return img, nil
+}
+
+// newCueTrack creates a track from the arguments of a TRACK command.
+func newCueTrack(number, mode, file string, session int) (*Track, error) {
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 1 || n > 99 {
+		return nil, fmt.Errorf("invalid track number %q", number)
+	}
+	m := TrackMode(strings.ToUpper(mode))
+	switch m {
+	case MODE_AUDIO, MODE_CDG, MODE1_2048, MODE1_2352, MODE2_2048, MODE2_2324, MODE2_2336, MODE2_2352, MODE_CDI_2336, MODE_CDI_2352:
+	default:
+		return nil, fmt.Errorf("unsupported track mode %q", mode)
+	}
+	return &Track{
+		Number:     n,
+		Mode:       m,
+		SectorSize: m.SectorSize(),
+		Session:    session,
+		File:       file,
+	}, nil
+}
+
+// cueFields splits a CUE sheet line into fields, double quoted fields may contain spaces.
+func cueFields(line string) []string {
+	var fields []string
+	line = strings.TrimSpace(line)
+	for line != "" {
+		var field string
+		if line[0] == '"' {
+			end := strings.IndexByte(line[1:], '"')
+			if end < 0 {
+				field, line = line[1:], ""
+			} else {
+				field, line = line[1:end+1], line[end+2:]
+			}
+		} else if end := strings.IndexAny(line, " \t"); end >= 0 {
+			field, line = line[:end], line[end:]
+		} else {
+			field, line = line, ""
+		}
+		fields = append(fields, field)
+		line = strings.TrimLeft(line, " \t")
+	}
+	return fields
+}
+
+// OpenCue opens a CUE sheet and the data files it references.
+func OpenCue(path string) (*Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, err := ParseCue(f, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	sizes, err := img.openFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := img.layout(sizes); err != nil {
+		img.Close()
+		return nil, fmt.Errorf("failed to lay out %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// layout computes the Offset, Length and Start of the tracks from their INDEX points and the sizes of their data files.
+// A track's sectors run from its first INDEX up to the first INDEX of the next track in the same file, or to the end of
+// the file. The first track of each further session starts SESSION_GAP_SECTORS after the end of the previous session.
+func (img *Image) layout(sizes map[string]int64) error {
+	var lba, fileOffset int64
+	for i, track := range img.Tracks {
+		if i == 0 || img.Tracks[i-1].File != track.File {
+			fileOffset = 0
+		}
+		if i > 0 && track.Session > img.Tracks[i-1].Session {
+			lba += SESSION_GAP_SECTORS
+		}
+
+		first := track.Indexes[0].Position
+		index1, _ := track.Index(1)
+		if index1 < first {
+			return fmt.Errorf("track %d INDEX 01 precedes its first index", track.Number)
+		}
+
+		var end int64
+		if i+1 < len(img.Tracks) && img.Tracks[i+1].File == track.File {
+			end = img.Tracks[i+1].Indexes[0].Position
+		} else {
+			end = first + (sizes[track.File]-fileOffset)/int64(track.SectorSize)
+		}
+		if end < index1 {
+			return fmt.Errorf("track %d ends before its INDEX 01", track.Number)
+		}
+
+		track.Offset = fileOffset + (index1-first)*int64(track.SectorSize)
+		track.Length = end - index1
+		track.Start = lba + track.Pregap + index1 - first
+		lba = track.Start + track.Length + track.Postgap
+		fileOffset += (end - first) * int64(track.SectorSize)
+	}
+	return nil
+}
+
+// OpenRaw opens a single track image of raw 2352 byte data sectors, such as a .bin file without its CUE sheet. The
+// track mode is taken from the header of the first sector.
+func OpenRaw(path string) (*Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	var header [MODE1_DATA_OFFSET]byte
+	if _, err := f.ReadAt(header[:], 0); err != nil || !IsRawSector(header[:]) {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a raw CD image", path)
+	}
+
+	mode := MODE1_2352
+	switch header[SYNC_SIZE+3] {
+	case 1:
+	case 2:
+		mode = MODE2_2352
+	default:
+		f.Close()
+		return nil, fmt.Errorf("%s has an invalid sector mode %d", path, header[SYNC_SIZE+3])
+	}
+
+	track := &Track{
+		Number:     1,
+		Mode:       mode,
+		SectorSize: RAW_SECTOR_SIZE,
+		Session:    1,
+		File:       path,
+		Indexes:    []Index{{Number: 1}},
+		Length:     info.Size() / RAW_SECTOR_SIZE,
+		reader:     f,
+	}
+	return &Image{Tracks: []*Track{track}, closers: []io.Closer{f}}, nil
+}
+
+// Open opens a CD image. CUE sheets, Alcohol 120% descriptors, CloneCD control files and Nero images are recognized
+// by their extension or signature, anything else is opened as a raw data track.
+func Open(path string) (*Image, error) {
+	switch Detect(path) {
+	case FORMAT_CUE:
+		return OpenCue(path)
+	case FORMAT_MDS:
+		return OpenMDS(path)
+	case FORMAT_CCD:
+		return OpenCCD(path)
+	case FORMAT_NRG:
+		return OpenNRG(path)
+	case FORMAT_RAW:
+		return OpenRaw(path)
+	}
+	return nil, fmt.Errorf("%s is not a CD image", path)
+}
@@ -0,0 +1,60 @@
+package cdimage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format is a CD image container format.
+type Format string
+
+const (
+	FORMAT_NONE Format = ""
+	FORMAT_CUE  Format = "cue"
+	FORMAT_MDS  Format = "mds"
+	FORMAT_CCD  Format = "ccd"
+	FORMAT_NRG  Format = "nrg"
+	FORMAT_RAW  Format = "raw"
+)
+
+// Detect returns the container format of the file at path, FORMAT_NONE for cooked images and other files. The
+// descriptor formats are recognized by their extension or signature, Nero images by their footer and raw images by the
+// sync pattern of their first sector.
+func Detect(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".cue":
+		return FORMAT_CUE
+	case ".mds":
+		return FORMAT_MDS
+	case ".ccd":
+		return FORMAT_CCD
+	case ".nrg":
+		return FORMAT_NRG
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return FORMAT_NONE
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return FORMAT_NONE
+	}
+
+	header := make([]byte, len(MDS_SIGNATURE))
+	n, _ := f.ReadAt(header, 0)
+	header = header[:n]
+	switch {
+	case IsMDS(header):
+		return FORMAT_MDS
+	case IsCCD(header):
+		return FORMAT_CCD
+	case IsNRG(f, info.Size()):
+		return FORMAT_NRG
+	case IsRawSector(header):
+		return FORMAT_RAW
+	}
+	return FORMAT_NONE
+}
@@ -0,0 +1,221 @@
+package cdimage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// Alcohol 120% images describe the disc in a .mds file, the track data is stored in a .mdf file. All values are
+// little-endian.
+//
+// Header, 88 bytes:
+//
+//	BP 1 - 16:  Signature "MEDIA DESCRIPTOR"
+//	BP 21 - 22: Number of sessions
+//	BP 81 - 84: Offset of the first session block
+//
+// Session block, 24 bytes:
+//
+//	BP 1 - 4:   Start sector
+//	BP 5 - 8:   End sector
+//	BP 9 - 10:  Session number
+//	BP 11:      Number of track blocks
+//	BP 21 - 24: Offset of the first track block
+//
+// Track block, 80 bytes:
+//
+//	BP 1:       Mode, the low nibble is 9 for audio, 10 for Mode 1 and 11 to 13 for Mode 2
+//	BP 2:       Subchannel mode, non-zero if 96 bytes of subchannel data follow each sector
+//	BP 5:       Point, the track number for track blocks and 0xA0 and above for TOC entries
+//	BP 13 - 16: Offset of the extra block holding the pregap and length of the track, 32-bit each
+//	BP 17 - 18: Sector size
+//	BP 37 - 40: Start sector
+//	BP 41 - 48: Byte offset of the track data in the .mdf file
+//	BP 49 - 52: Number of data files
+//	BP 53 - 56: Offset of the footer holding the offset of the data file name and whether it is UTF-16
+const (
+	MDS_SIGNATURE = "MEDIA DESCRIPTOR"
+
+	mdsHeaderSize  = 88
+	mdsSessionSize = 24
+	mdsTrackSize   = 80
+)
+
+// IsMDS returns true if the data starts with the signature of an Alcohol 120% descriptor.
+func IsMDS(data []byte) bool {
+	return len(data) >= len(MDS_SIGNATURE) && string(data[:len(MDS_SIGNATURE)]) == MDS_SIGNATURE
+}
+
+// ParseMDS parses an Alcohol 120% descriptor. Data file names are resolved relative to dir, "*.mdf" names the .mdf
+// file beside the descriptor named base. The files are not opened.
+func ParseMDS(data []byte, dir, base string) (*Image, error) {
+	if len(data) < mdsHeaderSize || !IsMDS(data) {
+		return nil, errors.New("not an Alcohol 120% descriptor")
+	}
+	sessions := int(binary.LittleEndian.Uint16(data[20:22]))
+	sessionOffset := int(binary.LittleEndian.Uint32(data[80:84]))
+
+	if sessionOffset < mdsHeaderSize || sessionOffset > len(data) {
+		return nil, fmt.Errorf("session blocks at offset %d are outside the descriptor", sessionOffset)
+	}
+
+	img := &Image{}
+	for s := 0; s < sessions; s++ {
+		start := sessionOffset + s*mdsSessionSize
+		if start+mdsSessionSize > len(data) {
+			return nil, fmt.Errorf("session block %d exceeds the descriptor", s+1)
+		}
+		session := data[start : start+mdsSessionSize]
+		number := int(binary.LittleEndian.Uint16(session[8:10]))
+		blocks := int(session[10])
+		trackOffset := int(binary.LittleEndian.Uint32(session[20:24]))
+
+		for b := 0; b < blocks; b++ {
+			start := trackOffset + b*mdsTrackSize
+			if start+mdsTrackSize > len(data) {
+				return nil, fmt.Errorf("track block %d of session %d exceeds the descriptor", b+1, number)
+			}
+			block := data[start : start+mdsTrackSize]
+			point := int(block[4])
+			if point < 1 || point > 99 {
+				continue
+			}
+
+			track, err := mdsTrack(data, block, dir, base)
+			if err != nil {
+				return nil, fmt.Errorf("track %d: %w", point, err)
+			}
+			track.Number = point
+			track.Session = number
+			img.Tracks = append(img.Tracks, track)
+		}
+	}
+
+	if len(img.Tracks) == 0 {
+		return nil, errors.New("no tracks found in descriptor")
+	}
+	return img, nil
+}
+
+// mdsTrack decodes a track block.
+func mdsTrack(data, block []byte, dir, base string) (*Track, error) {
+	track := &Track{
+		SectorSize: int(binary.LittleEndian.Uint16(block[16:18])),
+		Start:      int64(binary.LittleEndian.Uint32(block[36:40])),
+		Offset:     int64(binary.LittleEndian.Uint64(block[40:48])),
+	}
+
+	// The extra block holds the pregap and the length of the track
+	if extra := int(binary.LittleEndian.Uint32(block[12:16])); extra > 0 && extra+8 <= len(data) {
+		track.Pregap = int64(binary.LittleEndian.Uint32(data[extra : extra+4]))
+		track.Length = int64(binary.LittleEndian.Uint32(data[extra+4 : extra+8]))
+	}
+
+	userSize := track.SectorSize
+	if block[1] != 0 {
+		userSize -= SUBCHANNEL_SIZE
+	}
+	switch mode := block[0] & 0x0F; {
+	case mode == 0x09:
+		track.Mode = MODE_AUDIO
+	case userSize == COOKED_SECTOR_SIZE && mode == 0x0A:
+		track.Mode = MODE1_2048
+	case userSize == COOKED_SECTOR_SIZE:
+		track.Mode = MODE2_2048
+	case userSize == RAW_SECTOR_SIZE-MODE1_DATA_OFFSET:
+		track.Mode = MODE2_2336
+	case userSize == RAW_SECTOR_SIZE && mode == 0x0A:
+		track.Mode = MODE1_2352
+	case userSize == RAW_SECTOR_SIZE && mode >= 0x0B && mode <= 0x0D:
+		track.Mode = MODE2_2352
+	default:
+		return nil, fmt.Errorf("unsupported mode 0x%02X with %d byte sectors", block[0], track.SectorSize)
+	}
+
+	// The footer holds the name of the data file
+	footer := int(binary.LittleEndian.Uint32(block[52:56]))
+	if binary.LittleEndian.Uint32(block[48:52]) == 0 || footer+16 > len(data) {
+		return nil, errors.New("no data file recorded")
+	}
+	name, err := mdsString(data, int(binary.LittleEndian.Uint32(data[footer:footer+4])), binary.LittleEndian.Uint32(data[footer+4:footer+8]) != 0)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(name, "*") {
+		name = strings.TrimSuffix(base, filepath.Ext(base)) + name[1:]
+	}
+	track.File = filepath.Join(dir, name)
+	track.Indexes = []Index{{Number: 1, Position: track.Offset / int64(track.SectorSize)}}
+	return track, nil
+}
+
+// mdsString reads a NUL terminated file name, UTF-16 if wide is set.
+func mdsString(data []byte, offset int, wide bool) (string, error) {
+	if offset <= 0 || offset >= len(data) {
+		return "", fmt.Errorf("invalid file name offset %d", offset)
+	}
+	if !wide {
+		name := data[offset:]
+		if end := strings.IndexByte(string(name), 0); end >= 0 {
+			name = name[:end]
+		}
+		return string(name), nil
+	}
+	var units []uint16
+	for i := offset; i+1 < len(data); i += 2 {
+		unit := binary.LittleEndian.Uint16(data[i : i+2])
+		if unit == 0 {
+			break
+		}
+		units = append(units, unit)
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// OpenMDS opens an Alcohol 120% descriptor and its data files.
+func OpenMDS(path string) (*Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	img, err := ParseMDS(data, filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if _, err := img.openFiles(); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// openFiles opens the data files of the tracks and returns their sizes by name.
+func (img *Image) openFiles() (map[string]int64, error) {
+	files := make(map[string]*os.File)
+	sizes := make(map[string]int64)
+	for _, track := range img.Tracks {
+		if data, ok := files[track.File]; ok {
+			track.reader = data
+			continue
+		}
+		data, err := os.Open(track.File)
+		if err != nil {
+			img.Close()
+			return nil, err
+		}
+		img.closers = append(img.closers, data)
+		info, err := data.Stat()
+		if err != nil {
+			img.Close()
+			return nil, err
+		}
+		files[track.File] = data
+		sizes[track.File] = info.Size()
+		track.reader = data
+	}
+	return sizes, nil
+}
@@ -0,0 +1,228 @@
+package cdimage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Nero images hold the track data followed by a chain of chunks describing the layout. The file ends with a footer
+// pointing at the first chunk, "NER5" and a 64-bit offset for version 2 images or "NERO" and a 32-bit offset for
+// version 1 images. Each chunk is a 4 byte id and a 32-bit size followed by the chunk data, all big-endian.
+//
+// Disc-at-once sessions are described by DAOX (DAOI in version 1) chunks:
+//
+//	BP 1 - 4:   Chunk size
+//	BP 5 - 18:  UPC
+//	BP 19 - 20: TOC type
+//	BP 21:      First track
+//	BP 22:      Last track
+//	BP 23 -:    Tracks, 42 bytes each (30 for DAOI): ISRC (12), sector size (2), mode (1), reserved (3), then the byte
+//	            offsets of INDEX 00, INDEX 01 and the end of the track, 64-bit (32-bit for DAOI)
+//
+// Track-at-once sessions are described by ETN2 (ETNF) chunks holding 32 byte (20 byte) entries: data offset, data
+// length, mode and start sector.
+const (
+	NRG_FOOTER_V1 = "NERO"
+	NRG_FOOTER_V2 = "NER5"
+
+	nrgDAOHeaderSize = 22
+)
+
+// nrgModes maps the Nero track mode codes to the track modes and sector sizes they store.
+var nrgModes = map[byte]struct {
+	mode TrackMode
+	size int
+}{
+	0x00: {MODE1_2048, COOKED_SECTOR_SIZE},
+	0x02: {MODE2_2048, COOKED_SECTOR_SIZE},
+	0x03: {MODE2_2336, RAW_SECTOR_SIZE - MODE1_DATA_OFFSET},
+	0x05: {MODE1_2352, RAW_SECTOR_SIZE},
+	0x06: {MODE2_2352, RAW_SECTOR_SIZE},
+	0x07: {MODE_AUDIO, RAW_SECTOR_SIZE},
+	0x0F: {MODE1_2352, RAW_SECTOR_SIZE + SUBCHANNEL_SIZE},
+	0x10: {MODE_AUDIO, RAW_SECTOR_SIZE + SUBCHANNEL_SIZE},
+	0x11: {MODE2_2352, RAW_SECTOR_SIZE + SUBCHANNEL_SIZE},
+}
+
+// nrgChunkOffset returns the offset of the first chunk of a Nero image, false if the reader is not a Nero image.
+func nrgChunkOffset(r io.ReaderAt, size int64) (int64, bool) {
+	var footer [12]byte
+	if size < int64(len(footer)) {
+		return 0, false
+	}
+	if _, err := r.ReadAt(footer[:], size-int64(len(footer))); err != nil {
+		return 0, false
+	}
+	if string(footer[0:4]) == NRG_FOOTER_V2 {
+		return int64(binary.BigEndian.Uint64(footer[4:12])), true
+	}
+	if string(footer[4:8]) == NRG_FOOTER_V1 {
+		return int64(binary.BigEndian.Uint32(footer[8:12])), true
+	}
+	return 0, false
+}
+
+// IsNRG returns true if the reader holds a Nero image.
+func IsNRG(r io.ReaderAt, size int64) bool {
+	_, ok := nrgChunkOffset(r, size)
+	return ok
+}
+
+// ParseNRG parses the chunks of a Nero image. The tracks read from the reader.
+func ParseNRG(r io.ReaderAt, size int64) (*Image, error) {
+	offset, ok := nrgChunkOffset(r, size)
+	if !ok {
+		return nil, errors.New("no Nero footer found")
+	}
+
+	img := &Image{}
+	session := 1
+	for offset+8 <= size {
+		var header [8]byte
+		if _, err := r.ReadAt(header[:], offset); err != nil {
+			return nil, fmt.Errorf("failed to read chunk at offset %d: %w", offset, err)
+		}
+		id := string(header[0:4])
+		length := int64(binary.BigEndian.Uint32(header[4:8]))
+		if id == "END!" {
+			break
+		}
+		if offset+8+length > size {
+			return nil, fmt.Errorf("chunk %s at offset %d exceeds the image", id, offset)
+		}
+
+		data := make([]byte, length)
+		if _, err := r.ReadAt(data, offset+8); err != nil {
+			return nil, fmt.Errorf("failed to read chunk %s at offset %d: %w", id, offset, err)
+		}
+
+		var err error
+		switch id {
+		case "DAOX":
+			err = img.parseNRGDAO(data, 42, 8, session, r)
+			session++
+		case "DAOI":
+			err = img.parseNRGDAO(data, 30, 4, session, r)
+			session++
+		case "ETN2":
+			err = img.parseNRGETN(data, 32, 8, session, r)
+			session++
+		case "ETNF":
+			err = img.parseNRGETN(data, 20, 4, session, r)
+			session++
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse chunk %s: %w", id, err)
+		}
+		offset += 8 + length
+	}
+
+	if len(img.Tracks) == 0 {
+		return nil, errors.New("no tracks found in Nero image")
+	}
+	img.number()
+	return img, nil
+}
+
+// parseNRGDAO adds the tracks of a DAOX or DAOI chunk.
+func (img *Image) parseNRGDAO(data []byte, entrySize, offsetSize, session int, r io.ReaderAt) error {
+	if len(data) < nrgDAOHeaderSize {
+		return errors.New("chunk is too short")
+	}
+	for pos := nrgDAOHeaderSize; pos+entrySize <= len(data); pos += entrySize {
+		entry := data[pos : pos+entrySize]
+		mode, ok := nrgModes[entry[14]]
+		if !ok {
+			return fmt.Errorf("unsupported track mode 0x%02X", entry[14])
+		}
+		index0 := nrgOffset(entry[18:], offsetSize)
+		index1 := nrgOffset(entry[18+offsetSize:], offsetSize)
+		end := nrgOffset(entry[18+2*offsetSize:], offsetSize)
+		if index1 < index0 || end < index1 {
+			return fmt.Errorf("invalid offsets for track %d", len(img.Tracks)+1)
+		}
+
+		size := int64(mode.size)
+		img.Tracks = append(img.Tracks, &Track{
+			Mode:       mode.mode,
+			SectorSize: mode.size,
+			Session:    session,
+			Indexes:    []Index{{Number: 0, Position: index0 / size}, {Number: 1, Position: index1 / size}},
+			Length:     (end - index1) / size,
+			Offset:     index1,
+			reader:     r,
+		})
+	}
+	return nil
+}
+
+// parseNRGETN adds the tracks of an ETN2 or ETNF chunk.
+func (img *Image) parseNRGETN(data []byte, entrySize, offsetSize, session int, r io.ReaderAt) error {
+	for pos := 0; pos+entrySize <= len(data); pos += entrySize {
+		entry := data[pos : pos+entrySize]
+		offset := nrgOffset(entry, offsetSize)
+		length := nrgOffset(entry[offsetSize:], offsetSize)
+		code := binary.BigEndian.Uint32(entry[2*offsetSize:])
+		mode, ok := nrgModes[byte(code)]
+		if !ok || code > 0xFF {
+			return fmt.Errorf("unsupported track mode 0x%02X", code)
+		}
+
+		size := int64(mode.size)
+		img.Tracks = append(img.Tracks, &Track{
+			Mode:       mode.mode,
+			SectorSize: mode.size,
+			Session:    session,
+			Indexes:    []Index{{Number: 1, Position: offset / size}},
+			Length:     length / size,
+			Offset:     offset,
+			reader:     r,
+		})
+	}
+	return nil
+}
+
+// nrgOffset decodes a big-endian 32 or 64-bit offset.
+func nrgOffset(data []byte, size int) int64 {
+	if size == 8 {
+		return int64(binary.BigEndian.Uint64(data))
+	}
+	return int64(binary.BigEndian.Uint32(data))
+}
+
+// number assigns track numbers in disc order and computes the absolute start of each track from the stored pregaps.
+func (img *Image) number() {
+	var lba int64
+	for i, track := range img.Tracks {
+		track.Number = i + 1
+		track.Start = lba + track.PregapLength()
+		lba = track.Start + track.Length
+	}
+}
+
+// OpenNRG opens a Nero image.
+func OpenNRG(path string) (*Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	img, err := ParseNRG(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for _, track := range img.Tracks {
+		track.File = path
+	}
+	img.closers = []io.Closer{f}
+	return img, nil
+}
@@ -0,0 +1,187 @@
+package cdimage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rstms/iso-kit/pkg/consts"
+)
+
+// DataReader is an io.ReaderAt over the user data of the data tracks of an image, presenting them as a cooked image of
+// 2048 byte sectors addressed by their sector address on the disc relative to INDEX 01 of the first track, the
+// addressing used by the ISO9660 filesystem. The system area and the volume descriptor set are read relative to the
+// start of the reader's track, so the filesystem of a later session is found at sector 16 like that of the first.
+// Sync patterns, headers, subheaders, EDC and ECC are stripped. The EDC is not checked on read, use VerifySector for
+// that. Errors are not corrected with the ECC.
+type DataReader struct {
+	image *Image
+	track *Track
+	// origin is the Start of the first track, sector 0 of the filesystem
+	origin int64
+	// descriptors is the number of sectors at the start of the track holding the system area and the volume
+	// descriptor set, zero when the track is the first one
+	descriptors int64
+}
+
+// NewDataReader returns a reader over the user data of the track.
+func NewDataReader(img *Image, track *Track) *DataReader {
+	r := &DataReader{image: img, track: track, origin: track.Start}
+	if len(img.Tracks) > 0 {
+		r.origin = img.Tracks[0].Start
+	}
+	if track.Start > r.origin {
+		r.descriptors = r.descriptorSetEnd()
+	}
+	return r
+}
+
+// descriptorSetEnd returns the sector following the volume descriptor set terminator recorded in the track, zero if
+// the track does not start with an ISO9660 volume descriptor set.
+func (r *DataReader) descriptorSetEnd() int64 {
+	sector := make([]byte, r.track.SectorSize)
+	for lba := int64(consts.ISO9660_SYSTEM_AREA_SECTORS); lba < r.track.Length; lba++ {
+		if err := r.track.ReadSector(lba, sector); err != nil {
+			return 0
+		}
+		data, _ := r.track.UserData(sector)
+		if string(data[1:6]) != consts.ISO9660_STD_IDENTIFIER {
+			return 0
+		}
+		if data[0] == 255 {
+			return lba + 1
+		}
+	}
+	return 0
+}
+
+// DataReader returns a reader over the user data of the image's data tracks, reading the filesystem of the track
+// returned by DataTrack.
+func (img *Image) DataReader() (*DataReader, error) {
+	track, err := img.DataTrack()
+	if err != nil {
+		return nil, err
+	}
+	return NewDataReader(img, track), nil
+}
+
+// Image returns the image the reader belongs to.
+func (r *DataReader) Image() *Image {
+	return r.image
+}
+
+// Track returns the track the reader reads from.
+func (r *DataReader) Track() *Track {
+	return r.track
+}
+
+// Size returns the size of the user data in bytes, up to the end of the reader's track.
+func (r *DataReader) Size() int64 {
+	return (r.track.Start - r.origin + r.track.Length) * COOKED_SECTOR_SIZE
+}
+
+// locate returns the data track recording the sector with the given filesystem address and the sector number relative
+// to its INDEX 01.
+func (r *DataReader) locate(lba int64) (*Track, int64, error) {
+	if lba < r.descriptors {
+		return r.track, lba, nil
+	}
+	address := r.origin + lba
+	for _, track := range r.image.Tracks {
+		if !track.Mode.IsAudio() && address >= track.Start && address < track.Start+track.Length {
+			return track, address - track.Start, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("sector %d is not recorded in a data track", lba)
+}
+
+func (r *DataReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("cdimage: negative offset")
+	}
+
+	sector := make([]byte, RAW_SECTOR_SIZE+SUBCHANNEL_SIZE)
+	n := 0
+	for n < len(p) && off < r.Size() {
+		lba, within := off/COOKED_SECTOR_SIZE, off%COOKED_SECTOR_SIZE
+		track, relative, err := r.locate(lba)
+		if err != nil {
+			return n, err
+		}
+		if err := track.ReadSector(relative, sector); err != nil {
+			return n, err
+		}
+		data, _ := track.UserData(sector[:track.SectorSize])
+		m := copy(p[n:], data[within:COOKED_SECTOR_SIZE])
+		n += m
+		off += int64(m)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// HasForm2 returns true if the track stores the full payload of Mode 2 Form 2 sectors.
+func (r *DataReader) HasForm2() bool {
+	return r.track.hasForm2()
+}
+
+func (t *Track) hasForm2() bool {
+	switch t.Mode {
+	case MODE2_2324, MODE2_2336, MODE2_2352, MODE_CDI_2336, MODE_CDI_2352:
+		return true
+	}
+	return false
+}
+
+// Form2Reader returns a reader over the 2324 byte payloads of count sectors starting at the given sector address, as
+// recorded by Mode 2 Form 2 files such as MPEG streams on Video CDs. It returns nil if the track recording the sectors
+// does not store Form 2 payloads.
+func (r *DataReader) Form2Reader(start, count int64) *Form2Reader {
+	track, relative, err := r.locate(start)
+	if err != nil || !track.hasForm2() {
+		return nil
+	}
+	return &Form2Reader{track: track, start: relative, count: count}
+}
+
+// Close closes the image.
+func (r *DataReader) Close() error {
+	return r.image.Close()
+}
+
+// Form2Reader is an io.ReaderAt over the Form 2 payloads of consecutive sectors of a track.
+type Form2Reader struct {
+	track *Track
+	start int64
+	count int64
+}
+
+// Size returns the size of the payloads in bytes.
+func (r *Form2Reader) Size() int64 {
+	return r.count * FORM2_SECTOR_SIZE
+}
+
+func (r *Form2Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("cdimage: negative offset")
+	}
+
+	sector := make([]byte, r.track.SectorSize)
+	n := 0
+	for n < len(p) && off < r.Size() {
+		lba, within := off/FORM2_SECTOR_SIZE, off%FORM2_SECTOR_SIZE
+		if err := r.track.ReadSector(r.start+lba, sector); err != nil {
+			return n, err
+		}
+		data, _ := r.track.Form2Data(sector)
+		m := copy(p[n:], data[within:])
+		n += m
+		off += int64(m)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,111 @@
+package cdimage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// edcTable is the lookup table of the EDC, a CRC-32 with the polynomial x^32 + x^31 + x^16 + x^15 + x^4 + x^3 + x + 1
+// computed least significant bit first.
+var edcTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		edc := uint32(i)
+		for j := 0; j < 8; j++ {
+			if edc&1 != 0 {
+				edc = edc>>1 ^ 0xD8018001
+			} else {
+				edc >>= 1
+			}
+		}
+		table[i] = edc
+	}
+	return table
+}()
+
+// EDC computes the error detection code of the given bytes.
+func EDC(data []byte) uint32 {
+	var edc uint32
+	for _, b := range data {
+		edc = edc>>8 ^ edcTable[byte(edc)^b]
+	}
+	return edc
+}
+
+// VerifySector checks the sync pattern and the EDC of a raw 2352 byte data sector. The ECC is not checked. Form 2
+// sectors recorded without an EDC (a zero EDC field) pass.
+func VerifySector(sector []byte) error {
+	if len(sector) < RAW_SECTOR_SIZE {
+		return fmt.Errorf("sector is %d bytes, expected %d", len(sector), RAW_SECTOR_SIZE)
+	}
+	if !IsRawSector(sector) {
+		return errors.New("sector has no sync pattern")
+	}
+
+	var start, end int
+	switch sector[SYNC_SIZE+3] {
+	case 0:
+		return nil
+	case 1:
+		start, end = 0, mode1EDCOffset
+	case 2:
+		start, end = MODE1_DATA_OFFSET, mode2Form1EDCOffset
+		if sector[xaSubmodeOffset]&XA_SUBMODE_FORM2 != 0 {
+			end = mode2Form2EDCOffset
+		}
+	default:
+		return fmt.Errorf("invalid sector mode %d", sector[SYNC_SIZE+3])
+	}
+
+	stored := binary.LittleEndian.Uint32(sector[end : end+4])
+	if stored == 0 && end == mode2Form2EDCOffset {
+		return nil
+	}
+	if computed := EDC(sector[start:end]); computed != stored {
+		return fmt.Errorf("EDC mismatch: stored 0x%08X, computed 0x%08X", stored, computed)
+	}
+	return nil
+}
+
+// UserData returns the user data of a sector stored in the track's layout and whether it is a Mode 2 Form 2 sector.
+// Raw sectors are decoded according to the mode in their header.
+func (t *Track) UserData(sector []byte) ([]byte, bool) {
+	switch t.Mode {
+	case MODE1_2048, MODE2_2048:
+		return sector[:COOKED_SECTOR_SIZE], false
+	case MODE2_2324:
+		return sector[:FORM2_SECTOR_SIZE], true
+	case MODE2_2336, MODE_CDI_2336:
+		return xaUserData(sector)
+	case MODE1_2352, MODE2_2352, MODE_CDI_2352:
+		if sector[SYNC_SIZE+3] == 1 {
+			return sector[MODE1_DATA_OFFSET : MODE1_DATA_OFFSET+COOKED_SECTOR_SIZE], false
+		}
+		return xaUserData(sector[MODE1_DATA_OFFSET:])
+	default:
+		return sector[:RAW_SECTOR_SIZE], false
+	}
+}
+
+// xaUserData returns the user data following an XA subheader.
+func xaUserData(sector []byte) ([]byte, bool) {
+	if sector[2]&XA_SUBMODE_FORM2 != 0 {
+		return sector[XA_SUBHEADER_SIZE : XA_SUBHEADER_SIZE+FORM2_SECTOR_SIZE], true
+	}
+	return sector[XA_SUBHEADER_SIZE : XA_SUBHEADER_SIZE+COOKED_SECTOR_SIZE], false
+}
+
+// Form2Data returns the 2324 byte payload area following the XA subheader of a sector stored in the track's layout,
+// false if the layout does not store it. For Form 1 sectors the area holds the user data followed by the EDC and ECC.
+func (t *Track) Form2Data(sector []byte) ([]byte, bool) {
+	switch t.Mode {
+	case MODE2_2324:
+		return sector[:FORM2_SECTOR_SIZE], true
+	case MODE2_2336, MODE_CDI_2336:
+		return sector[XA_SUBHEADER_SIZE : XA_SUBHEADER_SIZE+FORM2_SECTOR_SIZE], true
+	case MODE2_2352, MODE_CDI_2352:
+		return sector[MODE2_DATA_OFFSET : MODE2_DATA_OFFSET+FORM2_SECTOR_SIZE], true
+	}
+	return nil, false
+}
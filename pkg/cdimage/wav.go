@@ -0,0 +1,91 @@
+package cdimage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CD-DA audio is 44.1 kHz 16-bit stereo PCM, 588 samples per sector.
+const (
+	AUDIO_SAMPLE_RATE     = 44100
+	AUDIO_CHANNELS        = 2
+	AUDIO_BITS_PER_SAMPLE = 16
+	WAV_HEADER_SIZE       = 44
+)
+
+// WAVHeader returns the RIFF header of a WAV file holding dataSize bytes of CD-DA audio.
+func WAVHeader(dataSize uint32) []byte {
+	blockAlign := AUDIO_CHANNELS * AUDIO_BITS_PER_SAMPLE / 8
+	header := make([]byte, WAV_HEADER_SIZE)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], WAV_HEADER_SIZE-8+dataSize)
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], 1) // PCM
+	binary.LittleEndian.PutUint16(header[22:24], AUDIO_CHANNELS)
+	binary.LittleEndian.PutUint32(header[24:28], AUDIO_SAMPLE_RATE)
+	binary.LittleEndian.PutUint32(header[28:32], uint32(AUDIO_SAMPLE_RATE*blockAlign))
+	binary.LittleEndian.PutUint16(header[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:36], AUDIO_BITS_PER_SAMPLE)
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], dataSize)
+	return header
+}
+
+// WriteWAV writes the audio of the track from INDEX 01 to its end as a WAV file. Samples of MOTOROLA files are swapped
+// to little-endian and subchannel data is dropped.
+func (t *Track) WriteWAV(w io.Writer) error {
+	if !t.Mode.IsAudio() {
+		return fmt.Errorf("track %d is not an audio track", t.Number)
+	}
+
+	if _, err := w.Write(WAVHeader(uint32(t.Length * RAW_SECTOR_SIZE))); err != nil {
+		return err
+	}
+
+	sector := make([]byte, t.SectorSize)
+	for lba := int64(0); lba < t.Length; lba++ {
+		if err := t.ReadSector(lba, sector); err != nil {
+			return err
+		}
+		samples := sector[:RAW_SECTOR_SIZE]
+		if t.SwapBytes {
+			for i := 0; i < len(samples); i += 2 {
+				samples[i], samples[i+1] = samples[i+1], samples[i]
+			}
+		}
+		if _, err := w.Write(samples); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExtractAudio writes each audio track of the image to the directory as trackNN.wav and returns the paths written.
+func (img *Image) ExtractAudio(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	var paths []string
+	for _, track := range img.AudioTracks() {
+		path := filepath.Join(dir, fmt.Sprintf("track%02d.wav", track.Number))
+		f, err := os.Create(path)
+		if err != nil {
+			return paths, fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		err = track.WriteWAV(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return paths, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,173 @@
+package compressed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Format is a compressed image container format.
+type Format string
+
+const (
+	FORMAT_NONE Format = ""
+	// FORMAT_CSO is a CISO image of deflate (version 1) or deflate and LZ4 (version 2) compressed blocks
+	FORMAT_CSO Format = "cso"
+	// FORMAT_ZSO is a ZISO image of LZ4 compressed blocks
+	FORMAT_ZSO Format = "zso"
+	// FORMAT_ZSTD is a zstd seekable format file, independent frames followed by a seek table
+	FORMAT_ZSTD Format = "zstd"
+	// FORMAT_XZ is an xz file, random access is by block so files written with a block size seek efficiently
+	FORMAT_XZ Format = "xz"
+	// FORMAT_GZIP is a gzip file, it has no index and is decompressed into a temporary spill file
+	FORMAT_GZIP Format = "gzip"
+)
+
+var (
+	xzMagic   = []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}
+	gzipMagic = []byte{0x1F, 0x8B}
+)
+
+const zstdFrameMagic = 0xFD2FB528
+
+// Reader is a random access reader over the decompressed contents of an image.
+type Reader interface {
+	io.ReaderAt
+	io.Closer
+	// Size returns the size of the decompressed image in bytes
+	Size() int64
+}
+
+// Detect returns the container format of the reader by its signature. Zstandard files are only recognized when they
+// carry a seek table.
+func Detect(r io.ReaderAt, size int64) Format {
+	header := make([]byte, 6)
+	n, _ := r.ReadAt(header, 0)
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte(CSO_MAGIC)):
+		return FORMAT_CSO
+	case bytes.HasPrefix(header, []byte(ZSO_MAGIC)):
+		return FORMAT_ZSO
+	case bytes.HasPrefix(header, xzMagic):
+		return FORMAT_XZ
+	case bytes.HasPrefix(header, gzipMagic):
+		return FORMAT_GZIP
+	case len(header) >= 4 && binary.LittleEndian.Uint32(header) == zstdFrameMagic:
+		if hasSeekTable(r, size) {
+			return FORMAT_ZSTD
+		}
+	}
+	return FORMAT_NONE
+}
+
+// NewReader returns a random access reader over the decompressed contents of r in the given format.
+func NewReader(r io.ReaderAt, size int64, format Format) (Reader, error) {
+	switch format {
+	case FORMAT_CSO, FORMAT_ZSO:
+		return NewCSOReader(r)
+	case FORMAT_ZSTD:
+		return NewZstdReader(r, size)
+	case FORMAT_XZ:
+		return NewXZReader(r, size)
+	case FORMAT_GZIP:
+		return NewSpillReader(io.NewSectionReader(r, 0, size))
+	}
+	return nil, fmt.Errorf("unsupported compression format %q", format)
+}
+
+// Open opens a compressed image. The file is closed when the returned reader is closed.
+func Open(path string) (Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	format := Detect(f, info.Size())
+	if format == FORMAT_NONE {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a compressed image", path)
+	}
+	r, err := NewReader(f, info.Size(), format)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return &fileReader{Reader: r, file: f}, nil
+}
+
+// fileReader closes the underlying file together with the reader.
+type fileReader struct {
+	Reader
+	file *os.File
+}
+
+func (r *fileReader) Close() error {
+	return errors.Join(r.Reader.Close(), r.file.Close())
+}
+
+// blockCache decompresses blocks on demand and keeps the most recently used one, image parsers read sequentially within
+// a block far more often than they jump between blocks.
+type blockCache struct {
+	mu     sync.Mutex
+	size   int64
+	block  int
+	start  int64
+	data   []byte
+	locate func(off int64) (block int, start int64)
+	decode func(block int, dst []byte) ([]byte, error)
+}
+
+// newBlockCache returns a cache over size bytes of decompressed data. locate maps an offset to the block holding it
+// and the offset the block starts at, decode decompresses a block appending to dst.
+func newBlockCache(size int64, locate func(off int64) (int, int64), decode func(block int, dst []byte) ([]byte, error)) *blockCache {
+	return &blockCache{size: size, block: -1, locate: locate, decode: decode}
+}
+
+// Size returns the size of the decompressed data.
+func (c *blockCache) Size() int64 {
+	return c.size
+}
+
+func (c *blockCache) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("compressed: negative offset")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for n < len(p) && off < c.size {
+		if c.block < 0 || off < c.start || off >= c.start+int64(len(c.data)) {
+			block, start := c.locate(off)
+			data, err := c.decode(block, c.data[:0])
+			if err != nil {
+				c.block = -1
+				return n, fmt.Errorf("failed to decompress block %d: %w", block, err)
+			}
+			if off >= start+int64(len(data)) {
+				c.block = -1
+				return n, fmt.Errorf("block %d is truncated", block)
+			}
+			c.block, c.start, c.data = block, start, data
+		}
+		m := copy(p[n:], c.data[off-c.start:])
+		n += m
+		off += int64(m)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
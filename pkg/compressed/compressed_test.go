@@ -0,0 +1,142 @@
+package compressed
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+// testImage returns compressible data with some random blocks.
+func testImage(size int) []byte {
+	data := make([]byte, size)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < size; i += 4096 {
+		if i%(3*4096) == 0 {
+			rng.Read(data[i:min(i+4096, size)])
+		} else {
+			copy(data[i:min(i+4096, size)], bytes.Repeat([]byte("iso-kit "), 512))
+		}
+	}
+	return data
+}
+
+// requireContents reads the reader back to front in uneven chunks and compares it with want.
+func requireContents(t *testing.T, r Reader, want []byte) {
+	require.Equal(t, int64(len(want)), r.Size())
+	got := make([]byte, len(want))
+	for off := len(want); off > 0; off -= 3000 {
+		start := max(off-3000, 0)
+		n, err := r.ReadAt(got[start:off], int64(start))
+		require.NoError(t, err)
+		require.Equal(t, off-start, n)
+	}
+	require.Equal(t, want, got)
+
+	_, err := r.ReadAt(make([]byte, 10), int64(len(want)-5))
+	require.ErrorIs(t, err, io.EOF)
+}
+
+type bufferWriterAt struct {
+	data []byte
+}
+
+func (b *bufferWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if need := int(off) + len(p); need > len(b.data) {
+		b.data = append(b.data, make([]byte, need-len(b.data))...)
+	}
+	return copy(b.data[off:], p), nil
+}
+
+func TestCSO(t *testing.T) {
+	want := testImage(50000)
+	var out bufferWriterAt
+	require.NoError(t, WriteCSO(&out, bytes.NewReader(want), int64(len(want)), CSO_BLOCK_SIZE))
+	require.Less(t, len(out.data), len(want))
+
+	cso := bytes.NewReader(out.data)
+	require.Equal(t, FORMAT_CSO, Detect(cso, cso.Size()))
+	r, err := NewReader(cso, cso.Size(), FORMAT_CSO)
+	require.NoError(t, err)
+	defer r.Close()
+	requireContents(t, r, want)
+}
+
+func TestZSO(t *testing.T) {
+	// One LZ4 block of 4 literals and a 12 byte match, one block stored uncompressed
+	want := append(bytes.Repeat([]byte("abcd"), 4), bytes.Repeat([]byte{'z'}, 16)...)
+	lz4Block := []byte{0x48, 'a', 'b', 'c', 'd', 0x04, 0x00}
+
+	var image bytes.Buffer
+	header := make([]byte, CSO_HEADER_SIZE)
+	copy(header, ZSO_MAGIC)
+	binary.LittleEndian.PutUint32(header[4:8], CSO_HEADER_SIZE)
+	binary.LittleEndian.PutUint64(header[8:16], uint64(len(want)))
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	header[20] = 1
+	image.Write(header)
+	first := uint32(CSO_HEADER_SIZE + 3*4)
+	for _, entry := range []uint32{first, (first + uint32(len(lz4Block))) | csoIndexFlag, first + uint32(len(lz4Block)) + 16} {
+		binary.Write(&image, binary.LittleEndian, entry)
+	}
+	image.Write(lz4Block)
+	image.Write(want[16:])
+
+	zso := bytes.NewReader(image.Bytes())
+	require.Equal(t, FORMAT_ZSO, Detect(zso, zso.Size()))
+	r, err := NewReader(zso, zso.Size(), FORMAT_ZSO)
+	require.NoError(t, err)
+	defer r.Close()
+	requireContents(t, r, want)
+}
+
+func TestZstd(t *testing.T) {
+	want := testImage(100000)
+	var out bytes.Buffer
+	require.NoError(t, WriteZstd(&out, bytes.NewReader(want), 16384))
+
+	zst := bytes.NewReader(out.Bytes())
+	require.Equal(t, FORMAT_ZSTD, Detect(zst, zst.Size()))
+	r, err := NewReader(zst, zst.Size(), FORMAT_ZSTD)
+	require.NoError(t, err)
+	defer r.Close()
+	requireContents(t, r, want)
+}
+
+func TestXZ(t *testing.T) {
+	want := testImage(100000)
+	var out bytes.Buffer
+	w, err := xz.WriterConfig{BlockSize: 16384}.NewWriter(&out)
+	require.NoError(t, err)
+	_, err = w.Write(want)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	xzr := bytes.NewReader(out.Bytes())
+	require.Equal(t, FORMAT_XZ, Detect(xzr, xzr.Size()))
+	r, err := NewReader(xzr, xzr.Size(), FORMAT_XZ)
+	require.NoError(t, err)
+	defer r.Close()
+	require.Greater(t, len(r.(*XZReader).blocks), 1)
+	requireContents(t, r, want)
+}
+
+func TestGzip(t *testing.T) {
+	want := testImage(20000)
+	var out bytes.Buffer
+	w := gzip.NewWriter(&out)
+	w.Write(want)
+	require.NoError(t, w.Close())
+
+	gz := bytes.NewReader(out.Bytes())
+	require.Equal(t, FORMAT_GZIP, Detect(gz, gz.Size()))
+	r, err := NewReader(gz, gz.Size(), FORMAT_GZIP)
+	require.NoError(t, err)
+	requireContents(t, r, want)
+	require.NoError(t, r.Close())
+}
@@ -0,0 +1,171 @@
+package compressed
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// CSO and ZSO images split the image into blocks compressed independently. All values are little-endian.
+//
+// Header, 24 bytes:
+//
+//	BP 1 - 4:   Magic "CISO" or "ZISO"
+//	BP 5 - 8:   Header size
+//	BP 9 - 16:  Uncompressed size
+//	BP 17 - 20: Block size
+//	BP 21:      Version
+//	BP 22:      Index alignment, block offsets are shifted left by this many bits
+//
+// The header is followed by an index of one 32-bit entry per block plus one marking the end of the last block. The low
+// 31 bits hold the shifted offset of the block, the high bit marks a block stored uncompressed (CSO version 1 and ZSO)
+// or LZ4 compressed (CSO version 2). Other blocks are raw deflate (CSO) or LZ4 (ZSO) streams, CSO version 2 blocks as
+// large as the block size are stored uncompressed.
+const (
+	CSO_MAGIC       = "CISO"
+	ZSO_MAGIC       = "ZISO"
+	CSO_HEADER_SIZE = 24
+	CSO_BLOCK_SIZE  = 2048
+
+	csoIndexFlag = 0x80000000
+)
+
+// CSOReader is a random access reader over a CSO or ZSO image.
+type CSOReader struct {
+	*blockCache
+	reader    io.ReaderAt
+	magic     string
+	version   uint8
+	align     uint8
+	blockSize int64
+	index     []uint32
+}
+
+// NewCSOReader reads the header and block index of a CSO or ZSO image.
+func NewCSOReader(r io.ReaderAt) (*CSOReader, error) {
+	var header [CSO_HEADER_SIZE]byte
+	if _, err := r.ReadAt(header[:], 0); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	cr := &CSOReader{
+		reader:    r,
+		magic:     string(header[0:4]),
+		version:   header[20],
+		align:     header[21],
+		blockSize: int64(binary.LittleEndian.Uint32(header[16:20])),
+	}
+	if cr.magic != CSO_MAGIC && cr.magic != ZSO_MAGIC {
+		return nil, errors.New("not a CSO or ZSO image")
+	}
+	size := int64(binary.LittleEndian.Uint64(header[8:16]))
+	if cr.blockSize == 0 || cr.blockSize&(cr.blockSize-1) != 0 {
+		return nil, fmt.Errorf("invalid block size %d", cr.blockSize)
+	}
+
+	blocks := (size + cr.blockSize - 1) / cr.blockSize
+	index := make([]byte, (blocks+1)*4)
+	if _, err := r.ReadAt(index, CSO_HEADER_SIZE); err != nil {
+		return nil, fmt.Errorf("failed to read block index: %w", err)
+	}
+	cr.index = make([]uint32, blocks+1)
+	for i := range cr.index {
+		cr.index[i] = binary.LittleEndian.Uint32(index[i*4:])
+	}
+
+	locate := func(off int64) (int, int64) {
+		block := off / cr.blockSize
+		return int(block), block * cr.blockSize
+	}
+	cr.blockCache = newBlockCache(size, locate, cr.decodeBlock)
+	return cr, nil
+}
+
+// decodeBlock reads and decompresses a block.
+func (cr *CSOReader) decodeBlock(block int, dst []byte) ([]byte, error) {
+	start := int64(cr.index[block]&^csoIndexFlag) << cr.align
+	end := int64(cr.index[block+1]&^csoIndexFlag) << cr.align
+	if end < start {
+		return nil, errors.New("invalid block index")
+	}
+	stored := make([]byte, end-start)
+	if n, err := cr.reader.ReadAt(stored, start); n < len(stored) {
+		return nil, err
+	}
+
+	want := min(cr.blockSize, cr.size-int64(block)*cr.blockSize)
+	flagged := cr.index[block]&csoIndexFlag != 0
+	switch {
+	case cr.magic == ZSO_MAGIC && !flagged, cr.magic == CSO_MAGIC && cr.version >= 2 && flagged:
+		out := append(dst, make([]byte, want)...)
+		n, err := decodeLZ4(stored, out[len(dst):])
+		return out[:len(dst)+n], err
+	case flagged, cr.version >= 2 && int64(len(stored)) >= cr.blockSize:
+		return append(dst, stored[:min(int64(len(stored)), want)]...), nil
+	default:
+		out := bytes.NewBuffer(dst)
+		_, err := io.CopyN(out, flate.NewReader(bytes.NewReader(stored)), want)
+		return out.Bytes(), err
+	}
+}
+
+// Close releases the block cache, the underlying reader is not closed.
+func (cr *CSOReader) Close() error {
+	cr.data = nil
+	return nil
+}
+
+// WriteCSO compresses size bytes of r into a version 1 CSO image written to w. Blocks are deflate compressed and stored
+// uncompressed when compression does not shrink them.
+func WriteCSO(w io.WriterAt, r io.ReaderAt, size int64, blockSize int) error {
+	if blockSize <= 0 || blockSize&(blockSize-1) != 0 {
+		return fmt.Errorf("invalid block size %d", blockSize)
+	}
+	blocks := (size + int64(blockSize) - 1) / int64(blockSize)
+
+	header := make([]byte, CSO_HEADER_SIZE)
+	copy(header, CSO_MAGIC)
+	binary.LittleEndian.PutUint32(header[4:8], CSO_HEADER_SIZE)
+	binary.LittleEndian.PutUint64(header[8:16], uint64(size))
+	binary.LittleEndian.PutUint32(header[16:20], uint32(blockSize))
+	header[20] = 1
+	if _, err := w.WriteAt(header, 0); err != nil {
+		return err
+	}
+
+	index := make([]byte, (blocks+1)*4)
+	offset := int64(CSO_HEADER_SIZE + len(index))
+	data := make([]byte, blockSize)
+	var compressed bytes.Buffer
+	fw, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	for block := int64(0); block < blocks; block++ {
+		if offset > csoIndexFlag-1 {
+			return errors.New("image is too large for an unaligned CSO index")
+		}
+		n := min(int64(blockSize), size-block*int64(blockSize))
+		if m, err := r.ReadAt(data[:n], block*int64(blockSize)); int64(m) < n {
+			return fmt.Errorf("failed to read block %d: %w", block, err)
+		}
+
+		compressed.Reset()
+		fw.Reset(&compressed)
+		fw.Write(data[:n])
+		fw.Close()
+
+		entry, stored := uint32(offset), compressed.Bytes()
+		if int64(len(stored)) >= n {
+			entry, stored = uint32(offset)|csoIndexFlag, data[:n]
+		}
+		binary.LittleEndian.PutUint32(index[block*4:], entry)
+		if _, err := w.WriteAt(stored, offset); err != nil {
+			return err
+		}
+		offset += int64(len(stored))
+	}
+	binary.LittleEndian.PutUint32(index[blocks*4:], uint32(offset))
+
+	_, err := w.WriteAt(index, CSO_HEADER_SIZE)
+	return err
+}
@@ -0,0 +1,65 @@
+package compressed
+
+import "errors"
+
+var errLZ4Corrupt = errors.New("lz4: corrupt block")
+
+// decodeLZ4 decompresses a raw LZ4 block into dst and returns the number of bytes written. Decoding stops once dst is
+// full so the alignment padding following blocks in ZSO images is ignored.
+func decodeLZ4(src, dst []byte) (int, error) {
+	si, di := 0, 0
+	for si < len(src) && di < len(dst) {
+		token := src[si]
+		si++
+
+		// Literals
+		length := int(token >> 4)
+		if length == 15 {
+			for si < len(src) {
+				b := src[si]
+				si++
+				length += int(b)
+				if b != 255 {
+					break
+				}
+			}
+		}
+		if si+length > len(src) {
+			return di, errLZ4Corrupt
+		}
+		di += copy(dst[di:], src[si:si+length])
+		si += length
+		if si >= len(src) || di >= len(dst) {
+			break
+		}
+
+		// Match
+		if si+2 > len(src) {
+			return di, errLZ4Corrupt
+		}
+		offset := int(src[si]) | int(src[si+1])<<8
+		si += 2
+		if offset == 0 || offset > di {
+			return di, errLZ4Corrupt
+		}
+		length = int(token & 0x0F)
+		if length == 15 {
+			for si < len(src) {
+				b := src[si]
+				si++
+				length += int(b)
+				if b != 255 {
+					break
+				}
+			}
+		}
+		length += 4
+
+		// Matches may overlap the bytes they produce so they are copied byte by byte
+		for i := 0; i < length && di < len(dst); i++ {
+			dst[di] = dst[di-offset]
+			di++
+		}
+	}
+	return di, nil
+}
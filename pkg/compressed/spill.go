@@ -0,0 +1,52 @@
+package compressed
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// SpillReader is a random access reader over a gzip stream decompressed into a temporary file. gzip has no index so
+// the whole stream is decompressed when the reader is created.
+type SpillReader struct {
+	file *os.File
+	size int64
+}
+
+// NewSpillReader decompresses the gzip stream into a temporary file, the file is removed when the reader is closed.
+// Concatenated gzip members are decompressed as one stream.
+func NewSpillReader(r io.Reader) (*SpillReader, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	file, err := os.CreateTemp("", "iso-kit-*.iso")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill file: %w", err)
+	}
+	size, err := io.Copy(file, zr)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to decompress gzip stream: %w", err)
+	}
+	return &SpillReader{file: file, size: size}, nil
+}
+
+// Size returns the size of the decompressed data.
+func (sr *SpillReader) Size() int64 {
+	return sr.size
+}
+
+func (sr *SpillReader) ReadAt(p []byte, off int64) (int, error) {
+	return sr.file.ReadAt(p, off)
+}
+
+// Close closes and removes the spill file.
+func (sr *SpillReader) Close() error {
+	return errors.Join(sr.file.Close(), os.Remove(sr.file.Name()))
+}
@@ -0,0 +1,292 @@
+package compressed
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/ulikunitz/xz/lzma"
+)
+
+// xz files are one or more streams, each a 12 byte header, compressed blocks, an index and a 12 byte footer. The index
+// records the unpadded and uncompressed size of every block so a block holding an offset can be located without
+// decompressing the ones before it. Files written by "xz -T" or with "--block-size" hold many blocks, a file with a
+// single block has to be decompressed from its start for every backward seek.
+//
+// Only blocks using the LZMA2 filter alone are supported, BCJ and delta filters are not. Integrity checks are not
+// verified.
+const (
+	xzHeaderSize = 12
+	xzLZMA2      = 0x21
+)
+
+// xzBlock is a block of an xz stream.
+type xzBlock struct {
+	// offset of the block header in the file
+	offset int64
+	// unpadded size of the block, the header, compressed data and check
+	unpadded int64
+	// checkSize is the size of the integrity check following the compressed data
+	checkSize int64
+	// start and size of the decompressed data
+	start int64
+	size  int64
+}
+
+// XZReader is a random access reader over an xz file.
+type XZReader struct {
+	mu     sync.Mutex
+	reader io.ReaderAt
+	blocks []xzBlock
+	size   int64
+	// The decompressor of the current block and its position in the decompressed data
+	block  int
+	stream io.Reader
+	pos    int64
+}
+
+// NewXZReader reads the indexes of the streams of an xz file.
+func NewXZReader(r io.ReaderAt, size int64) (*XZReader, error) {
+	xr := &XZReader{reader: r, block: -1}
+
+	// Streams are read back to front, each footer gives the size of its index
+	end := size
+	var streams [][]xzBlock
+	for end > 0 {
+		// Stream padding is a multiple of four zero bytes
+		var word [4]byte
+		for end >= 4 {
+			if _, err := r.ReadAt(word[:], end-4); err != nil {
+				return nil, err
+			}
+			if word != [4]byte{} {
+				break
+			}
+			end -= 4
+		}
+
+		blocks, start, err := readXZStream(r, end)
+		if err != nil {
+			return nil, err
+		}
+		streams = append([][]xzBlock{blocks}, streams...)
+		end = start
+	}
+
+	for _, blocks := range streams {
+		for _, block := range blocks {
+			block.start = xr.size
+			xr.blocks = append(xr.blocks, block)
+			xr.size += block.size
+		}
+	}
+	return xr, nil
+}
+
+// readXZStream reads the index of the stream ending at end and returns its blocks and the offset the stream starts at.
+func readXZStream(r io.ReaderAt, end int64) ([]xzBlock, int64, error) {
+	if end < 2*xzHeaderSize {
+		return nil, 0, errors.New("xz stream is too short")
+	}
+	var footer [xzHeaderSize]byte
+	if _, err := r.ReadAt(footer[:], end-xzHeaderSize); err != nil {
+		return nil, 0, err
+	}
+	if string(footer[10:12]) != "YZ" {
+		return nil, 0, errors.New("invalid xz stream footer")
+	}
+	checkSize := xzCheckSize(footer[9])
+	indexSize := (int64(binary.LittleEndian.Uint32(footer[4:8])) + 1) * 4
+	indexStart := end - xzHeaderSize - indexSize
+	if indexStart < xzHeaderSize {
+		return nil, 0, errors.New("xz index exceeds the stream")
+	}
+
+	index := make([]byte, indexSize)
+	if _, err := r.ReadAt(index, indexStart); err != nil {
+		return nil, 0, fmt.Errorf("failed to read xz index: %w", err)
+	}
+	ir := bytes.NewReader(index)
+	if indicator, _ := ir.ReadByte(); indicator != 0 {
+		return nil, 0, errors.New("invalid xz index")
+	}
+	count, err := readXZVarint(ir)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var blocks []xzBlock
+	var stored int64
+	for i := int64(0); i < count; i++ {
+		unpadded, err := readXZVarint(ir)
+		if err != nil {
+			return nil, 0, err
+		}
+		uncompressed, err := readXZVarint(ir)
+		if err != nil {
+			return nil, 0, err
+		}
+		blocks = append(blocks, xzBlock{unpadded: unpadded, checkSize: checkSize, size: uncompressed})
+		stored += (unpadded + 3) &^ 3
+	}
+
+	start := indexStart - stored - xzHeaderSize
+	if start < 0 {
+		return nil, 0, errors.New("xz blocks exceed the stream")
+	}
+	var header [6]byte
+	if _, err := r.ReadAt(header[:], start); err != nil || !bytes.Equal(header[:], xzMagic) {
+		return nil, 0, errors.New("invalid xz stream header")
+	}
+
+	offset := start + xzHeaderSize
+	for i := range blocks {
+		blocks[i].offset = offset
+		offset += (blocks[i].unpadded + 3) &^ 3
+	}
+	return blocks, start, nil
+}
+
+// xzCheckSize returns the size of the integrity check of the check type in the stream flags.
+func xzCheckSize(check byte) int64 {
+	switch check &= 0x0F; {
+	case check == 0:
+		return 0
+	case check <= 3:
+		return 4
+	case check <= 6:
+		return 8
+	case check <= 9:
+		return 16
+	case check <= 12:
+		return 32
+	}
+	return 64
+}
+
+// readXZVarint reads a multibyte integer, 7 bits per byte least significant first.
+func readXZVarint(r io.ByteReader) (int64, error) {
+	var value int64
+	for i := 0; i < 9; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, errors.New("truncated xz integer")
+		}
+		value |= int64(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return value, nil
+		}
+	}
+	return 0, errors.New("invalid xz integer")
+}
+
+// openBlock returns a decompressor over the data of a block.
+func (xr *XZReader) openBlock(block xzBlock) (io.Reader, error) {
+	var size [1]byte
+	if _, err := xr.reader.ReadAt(size[:], block.offset); err != nil {
+		return nil, err
+	}
+	header := make([]byte, (int(size[0])+1)*4)
+	if _, err := xr.reader.ReadAt(header, block.offset); err != nil {
+		return nil, err
+	}
+
+	hr := bytes.NewReader(header[2:])
+	flags := header[1]
+	if flags&0x40 != 0 {
+		if _, err := readXZVarint(hr); err != nil {
+			return nil, err
+		}
+	}
+	if flags&0x80 != 0 {
+		if _, err := readXZVarint(hr); err != nil {
+			return nil, err
+		}
+	}
+	filter, err := readXZVarint(hr)
+	if err != nil {
+		return nil, err
+	}
+	if flags&0x03 != 0 || filter != xzLZMA2 {
+		return nil, fmt.Errorf("unsupported xz filter chain (filter 0x%X)", filter)
+	}
+	if n, err := readXZVarint(hr); err != nil || n != 1 {
+		return nil, errors.New("invalid LZMA2 filter properties")
+	}
+	prop, err := hr.ReadByte()
+	if err != nil || prop > 40 {
+		return nil, errors.New("invalid LZMA2 dictionary size")
+	}
+
+	// The dictionary never needs to be larger than the block
+	dictCap := int64(0xFFFFFFFF)
+	if prop < 40 {
+		dictCap = int64(2|prop&1) << (prop/2 + 11)
+	}
+	dictCap = max(min(dictCap, block.size), lzma.MinDictCap)
+
+	data := io.NewSectionReader(xr.reader, block.offset+int64(len(header)), block.unpadded-int64(len(header))-block.checkSize)
+	return lzma.Reader2Config{DictCap: int(dictCap)}.NewReader2(bufio.NewReader(data))
+}
+
+// Size returns the size of the decompressed data.
+func (xr *XZReader) Size() int64 {
+	return xr.size
+}
+
+func (xr *XZReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("compressed: negative offset")
+	}
+
+	xr.mu.Lock()
+	defer xr.mu.Unlock()
+
+	n := 0
+	for n < len(p) && off < xr.size {
+		index := sort.Search(len(xr.blocks), func(i int) bool { return xr.blocks[i].start+xr.blocks[i].size > off })
+		block := xr.blocks[index]
+
+		// Blocks are decompressed sequentially, a backward seek restarts the block
+		if index != xr.block || off < xr.pos {
+			stream, err := xr.openBlock(block)
+			if err != nil {
+				xr.block = -1
+				return n, fmt.Errorf("failed to open xz block %d: %w", index, err)
+			}
+			xr.block, xr.stream, xr.pos = index, stream, block.start
+		}
+		if off > xr.pos {
+			skipped, err := io.CopyN(io.Discard, xr.stream, off-xr.pos)
+			xr.pos += skipped
+			if err != nil {
+				xr.block = -1
+				return n, fmt.Errorf("failed to decompress xz block %d: %w", index, err)
+			}
+		}
+
+		m, err := io.ReadFull(xr.stream, p[n:n+int(min(int64(len(p)-n), block.start+block.size-off))])
+		n += m
+		off += int64(m)
+		xr.pos += int64(m)
+		if err != nil {
+			xr.block = -1
+			return n, fmt.Errorf("failed to decompress xz block %d: %w", index, err)
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close releases the decompressor, the underlying reader is not closed.
+func (xr *XZReader) Close() error {
+	xr.block, xr.stream = -1, nil
+	return nil
+}
@@ -0,0 +1,160 @@
+package compressed
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// The zstd seekable format stores the data as independent zstd frames followed by a seek table in a skippable frame.
+// All values are little-endian.
+//
+//	BP 1 - 4:   Skippable frame magic 0x184D2A5E
+//	BP 5 - 8:   Frame size, the size of the seek table entries and footer
+//	Entries:    Compressed size (4), decompressed size (4) and, if flagged in the footer, a checksum (4) per frame
+//	Footer:     Number of frames (4), descriptor (1, bit 7 set if checksums are present), magic 0x8F92EAB1 (4)
+const (
+	ZSTD_FRAME_SIZE = 256 * 1024
+
+	zstdSkippableMagic = 0x184D2A5E
+	zstdSeekableMagic  = 0x8F92EAB1
+	zstdFooterSize     = 9
+	zstdChecksumFlag   = 0x80
+)
+
+// hasSeekTable returns true if the reader ends with a zstd seek table.
+func hasSeekTable(r io.ReaderAt, size int64) bool {
+	var footer [zstdFooterSize]byte
+	if size < zstdFooterSize {
+		return false
+	}
+	if _, err := r.ReadAt(footer[:], size-zstdFooterSize); err != nil {
+		return false
+	}
+	return binary.LittleEndian.Uint32(footer[5:9]) == zstdSeekableMagic
+}
+
+// ZstdReader is a random access reader over a zstd seekable format file.
+type ZstdReader struct {
+	*blockCache
+	reader  io.ReaderAt
+	decoder *zstd.Decoder
+	// offsets and starts hold the compressed and decompressed offsets of each frame plus one past the last
+	offsets []int64
+	starts  []int64
+}
+
+// NewZstdReader reads the seek table of a zstd seekable format file.
+func NewZstdReader(r io.ReaderAt, size int64) (*ZstdReader, error) {
+	if !hasSeekTable(r, size) {
+		return nil, errors.New("no zstd seek table found")
+	}
+	var footer [zstdFooterSize]byte
+	if _, err := r.ReadAt(footer[:], size-zstdFooterSize); err != nil {
+		return nil, err
+	}
+	frames := int64(binary.LittleEndian.Uint32(footer[0:4]))
+	entrySize := int64(8)
+	if footer[4]&zstdChecksumFlag != 0 {
+		entrySize = 12
+	}
+
+	tableSize := frames*entrySize + zstdFooterSize
+	if tableSize+8 > size {
+		return nil, errors.New("zstd seek table exceeds the file")
+	}
+	table := make([]byte, tableSize+8)
+	if _, err := r.ReadAt(table, size-int64(len(table))); err != nil {
+		return nil, fmt.Errorf("failed to read zstd seek table: %w", err)
+	}
+	if binary.LittleEndian.Uint32(table[0:4]) != zstdSkippableMagic || int64(binary.LittleEndian.Uint32(table[4:8])) != tableSize {
+		return nil, errors.New("invalid zstd seek table frame")
+	}
+
+	zr := &ZstdReader{reader: r, offsets: make([]int64, frames+1), starts: make([]int64, frames+1)}
+	for i := int64(0); i < frames; i++ {
+		entry := table[8+i*entrySize:]
+		zr.offsets[i+1] = zr.offsets[i] + int64(binary.LittleEndian.Uint32(entry[0:4]))
+		zr.starts[i+1] = zr.starts[i] + int64(binary.LittleEndian.Uint32(entry[4:8]))
+	}
+	if zr.offsets[frames] > size-int64(len(table)) {
+		return nil, errors.New("zstd frames exceed the file")
+	}
+
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	zr.decoder = decoder
+
+	locate := func(off int64) (int, int64) {
+		frame := sort.Search(int(frames), func(i int) bool { return zr.starts[i+1] > off })
+		return frame, zr.starts[frame]
+	}
+	zr.blockCache = newBlockCache(zr.starts[frames], locate, zr.decodeFrame)
+	return zr, nil
+}
+
+// decodeFrame reads and decompresses a frame.
+func (zr *ZstdReader) decodeFrame(frame int, dst []byte) ([]byte, error) {
+	stored := make([]byte, zr.offsets[frame+1]-zr.offsets[frame])
+	if n, err := zr.reader.ReadAt(stored, zr.offsets[frame]); n < len(stored) {
+		return nil, err
+	}
+	return zr.decoder.DecodeAll(stored, dst)
+}
+
+// Close releases the decoder, the underlying reader is not closed.
+func (zr *ZstdReader) Close() error {
+	zr.decoder.Close()
+	zr.data = nil
+	return nil
+}
+
+// WriteZstd compresses r into a zstd seekable format file of frames holding frameSize bytes each.
+func WriteZstd(w io.Writer, r io.Reader, frameSize int) error {
+	if frameSize <= 0 {
+		return fmt.Errorf("invalid frame size %d", frameSize)
+	}
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return err
+	}
+	defer encoder.Close()
+
+	var table []byte
+	data := make([]byte, frameSize)
+	var compressed []byte
+	for {
+		n, err := io.ReadFull(r, data)
+		if n > 0 {
+			compressed = encoder.EncodeAll(data[:n], compressed[:0])
+			if _, err := w.Write(compressed); err != nil {
+				return err
+			}
+			table = binary.LittleEndian.AppendUint32(table, uint32(len(compressed)))
+			table = binary.LittleEndian.AppendUint32(table, uint32(n))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	table = binary.LittleEndian.AppendUint32(table, uint32(len(table)/8))
+	table = append(table, 0)
+	table = binary.LittleEndian.AppendUint32(table, zstdSeekableMagic)
+
+	header := binary.LittleEndian.AppendUint32(nil, zstdSkippableMagic)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(table)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err = w.Write(table)
+	return err
+}
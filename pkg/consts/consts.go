@@ -10,9 +10,20 @@ const (
 	// ISO9660 volume descriptor version (always 1).
 	ISO9660_VOLUME_DESC_VERSION = 1
 
+	// ISO 9660:1999 enhanced volume descriptor and file structure version. An enhanced volume descriptor is a
+	// supplementary volume descriptor recorded with version 2, its identifiers are up to 207 bytes of any value and
+	// carry no version suffix.
+	ISO9660_ENHANCED_VOLUME_DESC_VERSION      = 2
+	ISO9660_ENHANCED_FILE_STRUCTURE_VERSION   = 2
+	ISO9660_ENHANCED_MAX_FILE_IDENTIFIER_SIZE = 207
+
 	// ISO9660 default sector size.
 	ISO9660_SECTOR_SIZE = 2048
 
+	// Smallest logical block size allowed by ECMA-119, the logical block size is a power of two no larger than the
+	// sector size.
+	ISO9660_MIN_LOGICAL_BLOCK_SIZE = 512
+
 	// ISO9660 volume descriptor header size
 	ISO9660_VOLUME_DESC_HEADER_SIZE = 7
 
@@ -24,6 +35,13 @@ const (
 	JOLIET_LEVEL_2_ESCAPE = "%/C"
 	JOLIET_LEVEL_3_ESCAPE = "%/E"
 
+	// Capacities in bytes of common recordable media, used as the maximum volume size when splitting a volume set.
+	CD_74_CAPACITY = 333000 * ISO9660_SECTOR_SIZE
+	CD_80_CAPACITY = 360000 * ISO9660_SECTOR_SIZE
+	DVD_5_CAPACITY = 2295104 * ISO9660_SECTOR_SIZE
+	DVD_9_CAPACITY = 4171712 * ISO9660_SECTOR_SIZE
+	BD_25_CAPACITY = 12219392 * ISO9660_SECTOR_SIZE
+
 	// El Torito bootable cdrom system identifier.
 	EL_TORITO_BOOT_SYSTEM_ID = "EL TORITO SPECIFICATION"
 
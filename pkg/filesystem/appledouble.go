@@ -0,0 +1,95 @@
+package filesystem
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// AppleDouble files hold the Finder info and resource fork of a file beside it as "._name". The header is followed by
+// a table of (id, offset, length) entry descriptors, all values are big-endian.
+const (
+	APPLEDOUBLE_MAGIC   = 0x00051607
+	APPLEDOUBLE_VERSION = 0x00020000
+
+	appleDoubleResourceFork = 2
+	appleDoubleFinderInfo   = 9
+	appleDoubleHeaderSize   = 26
+	appleDoubleEntrySize    = 12
+)
+
+type appleDoubleEntry struct {
+	id   uint32
+	data []byte
+}
+
+// AppleDoubleName returns the path of the AppleDouble file holding the Finder info and resource fork of path.
+func AppleDoubleName(path string) string {
+	return filepath.Join(filepath.Dir(path), "._"+filepath.Base(path))
+}
+
+// HasAppleDouble reports whether the entry carries Finder info or a resource fork to write as an AppleDouble file.
+func (fse *FileSystemEntry) HasAppleDouble() bool {
+	return fse.Apple != nil || fse.AssociatedFile != nil
+}
+
+// MarshalAppleDouble encodes the Finder info and resource fork of the entry as an AppleDouble file.
+func (fse *FileSystemEntry) MarshalAppleDouble() ([]byte, error) {
+	finderInfo := make([]byte, 32)
+	if fse.Apple != nil {
+		finderInfo = fse.Apple.FinderInfo()
+	}
+
+	var resourceFork []byte
+	if fse.AssociatedFile != nil {
+		content, err := fse.AssociatedFile.ContentReader()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open resource fork of %s: %w", fse.FullPath, err)
+		}
+		resourceFork = make([]byte, fse.AssociatedFile.Size)
+		if _, err := content.ReadAt(resourceFork, 0); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read resource fork of %s: %w", fse.FullPath, err)
+		}
+	}
+
+	entries := []appleDoubleEntry{{appleDoubleFinderInfo, finderInfo}}
+	if len(resourceFork) > 0 {
+		entries = append(entries, appleDoubleEntry{appleDoubleResourceFork, resourceFork})
+	}
+
+	offset := appleDoubleHeaderSize + appleDoubleEntrySize*len(entries)
+	size := offset
+	for _, entry := range entries {
+		size += len(entry.data)
+	}
+
+	buf := make([]byte, size)
+	binary.BigEndian.PutUint32(buf[0:4], APPLEDOUBLE_MAGIC)
+	binary.BigEndian.PutUint32(buf[4:8], APPLEDOUBLE_VERSION)
+	// 16 byte filler
+	binary.BigEndian.PutUint16(buf[24:26], uint16(len(entries)))
+	for i, entry := range entries {
+		desc := buf[appleDoubleHeaderSize+i*appleDoubleEntrySize:]
+		binary.BigEndian.PutUint32(desc[0:4], entry.id)
+		binary.BigEndian.PutUint32(desc[4:8], uint32(offset))
+		binary.BigEndian.PutUint32(desc[8:12], uint32(len(entry.data)))
+		copy(buf[offset:], entry.data)
+		offset += len(entry.data)
+	}
+
+	return buf, nil
+}
+
+// WriteAppleDouble writes the Finder info and resource fork of the entry to the AppleDouble file of outputPath.
+func (fse *FileSystemEntry) WriteAppleDouble(outputPath string) error {
+	data, err := fse.MarshalAppleDouble()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(AppleDoubleName(outputPath), data, 0644); err != nil {
+		return fmt.Errorf("failed to write AppleDouble file for %s: %w", outputPath, err)
+	}
+	return nil
+}
@@ -4,9 +4,16 @@ import (
 	"crypto/md5"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"github.com/rstms/iso-kit/pkg/cdimage"
 	"github.com/rstms/iso-kit/pkg/consts"
 	"github.com/rstms/iso-kit/pkg/iso9660/directory"
+	"github.com/rstms/iso-kit/pkg/iso9660/extensions"
+	"github.com/rstms/iso-kit/pkg/iso9660/extent"
+	"github.com/rstms/iso-kit/pkg/iso9660/sparse"
+	"github.com/rstms/iso-kit/pkg/iso9660/zisofs"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
@@ -15,11 +22,16 @@ import (
 
 // NewFileSystemEntry initializes a FileSystemEntry with a reader
 func NewFileSystemEntry(name, fullPath string, isDir bool, size, location uint32, uid *uint32, gid *uint32, mode os.FileMode, createTime, modTime time.Time, record *directory.DirectoryRecord, reader io.ReaderAt) *FileSystemEntry {
+	storedSize := uint64(size)
+	if record != nil {
+		storedSize = uint64(record.DataLength)
+	}
 	return &FileSystemEntry{
 		Name:       name,
 		FullPath:   fullPath,
 		IsDir:      isDir,
-		Size:       size,
+		Size:       uint64(size),
+		StoredSize: storedSize,
 		Location:   location,
 		UID:        uid,
 		GID:        gid,
@@ -38,8 +50,10 @@ type FileSystemEntry struct {
 	FullPath string `json:"full_path"`
 	// IsDir, true if it's a directory
 	IsDir bool `json:"is_dir"`
-	// Size of the file, 0 if it's a directory
-	Size uint32 `json:"size"`
+	// Size of the file, 0 if it's a directory. This is the apparent size for compressed and sparse files
+	Size uint64 `json:"size"`
+	// StoredSize is the number of bytes the file occupies in the iso, this differs from Size for compressed files
+	StoredSize uint64 `json:"stored_size"`
 	// Location of the file in the iso
 	Location uint32 `json:"location"`
 	// UID, userid of the file/directory
@@ -52,14 +66,52 @@ type FileSystemEntry struct {
 	CreateTime time.Time
 	// ModTime
 	ModTime time.Time
+	// AccessTime is the Rock Ridge access time, zero if it was not recorded
+	AccessTime time.Time
 	// RockRidge extended attributes
 	HasRockRidge bool `json:"has_rock_ridge"`
+	// Zisofs holds the compression information if the file data is zisofs compressed
+	Zisofs *extensions.ZisofsInfo `json:"zisofs,omitempty"`
+	// Sparse holds the sparse file information if the file data is recorded as a Rock Ridge sparse file
+	Sparse *extensions.SparseFileInfo `json:"sparse,omitempty"`
+	// ExtendedAttributes holds the AAIP extended attributes of the file, e.g. "security.selinux"
+	ExtendedAttributes []extensions.ExtendedAttribute `json:"extended_attributes,omitempty"`
+	// ACL holds the AAIP POSIX access and default ACLs of the file
+	ACL *extensions.ACL `json:"acl,omitempty"`
+	// Apple holds the Macintosh type, creator and Finder flags recorded by the Apple ISO 9660 extensions
+	Apple *extensions.AppleExtension `json:"apple,omitempty"`
+	// XA holds the CD-ROM XA attributes of the entry, recorded on Mode 2 discs such as Video CDs
+	XA *extensions.XARecord `json:"xa,omitempty"`
+	// AssociatedFile is the associated file recorded under the same name, on Mac-authored discs this is the resource fork
+	AssociatedFile *FileSystemEntry `json:"associated_file,omitempty"`
+	// LogicalBlockSize is the logical block size in which Location is counted, zero means ISO9660_SECTOR_SIZE
+	LogicalBlockSize uint16 `json:"logical_block_size,omitempty"`
 	// Original DirectoryRecord
 	record *directory.DirectoryRecord
+	// Further extents of a file recorded with the multi-extent flag, in file order
+	extents []fileExtent
 	// A reference to the io.ReaderAt so that we can extract the file contents easily
 	reader io.ReaderAt
 }
 
+// fileExtent is a further extent of a multi-extent file and the reader of the volume it is recorded on
+type fileExtent struct {
+	record *directory.DirectoryRecord
+	reader io.ReaderAt
+}
+
+// AddExtent appends the next extent of a file recorded in several directory records with the multi-extent flag set, the
+// reader is the volume the extent is recorded on.
+func (fse *FileSystemEntry) AddExtent(record *directory.DirectoryRecord, reader io.ReaderAt) {
+	fse.extents = append(fse.extents, fileExtent{record: record, reader: reader})
+	fse.StoredSize += uint64(record.DataLength)
+	if fr := fse.form2Reader(reader, int64(record.DataLocation()), int64(record.DataLength)); fr != nil {
+		fse.Size += uint64(fr.Size())
+	} else if fse.Zisofs == nil && fse.Sparse == nil {
+		fse.Size += uint64(record.DataLength)
+	}
+}
+
 // DirectoryRecord returns the original directory record for the entry
 func (fse *FileSystemEntry) DirectoryRecord() *directory.DirectoryRecord {
 	return fse.record
@@ -70,6 +122,129 @@ func (fse *FileSystemEntry) ReadAt(p []byte, off int64) (n int, err error) {
 	return fse.reader.ReadAt(p, off)
 }
 
+// SectionReader returns an io.SectionReader over the content of the file. Unlike ReadAt its offsets are relative to the
+// start of the file, so it can stand in for the file wherever an io.ReaderAt is taken, e.g. to open an image stored in
+// the image.
+func (fse *FileSystemEntry) SectionReader() (*io.SectionReader, error) {
+	content, err := fse.ContentReader()
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(content, 0, int64(fse.Size)), nil
+}
+
+// Open returns an io.ReadSeekCloser over the content of the file. The file is read from the image as it is consumed, so
+// files of any size can be streamed without holding them in memory.
+func (fse *FileSystemEntry) Open() (io.ReadSeekCloser, error) {
+	reader, err := fse.SectionReader()
+	if err != nil {
+		return nil, err
+	}
+	return &fsFile{SectionReader: reader, entry: fse}, nil
+}
+
+// ContentReader returns an io.ReaderAt over the logical content of the file. Offsets are relative to the start of the
+// file data, any Extended Attribute Record is skipped and compressed files are transparently decompressed.
+func (fse *FileSystemEntry) ContentReader() (io.ReaderAt, error) {
+	if fse.IsDir {
+		return nil, fmt.Errorf("cannot read content of a directory: %s", fse.FullPath)
+	}
+
+	// The file data starts after the Extended Attribute Record if one is recorded
+	location := int64(fse.Location)
+	if fse.record != nil {
+		location += int64(fse.record.ExtendedAttributeRecordLength)
+	}
+
+	// The File Sections of multi-extent files are read one after the other
+	size := int64(fse.StoredSize)
+	for _, ext := range fse.extents {
+		size -= int64(ext.record.DataLength)
+	}
+	stored := fse.sectionReader(fse.reader, fse.record, location, size)
+	if len(fse.extents) > 0 {
+		sections := []*io.SectionReader{stored}
+		for _, ext := range fse.extents {
+			sections = append(sections, fse.sectionReader(ext.reader, ext.record, int64(ext.record.DataLocation()), int64(ext.record.DataLength)))
+		}
+		mr := extent.NewMultiExtentReader(sections...)
+		stored = io.NewSectionReader(mr, 0, mr.Size())
+	}
+
+	// Sparse files start with a table of absolute block numbers so they are resolved against the whole image
+	if fse.Sparse != nil {
+		sr, err := sparse.NewReader(fse.reader, uint32(location), fse.Sparse.TableDepth, fse.Sparse.VirtualSize, int(fse.blockSize()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open sparse data for %s: %w", fse.FullPath, err)
+		}
+		return sr, nil
+	}
+
+	if fse.Zisofs != nil {
+		zr, err := zisofs.NewReader(stored, stored.Size())
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zisofs data for %s: %w", fse.FullPath, err)
+		}
+		return zr, nil
+	}
+
+	return stored, nil
+}
+
+// sectionReader returns a reader over size bytes of a File Section starting at the logical block location, interleaved
+// sections are reassembled from their file units. Mode 2 Form 2 sections read from raw CD images carry 2324 bytes per
+// sector.
+func (fse *FileSystemEntry) sectionReader(reader io.ReaderAt, record *directory.DirectoryRecord, location, size int64) *io.SectionReader {
+	if fr := fse.form2Reader(reader, location, size); fr != nil {
+		return io.NewSectionReader(fr, 0, fr.Size())
+	}
+	startOffset := location * fse.blockSize()
+	if record != nil && record.IsInterleaved() {
+		ir := extent.NewInterleavedReader(reader, startOffset, size, record.FileUnitSize, record.InterleaveGapSize, int(fse.blockSize()))
+		return io.NewSectionReader(ir, 0, ir.Size())
+	}
+	return io.NewSectionReader(reader, startOffset, size)
+}
+
+// form2Source is implemented by readers over the user data of CD image data tracks, such as cdimage.DataReader, that
+// can also read the full payloads of Mode 2 Form 2 sectors.
+type form2Source interface {
+	Form2Reader(start, count int64) *cdimage.Form2Reader
+}
+
+// Form2Reader returns a reader over the full 2324 byte payloads of the sectors of the entry's first File Section when the
+// entry is a Mode 2 Form 2 file read from a raw CD image, nil otherwise. The recorded data length of such files counts
+// 2048 bytes per sector. ContentReader reads the payloads of every File Section of multi-extent files.
+func (fse *FileSystemEntry) Form2Reader() *cdimage.Form2Reader {
+	location, size := int64(fse.Location), int64(fse.StoredSize)
+	if fse.record != nil {
+		location, size = int64(fse.record.DataLocation()), int64(fse.record.DataLength)
+	}
+	return fse.form2Reader(fse.reader, location, size)
+}
+
+// form2Reader returns a reader over the Form 2 payloads of the sectors of a File Section of size bytes recorded at the
+// logical block location, nil if the entry is not a Form 2 file or the reader is not over a raw CD image.
+func (fse *FileSystemEntry) form2Reader(reader io.ReaderAt, location, size int64) *cdimage.Form2Reader {
+	if fse.IsDir || fse.XA == nil || !fse.XA.IsForm2() {
+		return nil
+	}
+	raw, ok := reader.(form2Source)
+	if !ok {
+		return nil
+	}
+	sectors := (size + consts.ISO9660_SECTOR_SIZE - 1) / consts.ISO9660_SECTOR_SIZE
+	return raw.Form2Reader(location*fse.blockSize()/consts.ISO9660_SECTOR_SIZE, sectors)
+}
+
+// blockSize returns the logical block size in which the location of the entry is counted.
+func (fse *FileSystemEntry) blockSize() int64 {
+	if fse.LogicalBlockSize == 0 {
+		return consts.ISO9660_SECTOR_SIZE
+	}
+	return int64(fse.LogicalBlockSize)
+}
+
 // Extract the entry to disk
 func (fse *FileSystemEntry) ExtractToDisk(outputDir string) error {
 	outputPath := filepath.Join(outputDir, fse.FullPath)
@@ -91,13 +266,14 @@ func (fse *FileSystemEntry) ExtractToDisk(outputDir string) error {
 	}
 	defer outFile.Close()
 
-	// Read file bytes and write to disk
-	data, err := fse.GetBytes()
+	// Stream the file contents to disk
+	content, err := fse.Open()
 	if err != nil {
 		return fmt.Errorf("failed to read file data for %s: %w", fse.FullPath, err)
 	}
+	defer content.Close()
 
-	if _, err := outFile.Write(data); err != nil {
+	if _, err := io.Copy(outFile, content); err != nil {
 		return fmt.Errorf("failed to write file %s: %w", outputPath, err)
 	}
 
@@ -106,8 +282,12 @@ func (fse *FileSystemEntry) ExtractToDisk(outputDir string) error {
 		return fmt.Errorf("failed to set permissions on %s: %w", outputPath, err)
 	}
 
-	// Set timestamps
-	if err := os.Chtimes(outputPath, fse.ModTime, fse.ModTime); err != nil {
+	// Set timestamps, the modification time stands in for the access time if none was recorded
+	atime := fse.AccessTime
+	if atime.IsZero() {
+		atime = fse.ModTime
+	}
+	if err := os.Chtimes(outputPath, atime, fse.ModTime); err != nil {
 		return fmt.Errorf("failed to set timestamps on %s: %w", outputPath, err)
 	}
 
@@ -120,11 +300,14 @@ func (fse *FileSystemEntry) GetBytes() ([]byte, error) {
 		return nil, fmt.Errorf("cannot get bytes for a directory: %s", fse.FullPath)
 	}
 
-	startOffset := int64(fse.Location) * int64(consts.ISO9660_SECTOR_SIZE)
-	data := make([]byte, fse.Size)
-
-	_, err := fse.reader.ReadAt(data, startOffset)
+	content, err := fse.ContentReader()
 	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, fse.Size)
+	_, err = content.ReadAt(data, 0)
+	if err != nil && !(errors.Is(err, io.EOF) && fse.Size == 0) {
 		return nil, fmt.Errorf("failed to read file data for %s: %w", fse.FullPath, err)
 	}
 
@@ -137,13 +320,7 @@ func (fse *FileSystemEntry) GetMD5() (string, error) {
 		return "", fmt.Errorf("cannot compute MD5 for a directory: %s", fse.FullPath)
 	}
 
-	data, err := fse.GetBytes()
-	if err != nil {
-		return "", err
-	}
-
-	md5Sum := md5.Sum(data)
-	return hex.EncodeToString(md5Sum[:]), nil
+	return fse.hash(md5.New())
 }
 
 // Compute SHA-256 hash of the file
@@ -152,11 +329,19 @@ func (fse *FileSystemEntry) GetSHA256() (string, error) {
 		return "", fmt.Errorf("cannot compute SHA-256 for a directory: %s", fse.FullPath)
 	}
 
-	data, err := fse.GetBytes()
+	return fse.hash(sha256.New())
+}
+
+// hash streams the file contents through h and returns the hex encoded sum
+func (fse *FileSystemEntry) hash(h hash.Hash) (string, error) {
+	content, err := fse.Open()
 	if err != nil {
 		return "", err
 	}
+	defer content.Close()
 
-	sha256Sum := sha256.Sum256(data)
-	return hex.EncodeToString(sha256Sum[:]), nil
+	if _, err = io.Copy(h, content); err != nil {
+		return "", fmt.Errorf("failed to read file data for %s: %w", fse.FullPath, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
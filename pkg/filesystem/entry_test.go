@@ -0,0 +1,92 @@
+package filesystem
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rstms/iso-kit/pkg/cdimage"
+	"github.com/rstms/iso-kit/pkg/iso9660/directory"
+	"github.com/rstms/iso-kit/pkg/iso9660/extensions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenMultiExtent(t *testing.T) {
+	image := make([]byte, 4*2048)
+	copy(image[2048:], "hello, ")
+	copy(image[3*2048:], "world\n")
+	reader := bytes.NewReader(image)
+
+	entry := NewFileSystemEntry("greeting.txt", "/greeting.txt", false, 7, 1, nil, nil, 0o644, time.Time{}, time.Time{}, &directory.DirectoryRecord{LocationOfExtent: 1, DataLength: 7}, reader)
+	// The second extent is preceded by a one block Extended Attribute Record
+	entry.AddExtent(&directory.DirectoryRecord{LocationOfExtent: 2, ExtendedAttributeRecordLength: 1, DataLength: 6}, reader)
+	require.Equal(t, uint64(13), entry.Size)
+	require.Equal(t, uint64(13), entry.StoredSize)
+
+	file, err := entry.Open()
+	require.NoError(t, err)
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	require.Equal(t, "hello, world\n", string(data))
+
+	_, err = file.Seek(5, io.SeekStart)
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(file, buf)
+	require.NoError(t, err)
+	require.Equal(t, ", wo", string(buf))
+
+	sum := sha256.Sum256([]byte("hello, world\n"))
+	got, err := entry.GetSHA256()
+	require.NoError(t, err)
+	require.Equal(t, hex.EncodeToString(sum[:]), got)
+}
+
+func TestOpenMultiExtentForm2(t *testing.T) {
+	// Four raw Mode 2 Form 2 sectors, the file is recorded in the first two and the last one
+	var bin bytes.Buffer
+	for i := 0; i < 4; i++ {
+		sector := make([]byte, cdimage.RAW_SECTOR_SIZE)
+		copy(sector, []byte{0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00})
+		sector[15] = 2
+		sector[18], sector[22] = cdimage.XA_SUBMODE_FORM2, cdimage.XA_SUBMODE_FORM2
+		for j := cdimage.MODE2_DATA_OFFSET; j < cdimage.RAW_SECTOR_SIZE; j++ {
+			sector[j] = byte(i + 1)
+		}
+		bin.Write(sector)
+	}
+	path := filepath.Join(t.TempDir(), "xa.bin")
+	require.NoError(t, os.WriteFile(path, bin.Bytes(), 0644))
+
+	img, err := cdimage.Open(path)
+	require.NoError(t, err)
+	defer img.Close()
+	reader, err := img.DataReader()
+	require.NoError(t, err)
+
+	entry := NewFileSystemEntry("video.dat", "/video.dat", false, 2*2048, 0, nil, nil, 0o644, time.Time{}, time.Time{}, &directory.DirectoryRecord{LocationOfExtent: 0, DataLength: 2 * 2048}, reader)
+	entry.XA = &extensions.XARecord{Attributes: extensions.XA_ATTR_MODE2_FORM2}
+	entry.Size = uint64(entry.Form2Reader().Size())
+	entry.AddExtent(&directory.DirectoryRecord{LocationOfExtent: 3, DataLength: 2048}, reader)
+	require.Equal(t, uint64(3*cdimage.FORM2_SECTOR_SIZE), entry.Size)
+	require.Equal(t, uint64(3*2048), entry.StoredSize)
+
+	file, err := entry.Open()
+	require.NoError(t, err)
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+
+	var want []byte
+	for _, fill := range []byte{1, 2, 4} {
+		want = append(want, bytes.Repeat([]byte{fill}, cdimage.FORM2_SECTOR_SIZE)...)
+	}
+	require.Equal(t, want, data)
+}
@@ -0,0 +1,316 @@
+package filesystem
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MAX_SYMLINK_DEPTH is the number of symbolic links followed while resolving a path before giving up
+const MAX_SYMLINK_DEPTH = 40
+
+// FileInfo returns the entry as an fs.FileInfo. The mode carries the Rock Ridge permissions together with the fs.ModeDir
+// and fs.ModeSymlink type bits and Sys returns the entry itself.
+func (fse *FileSystemEntry) FileInfo() fs.FileInfo {
+	return fileInfo{entry: fse}
+}
+
+// DirEntry returns the entry as an fs.DirEntry.
+func (fse *FileSystemEntry) DirEntry() fs.DirEntry {
+	return fs.FileInfoToDirEntry(fse.FileInfo())
+}
+
+// SymlinkTarget returns the target of a Rock Ridge symbolic link, ok is false if the entry is not a symbolic link.
+func (fse *FileSystemEntry) SymlinkTarget() (target string, ok bool) {
+	if fse.record == nil || fse.record.RockRidge == nil || fse.record.RockRidge.SymlinkTarget == nil {
+		return "", false
+	}
+	return fse.record.RockRidge.SymlinkPath(), true
+}
+
+// fileInfo adapts a FileSystemEntry to fs.FileInfo, the entry fields would collide with the method names.
+type fileInfo struct {
+	entry *FileSystemEntry
+}
+
+func (fi fileInfo) Name() string {
+	name := strings.Trim(fi.entry.FullPath, "/")
+	if name == "" {
+		return "."
+	}
+	return path.Base(name)
+}
+
+func (fi fileInfo) Size() int64 {
+	if fi.entry.IsDir {
+		return 0
+	}
+	return int64(fi.entry.Size)
+}
+
+func (fi fileInfo) Mode() fs.FileMode {
+	mode := fi.entry.Mode &^ (fs.ModeDir | fs.ModeSymlink)
+	if fi.entry.IsDir {
+		return mode | fs.ModeDir
+	}
+	if _, ok := fi.entry.SymlinkTarget(); ok {
+		return mode | fs.ModeSymlink
+	}
+	return mode
+}
+
+func (fi fileInfo) ModTime() time.Time {
+	return fi.entry.ModTime
+}
+
+func (fi fileInfo) IsDir() bool {
+	return fi.entry.IsDir
+}
+
+func (fi fileInfo) Sys() any {
+	return fi.entry
+}
+
+// FS is a read-only fs.FS over the entries of an image. Besides fs.ReadDirFS, fs.ReadFileFS and fs.StatFS it implements
+// fs.ReadLinkFS so Rock Ridge symbolic links can be read with fs.ReadLink and fs.Lstat. Symbolic links are followed
+// by Open and Stat, absolute targets are resolved against the root of the image.
+type FS struct {
+	nodes map[string]*fsNode
+}
+
+// fsNode is a file or directory of an FS, children are sorted by name
+type fsNode struct {
+	entry    *FileSystemEntry
+	children []*fsNode
+}
+
+// NewFS builds an FS from the files and directories of an image. Directories missing from entries are synthesized so
+// every file is reachable from the root.
+func NewFS(entries []*FileSystemEntry) *FS {
+	fsys := &FS{nodes: make(map[string]*fsNode)}
+	fsys.nodes["."] = &fsNode{entry: &FileSystemEntry{Name: "/", FullPath: "/", IsDir: true, Mode: fs.ModeDir | 0o555}}
+
+	for _, entry := range entries {
+		name := strings.Trim(entry.FullPath, "/")
+		if name == "" {
+			fsys.nodes["."].entry = entry
+			continue
+		}
+		if !fs.ValidPath(name) {
+			continue
+		}
+		if node, ok := fsys.nodes[name]; ok {
+			// A directory synthesized for an earlier file is replaced by its recorded entry
+			if node.entry.record == nil && node.entry.reader == nil {
+				node.entry = entry
+			}
+			continue
+		}
+		fsys.add(name, entry)
+	}
+
+	for _, node := range fsys.nodes {
+		slices.SortFunc(node.children, func(a, b *fsNode) int {
+			return strings.Compare(a.entry.FileInfo().Name(), b.entry.FileInfo().Name())
+		})
+	}
+	return fsys
+}
+
+// add inserts the node for name and any missing parent directories
+func (fsys *FS) add(name string, entry *FileSystemEntry) *fsNode {
+	node := &fsNode{entry: entry}
+	fsys.nodes[name] = node
+
+	dir := path.Dir(name)
+	parent, ok := fsys.nodes[dir]
+	if !ok {
+		parent = fsys.add(dir, &FileSystemEntry{Name: path.Base(dir), FullPath: "/" + dir, IsDir: true, Mode: fs.ModeDir | 0o555})
+	}
+	parent.children = append(parent.children, node)
+	return node
+}
+
+// resolve looks up name following symbolic links in its directories, the final element is only followed if follow is
+// set.
+func (fsys *FS) resolve(op, name string, follow bool) (*fsNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	current := "."
+	remaining := strings.Split(name, "/")
+	if name == "." {
+		remaining = nil
+	}
+	links := 0
+	for len(remaining) > 0 {
+		element := remaining[0]
+		remaining = remaining[1:]
+
+		next := path.Join(current, element)
+		node, ok := fsys.nodes[next]
+		if !ok {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+
+		target, isLink := node.entry.SymlinkTarget()
+		if !isLink || (len(remaining) == 0 && !follow) {
+			if len(remaining) > 0 && !node.entry.IsDir {
+				return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+			}
+			current = next
+			continue
+		}
+
+		links++
+		if links > MAX_SYMLINK_DEPTH {
+			return nil, &fs.PathError{Op: op, Path: name, Err: errors.New("too many levels of symbolic links")}
+		}
+
+		// The target replaces the link and is resolved relative to the directory holding it
+		base := "/" + current
+		if strings.HasPrefix(target, "/") {
+			base = "/"
+		}
+		current = "."
+		if joined := path.Join(base, target); joined != "/" {
+			remaining = append(strings.Split(joined[1:], "/"), remaining...)
+		}
+	}
+	return fsys.nodes[current], nil
+}
+
+// Open opens the named file or directory, symbolic links are followed.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	node, err := fsys.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if node.entry.IsDir {
+		return &fsDir{node: node, name: name}, nil
+	}
+	reader, err := node.entry.SectionReader()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &fsFile{SectionReader: reader, entry: node.entry}, nil
+}
+
+// Stat returns the fs.FileInfo of the named file, symbolic links are followed.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	node, err := fsys.resolve("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return node.entry.FileInfo(), nil
+}
+
+// Lstat returns the fs.FileInfo of the named file without following a symbolic link in the final element.
+func (fsys *FS) Lstat(name string) (fs.FileInfo, error) {
+	node, err := fsys.resolve("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return node.entry.FileInfo(), nil
+}
+
+// ReadLink returns the target of the named symbolic link.
+func (fsys *FS) ReadLink(name string) (string, error) {
+	node, err := fsys.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	target, ok := node.entry.SymlinkTarget()
+	if !ok {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return target, nil
+}
+
+// ReadDir returns the entries of the named directory sorted by name.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, err := fsys.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if !node.entry.IsDir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	entries := make([]fs.DirEntry, 0, len(node.children))
+	for _, child := range node.children {
+		entries = append(entries, child.entry.DirEntry())
+	}
+	return entries, nil
+}
+
+// ReadFile returns the contents of the named file.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	node, err := fsys.resolve("read", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if node.entry.IsDir {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	data, err := node.entry.GetBytes()
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// fsFile is an open file of an FS
+type fsFile struct {
+	*io.SectionReader
+	entry *FileSystemEntry
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	return f.entry.FileInfo(), nil
+}
+
+func (f *fsFile) Close() error {
+	return nil
+}
+
+// fsDir is an open directory of an FS
+type fsDir struct {
+	node   *fsNode
+	name   string
+	offset int
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) {
+	return d.node.entry.FileInfo(), nil
+}
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *fsDir) Close() error {
+	return nil
+}
+
+// ReadDir returns the next n entries of the directory, or all remaining entries if n <= 0.
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.node.children[d.offset:]
+	if n > 0 && len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && n < len(remaining) {
+		remaining = remaining[:n]
+	}
+	d.offset += len(remaining)
+
+	entries := make([]fs.DirEntry, 0, len(remaining))
+	for _, child := range remaining {
+		entries = append(entries, child.entry.DirEntry())
+	}
+	return entries, nil
+}
@@ -0,0 +1,64 @@
+package filesystem
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/rstms/iso-kit/pkg/iso9660/directory"
+	"github.com/rstms/iso-kit/pkg/iso9660/extensions"
+	"github.com/stretchr/testify/require"
+)
+
+// symlinkEntry returns an entry for a Rock Ridge symbolic link with the given SL component records
+func symlinkEntry(fullPath string, components string) *FileSystemEntry {
+	record := &directory.DirectoryRecord{RockRidge: &extensions.RockRidgeExtensions{SymlinkTarget: &components}}
+	return NewFileSystemEntry("", fullPath, false, 0, 0, nil, nil, 0o777, time.Time{}, time.Time{}, record, nil)
+}
+
+func TestFS(t *testing.T) {
+	modTime := time.Date(2025, 2, 20, 1, 4, 20, 0, time.UTC)
+	image := make([]byte, 3*2048)
+	copy(image[2048:], "hello, world\n")
+
+	entries := []*FileSystemEntry{
+		NewFileSystemEntry("hosts", "/etc/hosts", false, 13, 1, nil, nil, 0o644, modTime, modTime, &directory.DirectoryRecord{DataLength: 13}, bytes.NewReader(image)),
+		NewFileSystemEntry("etc", "/etc", true, 0, 2, nil, nil, 0o755, modTime, modTime, nil, nil),
+		// hosts -> etc/hosts
+		symlinkEntry("/hosts", "\x00\x03etc\x00\x05hosts"),
+		// etc/self -> /etc
+		symlinkEntry("/etc/self", "\x08\x00\x00\x03etc"),
+		// up -> ..
+		symlinkEntry("/etc/up", "\x04\x00"),
+	}
+	fsys := NewFS(entries)
+
+	require.NoError(t, fstest.TestFS(fsys, "etc/hosts", "hosts", "etc/self", "etc/up"))
+
+	data, err := fs.ReadFile(fsys, "etc/self/self/hosts")
+	require.NoError(t, err)
+	require.Equal(t, "hello, world\n", string(data))
+
+	target, err := fs.ReadLink(fsys, "etc/self")
+	require.NoError(t, err)
+	require.Equal(t, "/etc", target)
+
+	target, err = fs.ReadLink(fsys, "etc/up")
+	require.NoError(t, err)
+	require.Equal(t, "..", target)
+
+	info, err := fs.Lstat(fsys, "hosts")
+	require.NoError(t, err)
+	require.Equal(t, fs.ModeSymlink|0o777, info.Mode())
+
+	info, err = fs.Stat(fsys, "etc/up/hosts")
+	require.NoError(t, err)
+	require.Equal(t, fs.FileMode(0o644), info.Mode())
+	require.Equal(t, int64(13), info.Size())
+	require.Equal(t, modTime, info.ModTime())
+
+	_, err = fs.Stat(fsys, "etc/hosts/missing")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,91 @@
+package filesystem
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/rstms/iso-kit/pkg/iso9660/extensions"
+	"sort"
+)
+
+// Linux stores POSIX ACLs in the "system.posix_acl_access" and "system.posix_acl_default" extended attributes as a
+// little-endian version number followed by (tag uint16, perm uint16, id uint32) entries sorted by tag and id.
+const (
+	XATTR_POSIX_ACL_ACCESS  = "system.posix_acl_access"
+	XATTR_POSIX_ACL_DEFAULT = "system.posix_acl_default"
+
+	posixACLVersion   = 2
+	posixACLUndefined = 0xFFFFFFFF
+)
+
+var posixACLTags = map[extensions.ACLTag]uint16{
+	extensions.ACL_USER_OBJ:  0x01,
+	extensions.ACL_USER_N:    0x02,
+	extensions.ACL_GROUP_OBJ: 0x04,
+	extensions.ACL_GROUP_N:   0x08,
+	extensions.ACL_MASK:      0x10,
+	extensions.ACL_OTHER:     0x20,
+}
+
+// marshalPosixACL encodes ACL entries in the Linux xattr format. Entries which name a user or group instead of
+// carrying a numeric id cannot be represented and are skipped.
+func marshalPosixACL(entries []extensions.ACLEntry) []byte {
+	type posixEntry struct {
+		tag  uint16
+		perm uint16
+		id   uint32
+	}
+
+	var out []posixEntry
+	for _, entry := range entries {
+		tag, ok := posixACLTags[entry.Tag]
+		if !ok {
+			continue
+		}
+		id := uint32(posixACLUndefined)
+		if entry.Tag == extensions.ACL_USER_N || entry.Tag == extensions.ACL_GROUP_N {
+			id = entry.ID
+		}
+		out = append(out, posixEntry{tag: tag, perm: uint16(entry.Perm), id: id})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].tag != out[j].tag {
+			return out[i].tag < out[j].tag
+		}
+		return out[i].id < out[j].id
+	})
+
+	buf := make([]byte, 4+8*len(out))
+	binary.LittleEndian.PutUint32(buf, posixACLVersion)
+	for i, entry := range out {
+		binary.LittleEndian.PutUint16(buf[4+i*8:], entry.tag)
+		binary.LittleEndian.PutUint16(buf[6+i*8:], entry.perm)
+		binary.LittleEndian.PutUint32(buf[8+i*8:], entry.id)
+	}
+	return buf
+}
+
+// unmarshalPosixACL decodes ACL entries from the Linux xattr format.
+func unmarshalPosixACL(data []byte) ([]extensions.ACLEntry, error) {
+	if len(data) < 4 || (len(data)-4)%8 != 0 || binary.LittleEndian.Uint32(data) != posixACLVersion {
+		return nil, errors.New("invalid POSIX ACL xattr")
+	}
+
+	var entries []extensions.ACLEntry
+	for off := 4; off < len(data); off += 8 {
+		tag := binary.LittleEndian.Uint16(data[off:])
+		entry := extensions.ACLEntry{Perm: uint8(binary.LittleEndian.Uint16(data[off+2:]) & 0x07)}
+		for aclTag, posixTag := range posixACLTags {
+			if posixTag == tag {
+				entry.Tag = aclTag
+			}
+		}
+		if entry.Tag == 0 {
+			return nil, errors.New("unknown POSIX ACL tag")
+		}
+		if entry.Tag == extensions.ACL_USER_N || entry.Tag == extensions.ACL_GROUP_N {
+			entry.ID = binary.LittleEndian.Uint32(data[off+4:])
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
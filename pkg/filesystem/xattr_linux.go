@@ -0,0 +1,104 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/rstms/iso-kit/pkg/iso9660/extensions"
+	"golang.org/x/sys/unix"
+)
+
+// ReadExtendedAttributes returns the extended attributes and POSIX ACL of the file at path. The ACL xattrs are
+// returned in the ACL rather than as attributes. Symbolic links are not followed, their own attributes are returned.
+func ReadExtendedAttributes(path string) ([]extensions.ExtendedAttribute, *extensions.ACL, error) {
+	size, err := unix.Llistxattr(path, nil)
+	if err != nil {
+		if errors.Is(err, unix.ENOTSUP) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to list xattrs of %s: %w", path, err)
+	}
+	if size == 0 {
+		return nil, nil, nil
+	}
+	list := make([]byte, size)
+	if size, err = unix.Llistxattr(path, list); err != nil {
+		return nil, nil, fmt.Errorf("failed to list xattrs of %s: %w", path, err)
+	}
+
+	var attrs []extensions.ExtendedAttribute
+	var acl *extensions.ACL
+	for _, name := range bytes.Split(bytes.TrimRight(list[:size], "\x00"), []byte{0}) {
+		value, err := getxattr(path, string(name))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch string(name) {
+		case XATTR_POSIX_ACL_ACCESS, XATTR_POSIX_ACL_DEFAULT:
+			entries, err := unmarshalPosixACL(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to decode %s of %s: %w", name, path, err)
+			}
+			if acl == nil {
+				acl = &extensions.ACL{}
+			}
+			if string(name) == XATTR_POSIX_ACL_ACCESS {
+				acl.Access = entries
+			} else {
+				acl.Default = entries
+			}
+		default:
+			attrs = append(attrs, extensions.ExtendedAttribute{Name: string(name), Value: value})
+		}
+	}
+
+	return attrs, acl, nil
+}
+
+// WriteExtendedAttributes sets the extended attributes and POSIX ACL on the file at path without following symbolic
+// links. Every attribute is attempted, the returned error joins the failures.
+func WriteExtendedAttributes(path string, attrs []extensions.ExtendedAttribute, acl *extensions.ACL) error {
+	var errs []error
+	for _, attr := range attrs {
+		if err := unix.Lsetxattr(path, attr.Name, attr.Value, 0); err != nil {
+			errs = append(errs, fmt.Errorf("failed to set xattr %s on %s: %w", attr.Name, path, err))
+		}
+	}
+
+	if acl != nil {
+		if len(acl.Access) > 0 {
+			if err := unix.Lsetxattr(path, XATTR_POSIX_ACL_ACCESS, marshalPosixACL(acl.Access), 0); err != nil {
+				errs = append(errs, fmt.Errorf("failed to set access ACL on %s: %w", path, err))
+			}
+		}
+		if len(acl.Default) > 0 {
+			if err := unix.Lsetxattr(path, XATTR_POSIX_ACL_DEFAULT, marshalPosixACL(acl.Default), 0); err != nil {
+				errs = append(errs, fmt.Errorf("failed to set default ACL on %s: %w", path, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func getxattr(path, name string) ([]byte, error) {
+	for {
+		size, err := unix.Lgetxattr(path, name, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read xattr %s of %s: %w", name, path, err)
+		}
+		value := make([]byte, size)
+		n, err := unix.Lgetxattr(path, name, value)
+		if errors.Is(err, unix.ERANGE) {
+			// The value grew between the two calls
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read xattr %s of %s: %w", name, path, err)
+		}
+		return value[:n], nil
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package filesystem
+
+import (
+	"errors"
+	"github.com/rstms/iso-kit/pkg/iso9660/extensions"
+)
+
+// ReadExtendedAttributes is only supported on Linux, other platforms report no attributes.
+func ReadExtendedAttributes(path string) ([]extensions.ExtendedAttribute, *extensions.ACL, error) {
+	return nil, nil, nil
+}
+
+// WriteExtendedAttributes is only supported on Linux.
+func WriteExtendedAttributes(path string, attrs []extensions.ExtendedAttribute, acl *extensions.ACL) error {
+	if len(attrs) == 0 && acl == nil {
+		return nil
+	}
+	return errors.New("restoring extended attributes is not supported on this platform")
+}
@@ -0,0 +1,111 @@
+package hfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Hybrid Mac/PC discs describe their HFS volume with an Apple Partition Map placed in the ISO9660 system area. Block 0
+// holds the driver descriptor map with the block size, the partition map entries follow in blocks 1 to n.
+//
+// Partition map entry layout (big-endian):
+//
+//	Offset 0:  Signature "PM"
+//	Offset 4:  Number of blocks in the partition map
+//	Offset 8:  First physical block of the partition
+//	Offset 12: Number of blocks in the partition
+//	Offset 16: Partition name (32 bytes)
+//	Offset 48: Partition type (32 bytes), e.g. "Apple_HFS"
+const (
+	DRIVER_DESCRIPTOR_SIGNATURE = 0x4552 // "ER"
+	PARTITION_MAP_SIGNATURE     = 0x504D // "PM"
+	PARTITION_TYPE_HFS          = "Apple_HFS"
+
+	apmDefaultBlockSize = 512
+	// apmMaxEntries bounds the partition map so a corrupt entry count cannot cause excessive reads
+	apmMaxEntries = 256
+)
+
+// Partition is an entry of the Apple Partition Map.
+type Partition struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Offset is the byte offset of the partition from the start of the image
+	Offset int64 `json:"offset"`
+	// Size is the size of the partition in bytes
+	Size int64 `json:"size"`
+}
+
+// ReadPartitionMap reads the Apple Partition Map from the start of the image.
+func ReadPartitionMap(r io.ReaderAt) ([]*Partition, error) {
+	block := make([]byte, apmDefaultBlockSize)
+	if _, err := r.ReadAt(block, 0); err != nil {
+		return nil, fmt.Errorf("failed to read driver descriptor map: %w", err)
+	}
+
+	// The partition map uses the block size of the driver descriptor map, fall back to 512 byte blocks if it is
+	// missing or the entries are not found at that size
+	blockSizes := []int64{apmDefaultBlockSize}
+	if binary.BigEndian.Uint16(block[0:2]) == DRIVER_DESCRIPTOR_SIGNATURE {
+		if size := int64(binary.BigEndian.Uint16(block[2:4])); size != apmDefaultBlockSize && size >= 512 && size%512 == 0 {
+			blockSizes = append([]int64{size}, blockSizes...)
+		}
+	}
+
+	for _, blockSize := range blockSizes {
+		partitions, err := readPartitionEntries(r, blockSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(partitions) > 0 {
+			return partitions, nil
+		}
+	}
+	return nil, errors.New("no Apple Partition Map found")
+}
+
+func readPartitionEntries(r io.ReaderAt, blockSize int64) ([]*Partition, error) {
+	var partitions []*Partition
+	entry := make([]byte, apmDefaultBlockSize)
+	count := uint32(1)
+	for i := uint32(1); i <= count && i <= apmMaxEntries; i++ {
+		if _, err := r.ReadAt(entry, int64(i)*blockSize); err != nil {
+			return nil, fmt.Errorf("failed to read partition map entry %d: %w", i, err)
+		}
+		if binary.BigEndian.Uint16(entry[0:2]) != PARTITION_MAP_SIGNATURE {
+			break
+		}
+		count = binary.BigEndian.Uint32(entry[4:8])
+		partitions = append(partitions, &Partition{
+			Name:   cString(entry[16:48]),
+			Type:   cString(entry[48:80]),
+			Offset: int64(binary.BigEndian.Uint32(entry[8:12])) * blockSize,
+			Size:   int64(binary.BigEndian.Uint32(entry[12:16])) * blockSize,
+		})
+	}
+	return partitions, nil
+}
+
+// FindHFSPartition returns the first Apple_HFS partition of the image's partition map.
+func FindHFSPartition(r io.ReaderAt) (*Partition, error) {
+	partitions, err := ReadPartitionMap(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, partition := range partitions {
+		if partition.Type == PARTITION_TYPE_HFS {
+			return partition, nil
+		}
+	}
+	return nil, errors.New("no Apple_HFS partition found")
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
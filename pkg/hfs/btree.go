@@ -0,0 +1,86 @@
+package hfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// HFS and HFS+ keep the catalog and extents overflow files as B-trees. Every node starts with a 14 byte descriptor
+// and ends with a table of record offsets growing backwards from the end of the node. Node 0 is the header node whose
+// first record holds the location of the leaf nodes and the node size.
+const (
+	nodeDescriptorSize = 14
+	nodeKindLeaf       = -1
+	nodeKindHeader     = 1
+)
+
+type btree struct {
+	fork      io.ReaderAt
+	nodeSize  int
+	firstLeaf uint32
+	nodes     uint32
+}
+
+// openBTree reads the header node of a B-tree file.
+func openBTree(fork io.ReaderAt) (*btree, error) {
+	header := make([]byte, 512)
+	if _, err := fork.ReadAt(header, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read B-tree header node: %w", err)
+	}
+	if int8(header[8]) != nodeKindHeader {
+		return nil, errors.New("invalid B-tree header node")
+	}
+
+	record := header[nodeDescriptorSize:]
+	t := &btree{
+		fork:      fork,
+		firstLeaf: binary.BigEndian.Uint32(record[10:14]),
+		nodeSize:  int(binary.BigEndian.Uint16(record[18:20])),
+		nodes:     binary.BigEndian.Uint32(record[22:26]),
+	}
+	if t.nodeSize < 512 || t.nodeSize&(t.nodeSize-1) != 0 {
+		return nil, fmt.Errorf("invalid B-tree node size %d", t.nodeSize)
+	}
+	return t, nil
+}
+
+// leafRecords returns the records of all leaf nodes in key order by following the leaf node chain.
+func (t *btree) leafRecords() ([][]byte, error) {
+	var records [][]byte
+	node := make([]byte, t.nodeSize)
+	visited := make(map[uint32]bool)
+
+	for index := t.firstLeaf; index != 0; {
+		if visited[index] || index >= t.nodes {
+			return nil, fmt.Errorf("invalid B-tree leaf node %d", index)
+		}
+		visited[index] = true
+
+		if _, err := t.fork.ReadAt(node, int64(index)*int64(t.nodeSize)); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read B-tree node %d: %w", index, err)
+		}
+		if int8(node[8]) != nodeKindLeaf {
+			return nil, fmt.Errorf("B-tree node %d is not a leaf node", index)
+		}
+
+		numRecords := int(binary.BigEndian.Uint16(node[10:12]))
+		if nodeDescriptorSize+2*(numRecords+1) > t.nodeSize {
+			return nil, fmt.Errorf("invalid record count in B-tree node %d", index)
+		}
+		offset := func(i int) int {
+			return int(binary.BigEndian.Uint16(node[t.nodeSize-2*(i+1):]))
+		}
+		for i := 0; i < numRecords; i++ {
+			start, end := offset(i), offset(i+1)
+			if start < nodeDescriptorSize || end > t.nodeSize || start > end {
+				return nil, fmt.Errorf("invalid record offset in B-tree node %d", index)
+			}
+			records = append(records, append([]byte(nil), node[start:end]...))
+		}
+
+		index = binary.BigEndian.Uint32(node[0:4])
+	}
+	return records, nil
+}
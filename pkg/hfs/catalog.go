@@ -0,0 +1,235 @@
+package hfs
+
+import (
+	"encoding/binary"
+	"sort"
+	"time"
+	"unicode/utf16"
+)
+
+// Catalog record types, HFS stores them in one byte and HFS+ in two
+const (
+	recordFolder       = 1
+	recordFile         = 2
+	recordFolderThread = 3
+	recordFileThread   = 4
+
+	forkTypeData     = 0x00
+	forkTypeResource = 0xFF
+
+	// ROOT_PARENT_ID is the parent of the root folder, ROOT_FOLDER_ID the id of the root folder itself
+	ROOT_PARENT_ID = 1
+	ROOT_FOLDER_ID = 2
+
+	// macEpochOffset is the number of seconds between 1904-01-01 and 1970-01-01
+	macEpochOffset = 2082844800
+)
+
+// catalogEntry is a folder or file record of the catalog file.
+type catalogEntry struct {
+	parentID uint32
+	name     string
+	id       uint32
+	isDir    bool
+
+	dataFork     forkData
+	resourceFork forkData
+
+	fileType    string
+	creator     string
+	finderFlags uint16
+
+	createDate time.Time
+	modifyDate time.Time
+	accessDate time.Time
+
+	// HFS+ BSD information, the mode is zero if it was never set
+	uid  *uint32
+	gid  *uint32
+	mode uint16
+}
+
+// forkKey identifies the overflow extents of a fork.
+type forkKey struct {
+	fileID   uint32
+	forkType uint8
+}
+
+// overflowExtent is a record of the extents overflow file.
+type overflowExtent struct {
+	startBlock uint32
+	extents    []extent
+}
+
+func macTime(seconds uint32) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds)-macEpochOffset, 0).UTC()
+}
+
+// parseHFSCatalogRecord decodes a leaf record of an HFS catalog. Thread records and deleted records return nil.
+func parseHFSCatalogRecord(record []byte) *catalogEntry {
+	keyLength := int(record[0])
+	if keyLength < 6 || len(record) < keyLength+1 {
+		return nil
+	}
+	nameLength := int(record[6])
+	if 7+nameLength > keyLength+1 {
+		return nil
+	}
+	// Record data starts on a word boundary after the key
+	dataOffset := (keyLength + 2) &^ 1
+	if dataOffset >= len(record) {
+		return nil
+	}
+	data := record[dataOffset:]
+
+	entry := &catalogEntry{
+		parentID: binary.BigEndian.Uint32(record[2:6]),
+		name:     decodeMacRoman(record[7 : 7+nameLength]),
+	}
+
+	switch data[0] {
+	case recordFolder:
+		if len(data) < 70 {
+			return nil
+		}
+		entry.isDir = true
+		entry.id = binary.BigEndian.Uint32(data[6:10])
+		entry.createDate = macTime(binary.BigEndian.Uint32(data[10:14]))
+		entry.modifyDate = macTime(binary.BigEndian.Uint32(data[14:18]))
+		entry.finderFlags = binary.BigEndian.Uint16(data[30:32])
+	case recordFile:
+		if len(data) < 102 {
+			return nil
+		}
+		entry.fileType = string(data[4:8])
+		entry.creator = string(data[8:12])
+		entry.finderFlags = binary.BigEndian.Uint16(data[12:14])
+		entry.id = binary.BigEndian.Uint32(data[20:24])
+		entry.dataFork.size = uint64(binary.BigEndian.Uint32(data[26:30]))
+		entry.resourceFork.size = uint64(binary.BigEndian.Uint32(data[36:40]))
+		entry.createDate = macTime(binary.BigEndian.Uint32(data[44:48]))
+		entry.modifyDate = macTime(binary.BigEndian.Uint32(data[48:52]))
+		entry.dataFork.extents = parseHFSExtents(data[74:86])
+		entry.resourceFork.extents = parseHFSExtents(data[86:98])
+	default:
+		return nil
+	}
+	return entry
+}
+
+// parseHFSExtents decodes an HFS extent record of three (start, count) 16-bit pairs.
+func parseHFSExtents(b []byte) []extent {
+	var extents []extent
+	for i := 0; i+4 <= len(b); i += 4 {
+		count := binary.BigEndian.Uint16(b[i+2 : i+4])
+		if count == 0 {
+			break
+		}
+		extents = append(extents, extent{start: uint32(binary.BigEndian.Uint16(b[i : i+2])), count: uint32(count)})
+	}
+	return extents
+}
+
+// parseHFSPlusCatalogRecord decodes a leaf record of an HFS+ catalog. Thread records return nil.
+func parseHFSPlusCatalogRecord(record []byte) *catalogEntry {
+	if len(record) < 8 {
+		return nil
+	}
+	keyLength := int(binary.BigEndian.Uint16(record[0:2]))
+	nameLength := int(binary.BigEndian.Uint16(record[6:8]))
+	if 8+2*nameLength > keyLength+2 || keyLength+4 > len(record) {
+		return nil
+	}
+	data := record[keyLength+2:]
+
+	name := make([]uint16, nameLength)
+	for i := range name {
+		name[i] = binary.BigEndian.Uint16(record[8+2*i:])
+	}
+	entry := &catalogEntry{
+		parentID: binary.BigEndian.Uint32(record[2:6]),
+		name:     string(utf16.Decode(name)),
+	}
+
+	recordType := binary.BigEndian.Uint16(data[0:2])
+	switch {
+	case recordType == recordFolder && len(data) >= 88:
+		entry.isDir = true
+		entry.id = binary.BigEndian.Uint32(data[8:12])
+		entry.finderFlags = binary.BigEndian.Uint16(data[56:58])
+	case recordType == recordFile && len(data) >= 248:
+		entry.id = binary.BigEndian.Uint32(data[8:12])
+		entry.fileType = string(data[48:52])
+		entry.creator = string(data[52:56])
+		entry.finderFlags = binary.BigEndian.Uint16(data[56:58])
+		entry.dataFork = parseHFSPlusFork(data[88:168])
+		entry.resourceFork = parseHFSPlusFork(data[168:248])
+	default:
+		return nil
+	}
+
+	entry.createDate = macTime(binary.BigEndian.Uint32(data[12:16]))
+	entry.modifyDate = macTime(binary.BigEndian.Uint32(data[16:20]))
+	entry.accessDate = macTime(binary.BigEndian.Uint32(data[24:28]))
+
+	// BSD info: owner, group, admin and owner flags, mode
+	if mode := binary.BigEndian.Uint16(data[42:44]); mode != 0 {
+		uid := binary.BigEndian.Uint32(data[32:36])
+		gid := binary.BigEndian.Uint32(data[36:40])
+		entry.uid, entry.gid, entry.mode = &uid, &gid, mode
+	}
+	return entry
+}
+
+// parseHFSPlusFork decodes an 80 byte HFSPlusForkData structure.
+func parseHFSPlusFork(b []byte) forkData {
+	return forkData{
+		size:    binary.BigEndian.Uint64(b[0:8]),
+		extents: parseHFSPlusExtents(b[16:80]),
+	}
+}
+
+// parseHFSPlusExtents decodes an HFS+ extent record of eight (start, count) 32-bit pairs.
+func parseHFSPlusExtents(b []byte) []extent {
+	var extents []extent
+	for i := 0; i+8 <= len(b); i += 8 {
+		count := binary.BigEndian.Uint32(b[i+4 : i+8])
+		if count == 0 {
+			break
+		}
+		extents = append(extents, extent{start: binary.BigEndian.Uint32(b[i : i+4]), count: count})
+	}
+	return extents
+}
+
+// parseOverflowRecords groups the records of an extents overflow file by fork, ordered by their first file block.
+func parseOverflowRecords(records [][]byte, plus bool) map[forkKey][]overflowExtent {
+	overflow := make(map[forkKey][]overflowExtent)
+	for _, record := range records {
+		var key forkKey
+		var ext overflowExtent
+		if plus {
+			if len(record) < 12+64 {
+				continue
+			}
+			key = forkKey{fileID: binary.BigEndian.Uint32(record[4:8]), forkType: record[2]}
+			ext = overflowExtent{startBlock: binary.BigEndian.Uint32(record[8:12]), extents: parseHFSPlusExtents(record[12:76])}
+		} else {
+			if len(record) < 8+12 {
+				continue
+			}
+			key = forkKey{fileID: binary.BigEndian.Uint32(record[2:6]), forkType: record[1]}
+			ext = overflowExtent{startBlock: uint32(binary.BigEndian.Uint16(record[6:8])), extents: parseHFSExtents(record[8:20])}
+		}
+		overflow[key] = append(overflow[key], ext)
+	}
+	for key := range overflow {
+		sort.Slice(overflow[key], func(i, j int) bool {
+			return overflow[key][i].startBlock < overflow[key][j].startBlock
+		})
+	}
+	return overflow
+}
@@ -0,0 +1,74 @@
+package hfs
+
+import (
+	"errors"
+	"io"
+)
+
+// extent is a run of contiguous allocation blocks.
+type extent struct {
+	start uint32
+	count uint32
+}
+
+// forkData describes the data or resource fork of a file.
+type forkData struct {
+	size    uint64
+	extents []extent
+}
+
+// blocks returns the number of allocation blocks covered by the extents.
+func (f *forkData) blocks() uint64 {
+	var total uint64
+	for _, e := range f.extents {
+		total += uint64(e.count)
+	}
+	return total
+}
+
+// forkReader is an io.ReaderAt over the logical content of a fork.
+type forkReader struct {
+	reader io.ReaderAt
+	// base is the byte offset of allocation block 0
+	base      int64
+	blockSize int64
+	fork      forkData
+}
+
+func (f *forkReader) Size() int64 {
+	return int64(f.fork.size)
+}
+
+func (f *forkReader) ReadAt(p []byte, off int64) (int, error) {
+	size := f.Size()
+	n := 0
+	for n < len(p) && off < size {
+		// Locate the extent holding the offset
+		block := uint64(off / f.blockSize)
+		position, remaining := int64(-1), int64(0)
+		for _, e := range f.fork.extents {
+			if block < uint64(e.count) {
+				position = (int64(e.start)+int64(block))*f.blockSize + off%f.blockSize
+				remaining = (int64(e.count)-int64(block))*f.blockSize - off%f.blockSize
+				break
+			}
+			block -= uint64(e.count)
+		}
+		if position < 0 {
+			return n, errors.New("read beyond the extents of the fork")
+		}
+
+		chunk := int64(len(p) - n)
+		chunk = min(chunk, remaining, size-off)
+		m, err := f.reader.ReadAt(p[n:n+int(chunk)], f.base+position)
+		n += m
+		off += int64(m)
+		if err != nil && !(err == io.EOF && int64(m) == chunk) {
+			return n, err
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
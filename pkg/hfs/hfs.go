@@ -0,0 +1,277 @@
+package hfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/rstms/iso-kit/pkg/filesystem"
+	"github.com/rstms/iso-kit/pkg/iso9660/extensions"
+	"io"
+	"os"
+	"strings"
+)
+
+// HFS volumes start with the Master Directory Block and HFS+ volumes with the Volume Header, both 1024 bytes into the
+// volume. An HFS volume may wrap an embedded HFS+ volume which is then used instead.
+const (
+	HFS_SIGNATURE      = 0x4244 // "BD"
+	HFS_PLUS_SIGNATURE = 0x482B // "H+"
+	HFSX_SIGNATURE     = 0x4858 // "HX"
+
+	volumeHeaderOffset = 1024
+	// Catalog node ids of the B-tree files
+	extentsFileID = 3
+	catalogFileID = 4
+)
+
+// Volume is a read-only HFS or HFS+ volume.
+type Volume struct {
+	// Name is the volume name
+	Name string `json:"name"`
+	// Plus is true for HFS+ and HFSX volumes
+	Plus bool `json:"plus"`
+
+	reader    io.ReaderAt
+	base      int64
+	blockSize int64
+	catalog   forkData
+	extents   forkData
+	overflow  map[forkKey][]overflowExtent
+	records   []*catalogEntry
+}
+
+// Open opens the HFS or HFS+ volume starting at offset in the reader.
+func Open(r io.ReaderAt, offset int64) (*Volume, error) {
+	header := make([]byte, 512)
+	if _, err := r.ReadAt(header, offset+volumeHeaderOffset); err != nil {
+		return nil, fmt.Errorf("failed to read HFS volume header: %w", err)
+	}
+
+	switch binary.BigEndian.Uint16(header[0:2]) {
+	case HFS_PLUS_SIGNATURE, HFSX_SIGNATURE:
+		return openHFSPlus(r, offset, header)
+	case HFS_SIGNATURE:
+		allocationStart := int64(binary.BigEndian.Uint16(header[28:30])) * 512
+		blockSize := int64(binary.BigEndian.Uint32(header[20:24]))
+		// An embedded HFS+ volume is described by the signature and extent in place of the HFS volume cache sizes
+		if binary.BigEndian.Uint16(header[124:126]) == HFS_PLUS_SIGNATURE {
+			embedded := offset + allocationStart + int64(binary.BigEndian.Uint16(header[126:128]))*blockSize
+			return openEmbedded(r, offset, embedded)
+		}
+		return openHFS(r, offset, header)
+	default:
+		return nil, errors.New("no HFS or HFS+ volume found")
+	}
+}
+
+// openEmbedded opens the HFS+ volume embedded in the HFS wrapper volume at offset. The embedded volume has to start
+// past the wrapper and cannot wrap a further volume, so a corrupt wrapper cannot refer back to itself.
+func openEmbedded(r io.ReaderAt, offset, embedded int64) (*Volume, error) {
+	if embedded <= offset {
+		return nil, fmt.Errorf("invalid embedded HFS+ volume offset %d", embedded)
+	}
+	header := make([]byte, 512)
+	if _, err := r.ReadAt(header, embedded+volumeHeaderOffset); err != nil {
+		return nil, fmt.Errorf("failed to read embedded HFS+ volume header: %w", err)
+	}
+	switch binary.BigEndian.Uint16(header[0:2]) {
+	case HFS_PLUS_SIGNATURE, HFSX_SIGNATURE:
+		return openHFSPlus(r, embedded, header)
+	default:
+		return nil, errors.New("no embedded HFS+ volume found")
+	}
+}
+
+func openHFS(r io.ReaderAt, offset int64, mdb []byte) (*Volume, error) {
+	v := &Volume{
+		Name:      decodeMacRoman(mdb[37 : 37+min(int(mdb[36]), 27)]),
+		reader:    r,
+		base:      offset + int64(binary.BigEndian.Uint16(mdb[28:30]))*512,
+		blockSize: int64(binary.BigEndian.Uint32(mdb[20:24])),
+		extents: forkData{
+			size:    uint64(binary.BigEndian.Uint32(mdb[130:134])),
+			extents: parseHFSExtents(mdb[134:146]),
+		},
+		catalog: forkData{
+			size:    uint64(binary.BigEndian.Uint32(mdb[146:150])),
+			extents: parseHFSExtents(mdb[150:162]),
+		},
+	}
+	if v.blockSize == 0 || v.blockSize%512 != 0 {
+		return nil, fmt.Errorf("invalid HFS allocation block size %d", v.blockSize)
+	}
+	if err := v.load(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func openHFSPlus(r io.ReaderAt, offset int64, header []byte) (*Volume, error) {
+	v := &Volume{
+		Plus:      true,
+		reader:    r,
+		base:      offset,
+		blockSize: int64(binary.BigEndian.Uint32(header[40:44])),
+		extents:   parseHFSPlusFork(header[192:272]),
+		catalog:   parseHFSPlusFork(header[272:352]),
+	}
+	if v.blockSize == 0 || v.blockSize%512 != 0 {
+		return nil, fmt.Errorf("invalid HFS+ allocation block size %d", v.blockSize)
+	}
+	if err := v.load(); err != nil {
+		return nil, err
+	}
+
+	// HFS+ keeps the volume name only as the name of the root folder
+	for _, record := range v.records {
+		if record.isDir && record.id == ROOT_FOLDER_ID {
+			v.Name = record.name
+		}
+	}
+	return v, nil
+}
+
+// load reads the extents overflow and catalog files.
+func (v *Volume) load() error {
+	if err := v.readOverflow(); err != nil {
+		return err
+	}
+	records, err := v.readCatalog()
+	if err != nil {
+		return err
+	}
+	v.records = records
+	return nil
+}
+
+// readOverflow loads the extents overflow file which records the extents of fragmented forks.
+func (v *Volume) readOverflow() error {
+	v.overflow = make(map[forkKey][]overflowExtent)
+	if v.extents.size == 0 {
+		return nil
+	}
+	tree, err := openBTree(v.forkReader(v.extents))
+	if err != nil {
+		return fmt.Errorf("failed to open extents overflow file: %w", err)
+	}
+	records, err := tree.leafRecords()
+	if err != nil {
+		return fmt.Errorf("failed to read extents overflow file: %w", err)
+	}
+	v.overflow = parseOverflowRecords(records, v.Plus)
+
+	// The catalog file itself may be fragmented
+	v.catalog = v.completeFork(catalogFileID, forkTypeData, v.catalog)
+	return nil
+}
+
+// completeFork appends the extents recorded in the extents overflow file.
+func (v *Volume) completeFork(fileID uint32, forkType uint8, fork forkData) forkData {
+	for _, record := range v.overflow[forkKey{fileID: fileID, forkType: forkType}] {
+		if uint64(record.startBlock) != fork.blocks() {
+			break
+		}
+		fork.extents = append(fork.extents, record.extents...)
+	}
+	return fork
+}
+
+func (v *Volume) forkReader(fork forkData) *forkReader {
+	return &forkReader{reader: v.reader, base: v.base, blockSize: v.blockSize, fork: fork}
+}
+
+// readCatalog returns the folder and file records of the catalog in key order.
+func (v *Volume) readCatalog() ([]*catalogEntry, error) {
+	tree, err := openBTree(v.forkReader(v.catalog))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open catalog file: %w", err)
+	}
+	records, err := tree.leafRecords()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog file: %w", err)
+	}
+
+	var entries []*catalogEntry
+	for _, record := range records {
+		var entry *catalogEntry
+		if v.Plus {
+			entry = parseHFSPlusCatalogRecord(record)
+		} else {
+			entry = parseHFSCatalogRecord(record)
+		}
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// FileSystemEntries returns the folders and files of the volume. The data fork is the content of each entry, the
+// resource fork is linked as its associated file and the type, creator and Finder flags are recorded like the Apple
+// ISO 9660 extensions do.
+func (v *Volume) FileSystemEntries() []*filesystem.FileSystemEntry {
+	children := make(map[uint32][]*catalogEntry)
+	for _, record := range v.records {
+		// The HFS+ metadata folders for hard links have names starting with NUL characters
+		if strings.HasPrefix(record.name, "\x00") {
+			continue
+		}
+		children[record.parentID] = append(children[record.parentID], record)
+	}
+
+	var entries []*filesystem.FileSystemEntry
+	visited := make(map[uint32]bool)
+	var walk func(parentID uint32, parentPath string)
+	walk = func(parentID uint32, parentPath string) {
+		if visited[parentID] {
+			return
+		}
+		visited[parentID] = true
+
+		for _, child := range children[parentID] {
+			// HFS names may contain "/" which is shown as ":" by the Finder's POSIX layer
+			name := strings.ReplaceAll(child.name, "/", ":")
+			fullPath := parentPath + "/" + name
+			entries = append(entries, v.newEntry(child, name, fullPath))
+			if child.isDir {
+				walk(child.id, fullPath)
+			}
+		}
+	}
+	walk(ROOT_FOLDER_ID, "")
+
+	return entries
+}
+
+func (v *Volume) newEntry(child *catalogEntry, name, fullPath string) *filesystem.FileSystemEntry {
+	mode := os.FileMode(0o644)
+	if child.isDir {
+		mode = os.ModeDir | 0o755
+	}
+	if child.mode != 0 {
+		mode = mode&^os.ModePerm | os.FileMode(child.mode&0o777)
+	}
+
+	if child.isDir {
+		entry := filesystem.NewFileSystemEntry(name, fullPath, true, 0, 0, child.uid, child.gid, mode, child.createDate, child.modifyDate, nil, nil)
+		entry.AccessTime = child.accessDate
+		return entry
+	}
+
+	dataFork := v.completeFork(child.id, forkTypeData, child.dataFork)
+	entry := filesystem.NewFileSystemEntry(name, fullPath, false, uint32(dataFork.size), 0, child.uid, child.gid, mode, child.createDate, child.modifyDate, nil, v.forkReader(dataFork))
+	entry.Size = dataFork.size
+	entry.AccessTime = child.accessDate
+	entry.Apple = &extensions.AppleExtension{
+		SystemUseID: extensions.APPLE_HFS,
+		Type:        child.fileType,
+		Creator:     child.creator,
+		FinderFlags: child.finderFlags,
+	}
+
+	if child.resourceFork.size > 0 {
+		resourceFork := v.completeFork(child.id, forkTypeResource, child.resourceFork)
+		entry.AssociatedFile = filesystem.NewFileSystemEntry(name, fullPath, false, uint32(resourceFork.size), 0, child.uid, child.gid, mode, child.createDate, child.modifyDate, nil, v.forkReader(resourceFork))
+	}
+	return entry
+}
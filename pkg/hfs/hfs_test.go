@@ -0,0 +1,247 @@
+package hfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/require"
+)
+
+// buildNode lays out a B-tree node with the given kind and records.
+func buildNode(nodeSize int, kind int8, fLink uint32, records ...[]byte) []byte {
+	node := make([]byte, nodeSize)
+	binary.BigEndian.PutUint32(node[0:4], fLink)
+	node[8] = byte(kind)
+	binary.BigEndian.PutUint16(node[10:12], uint16(len(records)))
+	offset := nodeDescriptorSize
+	for i, record := range records {
+		binary.BigEndian.PutUint16(node[nodeSize-2*(i+1):], uint16(offset))
+		offset += copy(node[offset:], record)
+	}
+	binary.BigEndian.PutUint16(node[nodeSize-2*(len(records)+1):], uint16(offset))
+	return node
+}
+
+// buildCatalog returns a header node followed by one leaf node.
+func buildCatalog(nodeSize int, records ...[]byte) []byte {
+	header := make([]byte, 106)
+	binary.BigEndian.PutUint32(header[10:14], 1) // First leaf
+	binary.BigEndian.PutUint16(header[18:20], uint16(nodeSize))
+	binary.BigEndian.PutUint32(header[22:26], 2) // Total nodes
+	return append(buildNode(nodeSize, nodeKindHeader, 0, header), buildNode(nodeSize, nodeKindLeaf, 0, records...)...)
+}
+
+func hfsPlusKey(parentID uint32, name string) []byte {
+	chars := utf16.Encode([]rune(name))
+	key := make([]byte, 8+2*len(chars))
+	binary.BigEndian.PutUint16(key[0:2], uint16(len(key)-2))
+	binary.BigEndian.PutUint32(key[2:6], parentID)
+	binary.BigEndian.PutUint16(key[6:8], uint16(len(chars)))
+	for i, c := range chars {
+		binary.BigEndian.PutUint16(key[8+2*i:], c)
+	}
+	return key
+}
+
+func hfsPlusFolder(parentID, id uint32, name string) []byte {
+	data := make([]byte, 88)
+	binary.BigEndian.PutUint16(data[0:2], recordFolder)
+	binary.BigEndian.PutUint32(data[8:12], id)
+	return append(hfsPlusKey(parentID, name), data...)
+}
+
+func hfsPlusFile(parentID, id uint32, name string, size uint64, extents []extent, rsrcSize uint64, rsrc []extent) []byte {
+	data := make([]byte, 248)
+	binary.BigEndian.PutUint16(data[0:2], recordFile)
+	binary.BigEndian.PutUint32(data[8:12], id)
+	binary.BigEndian.PutUint32(data[32:36], 501)
+	binary.BigEndian.PutUint16(data[42:44], 0o100600)
+	copy(data[48:56], "TEXTttxt")
+	putHFSPlusFork(data[88:168], size, extents)
+	putHFSPlusFork(data[168:248], rsrcSize, rsrc)
+	return append(hfsPlusKey(parentID, name), data...)
+}
+
+func putHFSPlusFork(b []byte, size uint64, extents []extent) {
+	binary.BigEndian.PutUint64(b[0:8], size)
+	for i, e := range extents {
+		binary.BigEndian.PutUint32(b[16+8*i:], e.start)
+		binary.BigEndian.PutUint32(b[20+8*i:], e.count)
+	}
+}
+
+func hfsKey(parentID uint32, name string) []byte {
+	key := make([]byte, 7+len(name))
+	key[0] = byte(6 + len(name))
+	binary.BigEndian.PutUint32(key[2:6], parentID)
+	key[6] = byte(len(name))
+	copy(key[7:], name)
+	if len(key)%2 != 0 {
+		key = append(key, 0)
+	}
+	return key
+}
+
+func hfsFolder(parentID, id uint32, name string) []byte {
+	data := make([]byte, 70)
+	data[0] = recordFolder
+	binary.BigEndian.PutUint32(data[6:10], id)
+	return append(hfsKey(parentID, name), data...)
+}
+
+func hfsFile(parentID, id uint32, name string, size uint32, extents []extent, rsrcSize uint32, rsrc []extent) []byte {
+	data := make([]byte, 102)
+	data[0] = recordFile
+	copy(data[4:12], "TEXTttxt")
+	binary.BigEndian.PutUint32(data[20:24], id)
+	binary.BigEndian.PutUint32(data[26:30], size)
+	binary.BigEndian.PutUint32(data[36:40], rsrcSize)
+	for i, e := range extents {
+		binary.BigEndian.PutUint16(data[74+4*i:], uint16(e.start))
+		binary.BigEndian.PutUint16(data[76+4*i:], uint16(e.count))
+	}
+	for i, e := range rsrc {
+		binary.BigEndian.PutUint16(data[86+4*i:], uint16(e.start))
+		binary.BigEndian.PutUint16(data[88+4*i:], uint16(e.count))
+	}
+	return append(hfsKey(parentID, name), data...)
+}
+
+// buildImage places the volume behind an Apple Partition Map at block 64 and returns the image and file content.
+func buildImage(t *testing.T, plus bool) ([]byte, []byte) {
+	t.Helper()
+	const partitionStart = 64
+	image := make([]byte, (partitionStart+64)*512)
+	volume := image[partitionStart*512:]
+
+	// Driver descriptor map and a single partition map entry
+	binary.BigEndian.PutUint16(image[0:2], DRIVER_DESCRIPTOR_SIGNATURE)
+	binary.BigEndian.PutUint16(image[2:4], 512)
+	entry := image[512:1024]
+	binary.BigEndian.PutUint16(entry[0:2], PARTITION_MAP_SIGNATURE)
+	binary.BigEndian.PutUint32(entry[4:8], 1)
+	binary.BigEndian.PutUint32(entry[8:12], partitionStart)
+	binary.BigEndian.PutUint32(entry[12:16], 64)
+	copy(entry[48:], PARTITION_TYPE_HFS)
+
+	// A 600 byte data fork split across two allocation blocks and a resource fork
+	content := bytes.Repeat([]byte("0123456789"), 60)
+	dataExtents := []extent{{16, 1}, {20, 1}}
+	rsrcExtents := []extent{{24, 1}}
+	var base []byte
+	if plus {
+		base = volume
+		header := volume[volumeHeaderOffset:]
+		binary.BigEndian.PutUint16(header[0:2], HFS_PLUS_SIGNATURE)
+		binary.BigEndian.PutUint32(header[40:44], 512)
+		putHFSPlusFork(header[272:352], 2*1024, []extent{{8, 4}})
+		copy(volume[8*512:], buildCatalog(1024,
+			hfsPlusFolder(ROOT_PARENT_ID, ROOT_FOLDER_ID, "Hybrid"),
+			hfsPlusFile(ROOT_FOLDER_ID, 18, "a/b", 0, nil, 0, nil),
+			hfsPlusFolder(ROOT_FOLDER_ID, 16, "docs"),
+			hfsPlusFile(16, 17, "Read Me", uint64(len(content)), dataExtents, 4, rsrcExtents),
+		))
+	} else {
+		// Allocation blocks start at sector 4 of the volume
+		base = volume[4*512:]
+		mdb := volume[volumeHeaderOffset:]
+		binary.BigEndian.PutUint16(mdb[0:2], HFS_SIGNATURE)
+		binary.BigEndian.PutUint32(mdb[20:24], 512)
+		binary.BigEndian.PutUint16(mdb[28:30], 4)
+		mdb[36] = 6
+		copy(mdb[37:], "Hybrid")
+		binary.BigEndian.PutUint32(mdb[146:150], 2*512)
+		binary.BigEndian.PutUint16(mdb[150:152], 8)
+		binary.BigEndian.PutUint16(mdb[152:154], 2)
+		copy(base[8*512:], buildCatalog(512,
+			hfsFolder(ROOT_PARENT_ID, ROOT_FOLDER_ID, "Hybrid"),
+			hfsFile(ROOT_FOLDER_ID, 18, "a/b", 0, nil, 0, nil),
+			hfsFolder(ROOT_FOLDER_ID, 16, "docs"),
+			hfsFile(16, 17, "Read Me", uint32(len(content)), dataExtents, 4, rsrcExtents),
+		))
+	}
+	copy(base[16*512:], content[:512])
+	copy(base[20*512:], content[512:])
+	copy(base[24*512:], "rsrc")
+	return image, content
+}
+
+func TestVolume(t *testing.T) {
+	for _, plus := range []bool{false, true} {
+		image, content := buildImage(t, plus)
+		r := bytes.NewReader(image)
+
+		partition, err := FindHFSPartition(r)
+		require.NoError(t, err)
+		require.Equal(t, int64(64*512), partition.Offset)
+
+		volume, err := Open(r, partition.Offset)
+		require.NoError(t, err)
+		require.Equal(t, plus, volume.Plus)
+		require.Equal(t, "Hybrid", volume.Name)
+
+		entries := volume.FileSystemEntries()
+		require.Len(t, entries, 3)
+		require.Equal(t, "/a:b", entries[0].FullPath)
+		require.Equal(t, "/docs", entries[1].FullPath)
+		require.True(t, entries[1].IsDir)
+
+		file := entries[2]
+		require.Equal(t, "/docs/Read Me", file.FullPath)
+		require.Equal(t, uint64(len(content)), file.Size)
+		require.Equal(t, "TEXT", file.Apple.Type)
+		require.Equal(t, "ttxt", file.Apple.Creator)
+		if plus {
+			require.Equal(t, uint32(501), *file.UID)
+			require.Equal(t, "-rw-------", file.Mode.String())
+		}
+
+		data, err := file.GetBytes()
+		require.NoError(t, err)
+		require.Equal(t, content, data)
+
+		rsrc, err := file.AssociatedFile.ContentReader()
+		require.NoError(t, err)
+		got, err := io.ReadAll(io.NewSectionReader(rsrc, 0, int64(file.AssociatedFile.Size)))
+		require.NoError(t, err)
+		require.Equal(t, []byte("rsrc"), got)
+	}
+}
+
+func TestOpenCorruptCatalog(t *testing.T) {
+	for _, plus := range []bool{false, true} {
+		image, _ := buildImage(t, plus)
+		catalog := (64 + 8) * 512
+		if !plus {
+			catalog += 4 * 512
+		}
+		clear(image[catalog : catalog+1024])
+
+		volume, err := Open(bytes.NewReader(image), 64*512)
+		require.Error(t, err)
+		require.Nil(t, volume)
+	}
+}
+
+func TestOpenEmbeddedWrapper(t *testing.T) {
+	// HFS wrappers whose embedded HFS+ volume refers back to the wrapper or to another wrapper
+	image := make([]byte, 4096)
+	mdb := image[volumeHeaderOffset:]
+	binary.BigEndian.PutUint16(mdb[0:2], HFS_SIGNATURE)
+	binary.BigEndian.PutUint32(mdb[20:24], 512)
+	binary.BigEndian.PutUint16(mdb[124:126], HFS_PLUS_SIGNATURE)
+	_, err := Open(bytes.NewReader(image), 0)
+	require.Error(t, err)
+
+	binary.BigEndian.PutUint16(mdb[126:128], 2)
+	copy(image[2*512+volumeHeaderOffset:], mdb[:512])
+	_, err = Open(bytes.NewReader(image), 0)
+	require.Error(t, err)
+}
+
+func TestDecodeMacRoman(t *testing.T) {
+	require.Equal(t, "Café ™", decodeMacRoman([]byte{'C', 'a', 'f', 0x8E, ' ', 0xAA}))
+}
@@ -399,7 +399,8 @@ func (et *ElTorito) BuildBootImageEntries() ([]*filesystem.FileSystemEntry, erro
 			Name:       filename,
 			FullPath:   "/[BOOT]/" + filename, // Logical path inside the ISO
 			IsDir:      false,
-			Size:       uint32(entry.size * 512), // Convert 512-byte block size
+			Size:       uint64(entry.size) * 512, // Convert 512-byte block size
+			StoredSize: uint64(entry.size) * 512,
 			Location:   entry.location,
 			Mode:       0444,        // Read-only boot image
 			CreateTime: time.Time{}, // No real timestamp in El Torito
@@ -3,9 +3,13 @@ package descriptor
 import (
 	"fmt"
 	"github.com/rstms/iso-kit/pkg/consts"
+	"github.com/rstms/iso-kit/pkg/helpers"
 	"github.com/rstms/iso-kit/pkg/iso9660/directory"
+	"github.com/rstms/iso-kit/pkg/iso9660/encoding"
 	"github.com/rstms/iso-kit/pkg/iso9660/info"
+	"github.com/rstms/iso-kit/pkg/iso9660/validation"
 	"github.com/rstms/iso-kit/pkg/logging"
+	"strings"
 	"time"
 )
 
@@ -14,6 +18,43 @@ const (
 	PARTITION_SYSTEM_USE_SIZE = consts.ISO9660_SECTOR_SIZE - 88
 )
 
+// NewVolumePartitionDescriptor creates a Volume Partition Descriptor for the partition of size logical blocks starting
+// at logical block location. The system use area is recorded as given and must not exceed PARTITION_SYSTEM_USE_SIZE.
+func NewVolumePartitionDescriptor(systemID, partitionID string, location, size uint32, systemUse []byte) (*VolumePartitionDescriptor, error) {
+	if len(systemID) > 32 {
+		return nil, fmt.Errorf("system identifier %q exceeds 32 bytes", systemID)
+	}
+	if err := validation.ValidateACharacters(systemID, false); err != nil {
+		return nil, fmt.Errorf("invalid system identifier: %w", err)
+	}
+	if len(partitionID) > 32 {
+		return nil, fmt.Errorf("volume partition identifier %q exceeds 32 bytes", partitionID)
+	}
+	if err := validation.ValidateDCharacters(partitionID, false); err != nil {
+		return nil, fmt.Errorf("invalid volume partition identifier: %w", err)
+	}
+	if len(systemUse) > PARTITION_SYSTEM_USE_SIZE {
+		return nil, fmt.Errorf("system use (%d bytes) exceeds %d bytes", len(systemUse), PARTITION_SYSTEM_USE_SIZE)
+	}
+
+	vpd := &VolumePartitionDescriptor{
+		VolumeDescriptorHeader: VolumeDescriptorHeader{
+			VolumeDescriptorType:    TYPE_PARTITION_DESCRIPTOR,
+			StandardIdentifier:      consts.ISO9660_STD_IDENTIFIER,
+			VolumeDescriptorVersion: consts.ISO9660_VOLUME_DESC_VERSION,
+		},
+		VolumePartitionDescriptorBody: VolumePartitionDescriptorBody{
+			SystemIdentifier:          systemID,
+			VolumePartitionIdentifier: partitionID,
+			VolumePartitionLocation:   location,
+			VolumePartitionSize:       size,
+			ObjectSize:                consts.ISO9660_SECTOR_SIZE,
+		},
+	}
+	copy(vpd.SystemUse[:], systemUse)
+	return vpd, nil
+}
+
 type VolumePartitionDescriptor struct {
 	VolumeDescriptorHeader
 	VolumePartitionDescriptorBody
@@ -23,84 +64,81 @@ func (d *VolumePartitionDescriptor) DescriptorType() VolumeDescriptorType {
 	return TYPE_PARTITION_DESCRIPTOR
 }
 
+// VolumeIdentifier returns the volume partition identifier, a partition descriptor does not identify a volume.
 func (d *VolumePartitionDescriptor) VolumeIdentifier() string {
-	//TODO implement me
-	panic("implement me")
+	return d.VolumePartitionIdentifier
 }
 
 func (d *VolumePartitionDescriptor) SystemIdentifier() string {
-	//TODO implement me
-	panic("implement me")
+	return d.VolumePartitionDescriptorBody.SystemIdentifier
 }
 
 func (d *VolumePartitionDescriptor) VolumeSetIdentifier() string {
-	//TODO implement me
-	panic("implement me")
+	return ""
 }
 
 func (d *VolumePartitionDescriptor) PublisherIdentifier() string {
-	//TODO implement me
-	panic("implement me")
+	return ""
 }
 
 func (d *VolumePartitionDescriptor) DataPreparerIdentifier() string {
-	//TODO implement me
-	panic("implement me")
+	return ""
 }
 
 func (d *VolumePartitionDescriptor) ApplicationIdentifier() string {
-	//TODO implement me
-	panic("implement me")
+	return ""
 }
 
 func (d *VolumePartitionDescriptor) CopyrightFileIdentifier() string {
-	//TODO implement me
-	panic("implement me")
+	return ""
 }
 
 func (d *VolumePartitionDescriptor) AbstractFileIdentifier() string {
-	//TODO implement me
-	panic("implement me")
+	return ""
 }
 
 func (d *VolumePartitionDescriptor) BibliographicFileIdentifier() string {
-	//TODO implement me
-	panic("implement me")
+	return ""
 }
 
 func (d *VolumePartitionDescriptor) VolumeCreationDateTime() time.Time {
-	//TODO implement me
-	panic("implement me")
+	return time.Time{}
 }
 
 func (d *VolumePartitionDescriptor) VolumeModificationDateTime() time.Time {
-	//TODO implement me
-	panic("implement me")
+	return time.Time{}
 }
 
 func (d *VolumePartitionDescriptor) VolumeExpirationDateTime() time.Time {
-	//TODO implement me
-	panic("implement me")
+	return time.Time{}
 }
 
 func (d *VolumePartitionDescriptor) VolumeEffectiveDateTime() time.Time {
-	//TODO implement me
-	panic("implement me")
+	return time.Time{}
+}
+
+func (d *VolumePartitionDescriptor) LocationOfPathTableL() uint32 {
+	return 0
+}
+
+func (d *VolumePartitionDescriptor) LocationOfPathTableM() uint32 {
+	return 0
+}
+
+func (d *VolumePartitionDescriptor) PathTableSize() uint32 {
+	return 0
 }
 
 func (d *VolumePartitionDescriptor) HasJoliet() bool {
-	//TODO implement me
-	panic("implement me")
+	return false
 }
 
 func (d *VolumePartitionDescriptor) HasRockRidge() bool {
-	//TODO implement me
-	panic("implement me")
+	return false
 }
 
 func (d *VolumePartitionDescriptor) RootDirectory() *directory.DirectoryRecord {
-	//TODO implement me
-	panic("implement me")
+	return nil
 }
 
 func (d *VolumePartitionDescriptor) GetObjects() []info.ImageObject {
@@ -162,10 +200,90 @@ func (v VolumePartitionDescriptorBody) Size() int {
 	return int(v.ObjectSize)
 }
 
+// Marshal converts the VolumePartitionDescriptor into its 2048-byte on-disk representation.
 func (d *VolumePartitionDescriptor) Marshal() ([]byte, error) {
-	return []byte{}, nil
+	var sector [consts.ISO9660_SECTOR_SIZE]byte
+
+	// 1. Header: 7 bytes.
+	headerBytes, err := d.VolumeDescriptorHeader.Marshal()
+	if err != nil {
+		return sector[:], fmt.Errorf("failed to marshal header: %w", err)
+	}
+	copy(sector[0:7], headerBytes[:])
+	offset := 7
+
+	// 2. unusedField1: 1 byte.
+	sector[offset] = d.UnusedField1
+	offset++
+
+	// 3. systemIdentifier: 32 bytes (padded with ' ').
+	if len(d.VolumePartitionDescriptorBody.SystemIdentifier) > 32 {
+		return sector[:], fmt.Errorf("systemIdentifier exceeds 32 bytes")
+	}
+	copy(sector[offset:offset+32], helpers.PadString(d.VolumePartitionDescriptorBody.SystemIdentifier, 32))
+	offset += 32
+
+	// 4. volumePartitionIdentifier: 32 bytes (padded with ' ').
+	if len(d.VolumePartitionIdentifier) > 32 {
+		return sector[:], fmt.Errorf("volumePartitionIdentifier exceeds 32 bytes")
+	}
+	copy(sector[offset:offset+32], helpers.PadString(d.VolumePartitionIdentifier, 32))
+	offset += 32
+
+	// 5. volumePartitionLocation: 8 bytes (both-byte orders for uint32).
+	locationBytes := encoding.MarshalBothByteOrders32(d.VolumePartitionLocation)
+	copy(sector[offset:offset+8], locationBytes[:])
+	offset += 8
+
+	// 6. volumePartitionSize: 8 bytes (both-byte orders for uint32).
+	sizeBytes := encoding.MarshalBothByteOrders32(d.VolumePartitionSize)
+	copy(sector[offset:offset+8], sizeBytes[:])
+	offset += 8
+
+	// 7. systemUse: the remainder of the sector.
+	copy(sector[offset:], d.SystemUse[:])
+
+	return sector[:], nil
 }
 
+// Unmarshal parses a 2048-byte sector into the VolumePartitionDescriptor.
 func (d *VolumePartitionDescriptor) Unmarshal(data [consts.ISO9660_SECTOR_SIZE]byte) error {
+	// 1. Header: 7 bytes.
+	if err := d.VolumeDescriptorHeader.Unmarshal([7]byte(data[0:7])); err != nil {
+		return fmt.Errorf("failed to unmarshal header: %w", err)
+	}
+	offset := 7
+
+	// 2. unusedField1: 1 byte.
+	d.UnusedField1 = data[offset]
+	offset++
+
+	// 3. systemIdentifier: 32 bytes.
+	d.VolumePartitionDescriptorBody.SystemIdentifier = strings.TrimRight(string(data[offset:offset+32]), " ")
+	offset += 32
+
+	// 4. volumePartitionIdentifier: 32 bytes.
+	d.VolumePartitionIdentifier = strings.TrimRight(string(data[offset:offset+32]), " ")
+	offset += 32
+
+	// 5. volumePartitionLocation: 8 bytes (both-byte orders for uint32).
+	location, err := encoding.UnmarshalUint32LSBMSB([8]byte(data[offset : offset+8]))
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal volumePartitionLocation: %w", err)
+	}
+	d.VolumePartitionLocation = location
+	offset += 8
+
+	// 6. volumePartitionSize: 8 bytes (both-byte orders for uint32).
+	size, err := encoding.UnmarshalUint32LSBMSB([8]byte(data[offset : offset+8]))
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal volumePartitionSize: %w", err)
+	}
+	d.VolumePartitionSize = size
+	offset += 8
+
+	// 7. systemUse: the remainder of the sector.
+	copy(d.SystemUse[:], data[offset:])
+
 	return nil
 }
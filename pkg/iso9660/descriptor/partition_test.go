@@ -0,0 +1,40 @@
+package descriptor
+
+import (
+	"github.com/rstms/iso-kit/pkg/consts"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestVolumePartitionDescriptor_MarshalUnmarshal(t *testing.T) {
+	vpd, err := NewVolumePartitionDescriptor("SYSTEM", "PARTITION_1", 1000, 64, []byte("system use"))
+	require.NoError(t, err)
+
+	data, err := vpd.Marshal()
+	require.NoError(t, err)
+	require.Len(t, data, consts.ISO9660_SECTOR_SIZE)
+	require.Equal(t, byte(TYPE_PARTITION_DESCRIPTOR), data[0])
+
+	var got VolumePartitionDescriptor
+	require.NoError(t, got.Unmarshal([consts.ISO9660_SECTOR_SIZE]byte(data)))
+	require.Equal(t, TYPE_PARTITION_DESCRIPTOR, got.VolumeDescriptorType)
+	require.Equal(t, "SYSTEM", got.SystemIdentifier())
+	require.Equal(t, "PARTITION_1", got.VolumeIdentifier())
+	require.Equal(t, uint32(1000), got.VolumePartitionLocation)
+	require.Equal(t, uint32(64), got.VolumePartitionSize)
+	require.Equal(t, vpd.SystemUse, got.SystemUse)
+
+	// Accessors that do not apply to a partition descriptor return zero values instead of panicking
+	var d VolumeDescriptor = &got
+	require.Empty(t, d.VolumeSetIdentifier())
+	require.True(t, d.VolumeCreationDateTime().IsZero())
+	require.Nil(t, d.RootDirectory())
+}
+
+func TestNewVolumePartitionDescriptor_Invalid(t *testing.T) {
+	_, err := NewVolumePartitionDescriptor("SYSTEM", "lower case", 0, 0, nil)
+	require.Error(t, err)
+
+	_, err = NewVolumePartitionDescriptor("SYSTEM", "PARTITION", 0, 0, make([]byte, PARTITION_SYSTEM_USE_SIZE+1))
+	require.Error(t, err)
+}
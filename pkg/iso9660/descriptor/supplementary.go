@@ -90,8 +90,14 @@ func (d *SupplementaryVolumeDescriptor) VolumeEffectiveDateTime() time.Time {
 }
 
 func (d *SupplementaryVolumeDescriptor) HasJoliet() bool {
-	// TODO: Should actually detect the Escape Sequences field to determine if Joliet is present.
-	return true
+	return d.IsJoliet()
+}
+
+// IsEnhanced returns true if this is an ISO 9660:1999 Enhanced Volume Descriptor, a supplementary volume descriptor
+// recorded with volume descriptor and file structure version 2.
+func (d *SupplementaryVolumeDescriptor) IsEnhanced() bool {
+	return d.VolumeDescriptorVersion == consts.ISO9660_ENHANCED_VOLUME_DESC_VERSION &&
+		d.FileStructureVersion == consts.ISO9660_ENHANCED_FILE_STRUCTURE_VERSION
 }
 
 func (d *SupplementaryVolumeDescriptor) HasRockRidge() bool {
@@ -334,18 +340,18 @@ func (svdb *SupplementaryVolumeDescriptorBody) Marshal() ([]byte, error) {
 	data[offset] = svdb.VolumeFlags
 	offset++
 
-	// 2. systemIdentifier: 32 bytes (UCS2).
-	sysID := encoding.EncodeUCS2BigEndian(svdb.SystemIdentifier)
+	// 2. systemIdentifier: 32 bytes (UCS2 for Joliet).
+	sysID := svdb.encodeIdentifier(svdb.SystemIdentifier, 32)
 	if len(sysID) > 32 {
-		return data[:], fmt.Errorf("systemIdentifier (%d bytes) exceeds 32 bytes after UCS2 encoding", len(sysID))
+		return data[:], fmt.Errorf("systemIdentifier (%d bytes) exceeds 32 bytes after encoding", len(sysID))
 	}
 	copy(data[offset:offset+len(sysID)], sysID)
 	offset += 32
 
-	// 3. volumeIdentifier: 32 bytes (UCS2).
-	volID := encoding.EncodeUCS2BigEndian(svdb.VolumeIdentifier)
+	// 3. volumeIdentifier: 32 bytes (UCS2 for Joliet).
+	volID := svdb.encodeIdentifier(svdb.VolumeIdentifier, 32)
 	if len(volID) > 32 {
-		return data[:], fmt.Errorf("volumeIdentifier (%d bytes) exceeds 32 bytes after UCS2 encoding", len(volID))
+		return data[:], fmt.Errorf("volumeIdentifier (%d bytes) exceeds 32 bytes after encoding", len(volID))
 	}
 	copy(data[offset:offset+len(volID)], volID)
 	offset += 32
@@ -409,58 +415,58 @@ func (svdb *SupplementaryVolumeDescriptorBody) Marshal() ([]byte, error) {
 	copy(data[offset:offset+34], rdBytes)
 	offset += 34
 
-	// 16. volumeSetIdentifier: 128 bytes (UCS2).
-	vsi := encoding.EncodeUCS2BigEndian(svdb.VolumeSetIdentifier)
+	// 16. volumeSetIdentifier: 128 bytes (UCS2 for Joliet).
+	vsi := svdb.encodeIdentifier(svdb.VolumeSetIdentifier, 128)
 	if len(vsi) > 128 {
-		return data[:], fmt.Errorf("volumeSetIdentifier exceeds 128 bytes after UCS2 encoding")
+		return data[:], fmt.Errorf("volumeSetIdentifier exceeds 128 bytes after encoding")
 	}
 	copy(data[offset:offset+len(vsi)], vsi)
 	offset += 128
 
-	// 17. publisherIdentifier: 128 bytes (UCS2).
-	pubID := encoding.EncodeUCS2BigEndian(svdb.PublisherIdentifier)
+	// 17. publisherIdentifier: 128 bytes (UCS2 for Joliet).
+	pubID := svdb.encodeIdentifier(svdb.PublisherIdentifier, 128)
 	if len(pubID) > 128 {
-		return data[:], fmt.Errorf("publisherIdentifier exceeds 128 bytes after UCS2 encoding")
+		return data[:], fmt.Errorf("publisherIdentifier exceeds 128 bytes after encoding")
 	}
 	copy(data[offset:offset+len(pubID)], pubID)
 	offset += 128
 
-	// 18. dataPreparerIdentifier: 128 bytes (UCS2).
-	dpID := encoding.EncodeUCS2BigEndian(svdb.DataPreparerIdentifier)
+	// 18. dataPreparerIdentifier: 128 bytes (UCS2 for Joliet).
+	dpID := svdb.encodeIdentifier(svdb.DataPreparerIdentifier, 128)
 	if len(dpID) > 128 {
-		return data[:], fmt.Errorf("dataPreparerIdentifier exceeds 128 bytes after UCS2 encoding")
+		return data[:], fmt.Errorf("dataPreparerIdentifier exceeds 128 bytes after encoding")
 	}
 	copy(data[offset:offset+len(dpID)], dpID)
 	offset += 128
 
-	// 19. applicationIdentifier: 128 bytes (UCS2).
-	appID := encoding.EncodeUCS2BigEndian(svdb.ApplicationIdentifier)
+	// 19. applicationIdentifier: 128 bytes (UCS2 for Joliet).
+	appID := svdb.encodeIdentifier(svdb.ApplicationIdentifier, 128)
 	if len(appID) > 128 {
-		return data[:], fmt.Errorf("applicationIdentifier exceeds 128 bytes after UCS2 encoding")
+		return data[:], fmt.Errorf("applicationIdentifier exceeds 128 bytes after encoding")
 	}
 	copy(data[offset:offset+len(appID)], appID)
 	offset += 128
 
-	// 20. copyrightFileIdentifier: 37 bytes (UCS2).
-	cfID := encoding.EncodeUCS2BigEndian(svdb.CopyrightFileIdentifier)
+	// 20. copyrightFileIdentifier: 37 bytes (UCS2 for Joliet).
+	cfID := svdb.encodeIdentifier(svdb.CopyrightFileIdentifier, 37)
 	if len(cfID) > 37 {
-		return data[:], fmt.Errorf("copyrightFileIdentifier exceeds 37 bytes after UCS2 encoding")
+		return data[:], fmt.Errorf("copyrightFileIdentifier exceeds 37 bytes after encoding")
 	}
 	copy(data[offset:offset+len(cfID)], cfID)
 	offset += 37
 
-	// 21. abstractFileIdentifier: 37 bytes (UCS2).
-	afID := encoding.EncodeUCS2BigEndian(svdb.AbstractFileIdentifier)
+	// 21. abstractFileIdentifier: 37 bytes (UCS2 for Joliet).
+	afID := svdb.encodeIdentifier(svdb.AbstractFileIdentifier, 37)
 	if len(afID) > 37 {
-		return data[:], fmt.Errorf("abstractFileIdentifier exceeds 37 bytes after UCS2 encoding")
+		return data[:], fmt.Errorf("abstractFileIdentifier exceeds 37 bytes after encoding")
 	}
 	copy(data[offset:offset+len(afID)], afID)
 	offset += 37
 
-	// 22. bibliographicFileIdentifier: 37 bytes (UCS2).
-	bfID := encoding.EncodeUCS2BigEndian(svdb.BibliographicFileIdentifier)
+	// 22. bibliographicFileIdentifier: 37 bytes (UCS2 for Joliet).
+	bfID := svdb.encodeIdentifier(svdb.BibliographicFileIdentifier, 37)
 	if len(bfID) > 37 {
-		return data[:], fmt.Errorf("bibliographicFileIdentifier exceeds 37 bytes after UCS2 encoding")
+		return data[:], fmt.Errorf("bibliographicFileIdentifier exceeds 37 bytes after encoding")
 	}
 	copy(data[offset:offset+len(bfID)], bfID)
 	offset += 37
@@ -528,7 +534,7 @@ func (svdb *SupplementaryVolumeDescriptorBody) Unmarshal(data []byte) error {
 	}
 
 	// Handle Joliet early to determine UCS-2 encoding.
-	copy(svdb.EscapeSequences[:], data[81:112])
+	copy(svdb.EscapeSequences[:], data[81:113])
 
 	offset := 0
 
@@ -731,6 +737,18 @@ func (svdb *SupplementaryVolumeDescriptorBody) Unmarshal(data []byte) error {
 	return nil
 }
 
+// encodeIdentifier encodes a descriptor identifier field as UCS-2 for Joliet descriptors, otherwise the bytes are
+// recorded as is and padded with spaces to the size of the field.
+func (svdb *SupplementaryVolumeDescriptorBody) encodeIdentifier(value string, size int) []byte {
+	if svdb.IsJoliet() {
+		return encoding.EncodeUCS2BigEndian(value)
+	}
+	if len(value) >= size {
+		return []byte(value)
+	}
+	return []byte(value + strings.Repeat(" ", size-len(value)))
+}
+
 // Check if the SVD is Joliet by inspecting the Escape Sequences
 func (svdb *SupplementaryVolumeDescriptorBody) IsJoliet() bool {
 	return string(svdb.EscapeSequences[:3]) == consts.JOLIET_LEVEL_1_ESCAPE ||
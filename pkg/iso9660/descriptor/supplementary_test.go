@@ -0,0 +1,56 @@
+package descriptor
+
+import (
+	"github.com/rstms/iso-kit/pkg/consts"
+	"github.com/rstms/iso-kit/pkg/iso9660/directory"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSupplementaryVolumeDescriptor_Enhanced(t *testing.T) {
+	newSVD := func(version uint8, escape string) *SupplementaryVolumeDescriptor {
+		svd := &SupplementaryVolumeDescriptor{
+			VolumeDescriptorHeader: VolumeDescriptorHeader{
+				VolumeDescriptorType:    TYPE_SUPPLEMENTARY_DESCRIPTOR,
+				StandardIdentifier:      consts.ISO9660_STD_IDENTIFIER,
+				VolumeDescriptorVersion: version,
+			},
+			SupplementaryVolumeDescriptorBody: SupplementaryVolumeDescriptorBody{
+				VolumeIdentifier: "Volume Name",
+				RootDirectoryRecord: &directory.DirectoryRecord{
+					FileIdentifier:       "\x00",
+					FileFlags:            directory.FileFlags{Directory: true},
+					RecordingDateAndTime: time.Now(),
+				},
+				FileStructureVersion: version,
+			},
+		}
+		copy(svd.EscapeSequences[:], escape)
+		return svd
+	}
+
+	tests := []struct {
+		name     string
+		svd      *SupplementaryVolumeDescriptor
+		joliet   bool
+		enhanced bool
+	}{
+		{"joliet", newSVD(consts.ISO9660_VOLUME_DESC_VERSION, consts.JOLIET_LEVEL_3_ESCAPE), true, false},
+		{"enhanced", newSVD(consts.ISO9660_ENHANCED_VOLUME_DESC_VERSION, ""), false, true},
+		{"plain", newSVD(consts.ISO9660_VOLUME_DESC_VERSION, ""), false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.svd.Marshal()
+			require.NoError(t, err)
+
+			var svd SupplementaryVolumeDescriptor
+			require.NoError(t, svd.Unmarshal([consts.ISO9660_SECTOR_SIZE]byte(data)))
+			require.Equal(t, tt.joliet, svd.HasJoliet())
+			require.Equal(t, tt.enhanced, svd.IsEnhanced())
+			require.Equal(t, "Volume Name", svd.VolumeIdentifier())
+		})
+	}
+}
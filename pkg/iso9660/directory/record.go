@@ -6,6 +6,7 @@ import (
 	"github.com/rstms/iso-kit/pkg/iso9660/extensions"
 	"github.com/rstms/iso-kit/pkg/iso9660/extent"
 	"github.com/rstms/iso-kit/pkg/iso9660/info"
+	"github.com/rstms/iso-kit/pkg/iso9660/xattr"
 	"github.com/rstms/iso-kit/pkg/logging"
 	"os"
 	"time"
@@ -83,9 +84,13 @@ type DirectoryRecord struct {
 	SystemUse []byte `json:"system_use"`
 	// RockRidge is a field to store Rock Ridge extensions if they exist
 	RockRidge *extensions.RockRidgeExtensions `json:"rock_ridge"`
+	// XA is the CD-ROM XA record at the start of the system use field, if present
+	XA *extensions.XARecord `json:"xa,omitempty"`
 	// Joliet is a field to store if this record is from a volume with Joliet extensions
 	Joliet bool `json:"joliet"`
 	// --- Fields that are not part of the ISO9660 object ---
+	// ExtendedAttributeRecord is the decoded Extended Attribute Record when ExtendedAttributeRecordLength is non-zero
+	ExtendedAttributeRecord *xattr.ExtendedAttributeRecord `json:"extended_attribute_record,omitempty"`
 	// File Extent
 	FileExtent *extent.FileExtent
 	// Object Location (in bytes)
@@ -127,12 +132,31 @@ func (dr *DirectoryRecord) Size() int {
 
 func (dr *DirectoryRecord) GetObjects() []info.ImageObject {
 	objects := []info.ImageObject{dr}
+	if dr.ExtendedAttributeRecord != nil && !dr.IsSpecial() {
+		objects = append(objects, dr.ExtendedAttributeRecord)
+	}
 	if !dr.IsDirectory() && dr.FileExtent != nil {
 		objects = append(objects, dr.FileExtent.GetObjects()...)
 	}
 	return objects
 }
 
+// DataLocation returns the Logical Block Number at which the data of the extent starts, this is after the Extended
+// Attribute Record if one is recorded.
+func (dr *DirectoryRecord) DataLocation() uint32 {
+	return dr.LocationOfExtent + uint32(dr.ExtendedAttributeRecordLength)
+}
+
+// IsInterleaved checks if the file section is recorded in interleaved mode
+func (dr *DirectoryRecord) IsInterleaved() bool {
+	return dr.FileUnitSize > 0
+}
+
+// hasRockRidge checks if Rock Ridge attributes should be used for the record
+func (dr *DirectoryRecord) hasRockRidge(RockRidgeEnabled bool) bool {
+	return RockRidgeEnabled && dr.RockRidge != nil && dr.RockRidge.HasRockRidge()
+}
+
 // IsDirectory checks if the entry is a Directory
 func (dr *DirectoryRecord) IsDirectory() bool {
 	if dr.RockRidge != nil && dr.RockRidge.HasRockRidge() && dr.RockRidge.Permissions != nil {
@@ -168,6 +192,13 @@ func (dr *DirectoryRecord) GetPermissions(RockRidgeEnabled bool) os.FileMode {
 	if RockRidgeEnabled && dr.RockRidge != nil && dr.RockRidge.Permissions != nil {
 		return os.FileMode(*dr.RockRidge.Permissions)
 	}
+	if dr.ExtendedAttributeRecord != nil && !dr.hasRockRidge(RockRidgeEnabled) {
+		mode := dr.ExtendedAttributeRecord.Permissions.FileMode()
+		if dr.IsDirectory() {
+			mode |= os.ModeDir
+		}
+		return mode
+	}
 	if dr.IsDirectory() {
 		return os.FileMode(0o755) // Default for directories
 	}
@@ -176,6 +207,11 @@ func (dr *DirectoryRecord) GetPermissions(RockRidgeEnabled bool) os.FileMode {
 
 // GetOwnership retrieves UID & GID
 func (dr *DirectoryRecord) GetOwnership(RockRidgeEnabled bool) (uid, gid *uint32) {
+	if dr.ExtendedAttributeRecord != nil && !dr.hasRockRidge(RockRidgeEnabled) {
+		owner := uint32(dr.ExtendedAttributeRecord.OwnerIdentification)
+		group := uint32(dr.ExtendedAttributeRecord.GroupIdentification)
+		return &owner, &group
+	}
 	if RockRidgeEnabled && dr.RockRidge != nil {
 		return dr.RockRidge.UID, dr.RockRidge.GID
 	}
@@ -184,7 +220,10 @@ func (dr *DirectoryRecord) GetOwnership(RockRidgeEnabled bool) (uid, gid *uint32
 
 // GetTimestamps retrieves creation & modification time
 func (dr *DirectoryRecord) GetTimestamps(RockRidgeEnabled bool) (creation, modification time.Time) {
-	if RockRidgeEnabled && dr.RockRidge != nil {
+	if dr.hasRockRidge(RockRidgeEnabled) {
+		// The TF entry does not need to record every timestamp, fall back to the recording date for missing ones
+		creation = dr.RecordingDateAndTime
+		modification = dr.RecordingDateAndTime
 		if dr.RockRidge.CreationTime != nil {
 			creation = *dr.RockRidge.CreationTime
 		}
@@ -194,10 +233,31 @@ func (dr *DirectoryRecord) GetTimestamps(RockRidgeEnabled bool) (creation, modif
 	} else {
 		creation = dr.RecordingDateAndTime
 		modification = dr.RecordingDateAndTime
+		if ear := dr.ExtendedAttributeRecord; ear != nil {
+			if !ear.FileCreationDateAndTime.IsZero() {
+				creation = ear.FileCreationDateAndTime
+			}
+			if !ear.FileModificationDateAndTime.IsZero() {
+				modification = ear.FileModificationDateAndTime
+			}
+		}
 	}
 	return creation, modification
 }
 
+// GetAccessTime retrieves the Rock Ridge access time, zero if none was recorded
+func (dr *DirectoryRecord) GetAccessTime(RockRidgeEnabled bool) time.Time {
+	if dr.hasRockRidge(RockRidgeEnabled) && dr.RockRidge.AccessTime != nil {
+		return *dr.RockRidge.AccessTime
+	}
+	return time.Time{}
+}
+
+// Len returns the recorded length of the record including the padding byte and the system use entries.
+func (dr *DirectoryRecord) Len() int {
+	return 33 + len(dr.FileIdentifier) + 1 - len(dr.FileIdentifier)%2 + len(dr.SystemUse)
+}
+
 // Marshal converts the DirectoryRecord into its on‑disk byte representation.
 // It computes and sets the LengthOfDirectoryRecord field and handles the optional
 // padding byte for the File Identifier.
@@ -256,6 +316,9 @@ func (dr *DirectoryRecord) Marshal() ([]byte, error) {
 	buf = append(buf, dr.SystemUse...)
 
 	// Now that we know the total length, set the LengthOfDirectoryRecord.
+	if len(buf) > 255 {
+		return nil, fmt.Errorf("record length %d exceeds 255 bytes", len(buf))
+	}
 	recordLength := uint8(len(buf))
 	if recordLength == 0 {
 		return nil, fmt.Errorf("record length is zero")
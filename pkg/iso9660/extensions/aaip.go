@@ -0,0 +1,273 @@
+package extensions
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// AAIP (Arbitrary Attribute Interchange Protocol) is the libisofs scheme for storing POSIX ACLs and extended
+// attributes in "AL" SUSP entries. The payload of consecutive AL entries forms a stream of component records
+// (a flags byte, a length byte and up to 255 bytes of content) which encode alternating names and values. A name or
+// value continues into the next component record while bit 0 of the component flags is set.
+//
+// AL entry layout:
+//
+//	BP 1 - 2: Signature "AL"
+//	BP 3:     Length
+//	BP 4:     Version (1)
+//	BP 5:     Flags (bit 0: the attribute list continues in the next AL entry)
+//	BP 6 - n: Component records
+const (
+	AAIP_VERSION = 1
+
+	// AAIP_IDENTIFIER, AAIP_DESCRIPTOR and AAIP_SOURCE are recorded in the ER entry announcing AL entries
+	AAIP_IDENTIFIER = "AAIP_0200"
+	AAIP_DESCRIPTOR = "AL PROVIDES VIA AAIP 2.0 SUPPORT FOR ARBITRARY FILE ATTRIBUTES IN ISO 9660 IMAGES"
+	AAIP_SOURCE     = "PLEASE CONTACT THE PUBLISHER OF THIS MEDIUM."
+
+	// aaipMaxEntryPayload is the number of component record bytes which fit into one AL entry
+	aaipMaxEntryPayload = 255 - 5
+	// aaipMaxComponent is the maximum amount of content in one component record
+	aaipMaxComponent = 255
+)
+
+// aaipNamespaces maps the namespace code that may start an attribute name to its textual prefix. Code 0x01 escapes
+// names which literally begin with a byte below 0x20.
+var aaipNamespaces = map[byte]string{
+	0x02: "system.",
+	0x03: "user.",
+	0x04: "isofs.",
+	0x05: "trusted.",
+	0x06: "security.",
+}
+
+// ExtendedAttribute is a single name/value pair stored in an AAIP "AL" entry, e.g. "security.selinux".
+type ExtendedAttribute struct {
+	Name  string `json:"name"`
+	Value []byte `json:"value"`
+}
+
+// ACLTag identifies the kind of an ACL entry, the values are the AAIP entry types.
+type ACLTag uint8
+
+const (
+	ACL_USER_OBJ  ACLTag = 1
+	ACL_USER      ACLTag = 2
+	ACL_GROUP_OBJ ACLTag = 3
+	ACL_GROUP     ACLTag = 4
+	ACL_MASK      ACLTag = 5
+	ACL_OTHER     ACLTag = 6
+	// ACL_USER_N and ACL_GROUP_N are named entries whose qualifier is a numeric id rather than a name
+	ACL_USER_N  ACLTag = 10
+	ACL_GROUP_N ACLTag = 12
+
+	aclTranslate     = 0
+	aclSwitchMark    = 8
+	aclFutureVersion = 15
+)
+
+// ACLEntry is a single POSIX ACL entry. Perm holds the read (4), write (2) and execute (1) bits.
+type ACLEntry struct {
+	Tag  ACLTag `json:"tag"`
+	Perm uint8  `json:"perm"`
+	// ID is the uid or gid of ACL_USER_N and ACL_GROUP_N entries
+	ID uint32 `json:"id,omitempty"`
+	// Name is the user or group name of ACL_USER and ACL_GROUP entries
+	Name string `json:"name,omitempty"`
+}
+
+// ACL holds the access ACL of a file and, for directories, the default ACL inherited by new children.
+type ACL struct {
+	Access  []ACLEntry `json:"access,omitempty"`
+	Default []ACLEntry `json:"default,omitempty"`
+}
+
+// unmarshalAAIP decodes the concatenated component records of a file's AL entries. An attribute with an empty name
+// holds the file's ACL.
+func unmarshalAAIP(data []byte) ([]ExtendedAttribute, *ACL, error) {
+	var attrs []ExtendedAttribute
+	var acl *ACL
+
+	for len(data) > 0 {
+		name, rest, err := readAAIPComponents(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read AL attribute name: %w", err)
+		}
+		value, rest, err := readAAIPComponents(rest)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read AL attribute value: %w", err)
+		}
+		data = rest
+
+		if len(name) == 0 {
+			if acl, err = unmarshalAAIPACL(value); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		attrs = append(attrs, ExtendedAttribute{Name: decodeAAIPName(name), Value: value})
+	}
+
+	return attrs, acl, nil
+}
+
+// readAAIPComponents reads one name or value from a sequence of component records.
+func readAAIPComponents(data []byte) ([]byte, []byte, error) {
+	var out []byte
+	for {
+		if len(data) < 2 {
+			return nil, nil, errors.New("truncated component record")
+		}
+		flags, length := data[0], int(data[1])
+		if len(data) < 2+length {
+			return nil, nil, errors.New("component record exceeds AL data")
+		}
+		out = append(out, data[2:2+length]...)
+		data = data[2+length:]
+		if flags&0x01 == 0 {
+			return out, data, nil
+		}
+	}
+}
+
+func decodeAAIPName(name []byte) string {
+	if prefix, ok := aaipNamespaces[name[0]]; ok {
+		return prefix + string(name[1:])
+	}
+	if name[0] == 0x01 {
+		return string(name[1:])
+	}
+	return string(name)
+}
+
+func encodeAAIPName(name string) []byte {
+	for code, prefix := range aaipNamespaces {
+		if len(name) > len(prefix) && name[:len(prefix)] == prefix {
+			return append([]byte{code}, name[len(prefix):]...)
+		}
+	}
+	if len(name) > 0 && name[0] < 0x20 {
+		return append([]byte{0x01}, name...)
+	}
+	return []byte(name)
+}
+
+// unmarshalAAIPACL decodes an AAIP ACL value. Each entry is a byte with the entry type in the upper and the
+// permissions in the lower four bits. Named entries are followed by their qualifier as component records, a big-endian
+// number for the _N types and a name otherwise. A switch mark separates the access ACL from the default ACL.
+func unmarshalAAIPACL(data []byte) (*ACL, error) {
+	acl := &ACL{}
+	target := &acl.Access
+
+	for len(data) > 0 {
+		tag, perm := data[0]>>4, data[0]&0x07
+		data = data[1:]
+
+		switch tag {
+		case aclSwitchMark:
+			target = &acl.Default
+			continue
+		case aclFutureVersion:
+			return nil, errors.New("unsupported AAIP ACL version")
+		}
+
+		entry := ACLEntry{Tag: ACLTag(tag), Perm: perm}
+		switch ACLTag(tag) {
+		case ACL_USER, ACL_GROUP, ACL_USER_N, ACL_GROUP_N, aclTranslate:
+			qualifier, rest, err := readAAIPComponents(data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read ACL qualifier: %w", err)
+			}
+			data = rest
+			if ACLTag(tag) == ACL_USER_N || ACLTag(tag) == ACL_GROUP_N {
+				for _, b := range qualifier {
+					entry.ID = entry.ID<<8 | uint32(b)
+				}
+			} else {
+				entry.Name = string(qualifier)
+			}
+		}
+
+		// Translation entries only map names to ids for the benefit of the reader
+		if tag == aclTranslate {
+			continue
+		}
+		*target = append(*target, entry)
+	}
+
+	return acl, nil
+}
+
+func marshalAAIPACL(acl *ACL) []byte {
+	var buf bytes.Buffer
+	writeEntries := func(entries []ACLEntry) {
+		for _, entry := range entries {
+			buf.WriteByte(byte(entry.Tag)<<4 | entry.Perm&0x07)
+			switch entry.Tag {
+			case ACL_USER_N, ACL_GROUP_N:
+				var id [4]byte
+				binary.BigEndian.PutUint32(id[:], entry.ID)
+				writeAAIPComponents(&buf, bytes.TrimLeft(id[:], "\x00"))
+			case ACL_USER, ACL_GROUP:
+				writeAAIPComponents(&buf, []byte(entry.Name))
+			}
+		}
+	}
+
+	writeEntries(acl.Access)
+	if len(acl.Default) > 0 {
+		buf.WriteByte(aclSwitchMark << 4)
+		writeEntries(acl.Default)
+	}
+	return buf.Bytes()
+}
+
+// writeAAIPComponents writes data as a sequence of component records.
+func writeAAIPComponents(buf *bytes.Buffer, data []byte) {
+	for {
+		n := min(len(data), aaipMaxComponent)
+		var flags byte
+		if n < len(data) {
+			flags = 0x01
+		}
+		buf.WriteByte(flags)
+		buf.WriteByte(byte(n))
+		buf.Write(data[:n])
+		data = data[n:]
+		if flags == 0 {
+			return
+		}
+	}
+}
+
+// marshalAAIP encodes the attributes and ACL as a sequence of AL entries.
+func marshalAAIP(attrs []ExtendedAttribute, acl *ACL) []byte {
+	var stream bytes.Buffer
+	if acl != nil && (len(acl.Access) > 0 || len(acl.Default) > 0) {
+		writeAAIPComponents(&stream, nil)
+		writeAAIPComponents(&stream, marshalAAIPACL(acl))
+	}
+	for _, attr := range attrs {
+		writeAAIPComponents(&stream, encodeAAIPName(attr.Name))
+		writeAAIPComponents(&stream, attr.Value)
+	}
+
+	var buf bytes.Buffer
+	data := stream.Bytes()
+	for len(data) > 0 {
+		n := min(len(data), aaipMaxEntryPayload)
+		var flags byte
+		if n < len(data) {
+			flags = 0x01
+		}
+		buf.Write([]byte(AAIP_ATTRIBUTES)) // Signature
+		buf.WriteByte(byte(n + 5))
+		buf.WriteByte(AAIP_VERSION)
+		buf.WriteByte(flags)
+		buf.Write(data[:n])
+		data = data[n:]
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,51 @@
+package extensions
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAAIPRoundTrip(t *testing.T) {
+	rr := &RockRidgeExtensions{
+		ExtendedAttributes: []ExtendedAttribute{
+			{Name: "security.selinux", Value: []byte("system_u:object_r:bin_t:s0\x00")},
+			{Name: "user.comment", Value: bytes.Repeat([]byte("x"), 600)},
+			{Name: "custom", Value: []byte("plain")},
+		},
+		ACL: &ACL{
+			Access: []ACLEntry{
+				{Tag: ACL_USER_OBJ, Perm: 6},
+				{Tag: ACL_USER_N, Perm: 4, ID: 1000},
+				{Tag: ACL_GROUP_OBJ, Perm: 4},
+				{Tag: ACL_MASK, Perm: 4},
+				{Tag: ACL_OTHER, Perm: 0},
+			},
+			Default: []ACLEntry{
+				{Tag: ACL_GROUP_N, Perm: 7, ID: 70000},
+			},
+		},
+	}
+
+	data, err := MarshalRockRidge(rr)
+	require.NoError(t, err)
+	require.Equal(t, "AL", string(data[0:2]))
+
+	got, err := UnmarshalRockRidge(data)
+	require.NoError(t, err)
+	require.Equal(t, rr.ExtendedAttributes, got.ExtendedAttributes)
+	require.Equal(t, rr.ACL, got.ACL)
+}
+
+func TestAAIPNamespaces(t *testing.T) {
+	// "user." is stored as namespace code 0x03 followed by the remainder of the name
+	stream := []byte{0, 4, 0x03, 'a', 'b', 'c', 0, 1, 'v'}
+	attrs, acl, err := unmarshalAAIP(stream)
+	require.NoError(t, err)
+	require.Nil(t, acl)
+	require.Equal(t, []ExtendedAttribute{{Name: "user.abc", Value: []byte("v")}}, attrs)
+
+	_, _, err = unmarshalAAIP([]byte{0, 4, 0x03})
+	require.Error(t, err)
+}
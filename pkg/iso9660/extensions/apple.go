@@ -0,0 +1,105 @@
+package extensions
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Apple ISO 9660 extensions record Macintosh file information in an "AA" (or the later SUSP compatible "BA") system
+// use entry. The byte following the length holds the system use id instead of an entry version and selects the
+// layout of the remaining bytes.
+//
+// HFS layout (system use id 2), 14 bytes:
+//
+//	BP 1 - 2:   Signature "AA" or "BA"
+//	BP 3:       Length
+//	BP 4:       System use id
+//	BP 5 - 8:   File type, e.g. "TEXT"
+//	BP 9 - 12:  File creator, e.g. "ttxt"
+//	BP 13 - 14: Finder flags (big-endian)
+//
+// ProDOS layout (system use id 1), 7 bytes:
+//
+//	BP 5:     ProDOS file type
+//	BP 6 - 7: ProDOS auxiliary type (little-endian)
+const (
+	APPLE_EXTENSION    RockRidgeEntryType = "AA"
+	APPLE_EXTENSION_BA RockRidgeEntryType = "BA"
+
+	APPLE_PRODOS = 0x01
+	APPLE_HFS    = 0x02
+)
+
+// AppleExtension holds the Macintosh file information of an "AA" or "BA" entry.
+type AppleExtension struct {
+	// SystemUseID selects the ProDOS (1) or HFS (2) layout
+	SystemUseID uint8 `json:"systemUseId"`
+	// Type is the four character HFS file type
+	Type string `json:"type,omitempty"`
+	// Creator is the four character HFS creator code
+	Creator string `json:"creator,omitempty"`
+	// FinderFlags are the HFS Finder flags, e.g. 0x4000 for invisible files
+	FinderFlags uint16 `json:"finderFlags,omitempty"`
+	// ProDOSType is the ProDOS file type
+	ProDOSType uint8 `json:"prodosType,omitempty"`
+	// ProDOSAuxType is the ProDOS auxiliary type, usually the load address
+	ProDOSAuxType uint16 `json:"prodosAuxType,omitempty"`
+}
+
+// unmarshalApple decodes the payload of an "AA" or "BA" entry.
+func unmarshalApple(id uint8, payload []byte) *AppleExtension {
+	apple := &AppleExtension{SystemUseID: id}
+	switch {
+	case id == APPLE_PRODOS && len(payload) >= 3:
+		apple.ProDOSType = payload[0]
+		apple.ProDOSAuxType = binary.LittleEndian.Uint16(payload[1:3])
+	case len(payload) >= 10:
+		apple.Type = string(payload[0:4])
+		apple.Creator = string(payload[4:8])
+		apple.FinderFlags = binary.BigEndian.Uint16(payload[8:10])
+	}
+	return apple
+}
+
+// marshalApple encodes an "AA" entry.
+func marshalApple(apple *AppleExtension) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte(APPLE_EXTENSION)) // Signature
+	if apple.SystemUseID == APPLE_PRODOS {
+		buf.WriteByte(7)
+		buf.WriteByte(APPLE_PRODOS)
+		buf.WriteByte(apple.ProDOSType)
+		binary.Write(&buf, binary.LittleEndian, apple.ProDOSAuxType)
+		return buf.Bytes()
+	}
+	buf.WriteByte(14)
+	buf.WriteByte(APPLE_HFS)
+	buf.Write(fourCC(apple.Type))
+	buf.Write(fourCC(apple.Creator))
+	binary.Write(&buf, binary.BigEndian, apple.FinderFlags)
+	return buf.Bytes()
+}
+
+// FinderInfo returns the 32 byte Finder info of the file as stored in HFS catalogs and AppleDouble files.
+func (a *AppleExtension) FinderInfo() []byte {
+	info := make([]byte, 32)
+	if a.SystemUseID == APPLE_PRODOS {
+		// ProDOS files are presented to the Finder as type 'p' + file type + aux type with creator "pdos"
+		info[0] = 'p'
+		info[1] = a.ProDOSType
+		binary.BigEndian.PutUint16(info[2:4], a.ProDOSAuxType)
+		copy(info[4:8], "pdos")
+		return info
+	}
+	copy(info[0:4], fourCC(a.Type))
+	copy(info[4:8], fourCC(a.Creator))
+	binary.BigEndian.PutUint16(info[8:10], a.FinderFlags)
+	return info
+}
+
+// fourCC pads or truncates a type or creator code to four bytes.
+func fourCC(code string) []byte {
+	out := []byte("    ")
+	copy(out, code)
+	return out
+}
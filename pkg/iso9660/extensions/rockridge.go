@@ -4,15 +4,52 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"github.com/rstms/iso-kit/pkg/iso9660/encoding"
+	"io"
 	"io/fs"
 	"os"
 	"time"
 )
 
 const (
-	ROCK_RIDGE_IDENTIFIER = "RRIP_1991A"
+	// ROCK_RIDGE_IDENTIFIER is the ER identifier written when creating images
+	ROCK_RIDGE_IDENTIFIER = RRIP_1991A
 	ROCK_RIDGE_VERSION    = 1
+	// ROCK_RIDGE_DESCRIPTOR and ROCK_RIDGE_SOURCE are the ER description and source recorded for RRIP_1991A
+	ROCK_RIDGE_DESCRIPTOR = "THE ROCK RIDGE INTERCHANGE PROTOCOL PROVIDES SUPPORT FOR POSIX FILE SYSTEM SEMANTICS"
+	ROCK_RIDGE_SOURCE     = "PLEASE CONTACT DISC PUBLISHER FOR SPECIFICATION SOURCE.  SEE PUBLISHER IDENTIFIER IN PRIMARY VOLUME DESCRIPTOR FOR CONTACT INFORMATION."
+)
+
+// Extension identifiers recorded in the ER entry by the different Rock Ridge Interchange Protocol versions
+const (
+	// RRIP_1991A identifies RRIP 1.09 and 1.10, PX entries are 36 bytes long
+	RRIP_1991A = "RRIP_1991A"
+	// IEEE_P1282 identifies the RRIP 1.12 draft, PX entries are 44 bytes long and carry a file serial number
+	IEEE_P1282 = "IEEE_P1282"
+	// IEEE_1282 identifies RRIP 1.12, PX entries are 44 bytes long and carry a file serial number
+	IEEE_1282 = "IEEE_1282"
+)
+
+// SL component record flags
+const (
+	SL_CONTINUE = 0x01
+	SL_CURRENT  = 0x02
+	SL_PARENT   = 0x04
+	SL_ROOT     = 0x08
+)
+
+// TF entry flags, the timestamps are recorded in the order of the flags
+const (
+	TF_CREATION   = 0x01
+	TF_MODIFY     = 0x02
+	TF_ACCESS     = 0x04
+	TF_ATTRIBUTES = 0x08
+	TF_BACKUP     = 0x10
+	TF_EXPIRATION = 0x20
+	TF_EFFECTIVE  = 0x40
+	// TF_LONG_FORM means the timestamps use the 17-byte volume descriptor format instead of the 7-byte format
+	TF_LONG_FORM = 0x80
 )
 
 type RockRidgeEntryType string
@@ -38,8 +75,71 @@ const (
 	SPARSE_FILE RockRidgeEntryType = "SF"
 	//An older “Rock Ridge” extension signature (now typically replaced by ER).
 	ROCK_RIDGE RockRidgeEntryType = "RR"
+	//zisofs compressed file information (algorithm, block size, uncompressed size)
+	ZISOFS RockRidgeEntryType = "ZF"
+	//zisofs2 compressed file information written by newer libisofs versions
+	ZISOFS2 RockRidgeEntryType = "Z2"
+	//AAIP extended attributes and ACLs written by libisofs
+	AAIP_ATTRIBUTES RockRidgeEntryType = "AL"
+	//SUSP extension reference identifying the extensions in use (recorded in the root directory)
+	EXTENSION_REFERENCE RockRidgeEntryType = "ER"
+	//SUSP continuation area holding further entries which did not fit in the directory record
+	CONTINUATION_AREA RockRidgeEntryType = "CE"
+	//SUSP indicator recorded first in the root directory's "." record
+	SHARING_PROTOCOL RockRidgeEntryType = "SP"
+)
+
+const (
+	// ZISOFS_ALGORITHM_ZLIB is the algorithm identifier of zisofs version 1 (zlib)
+	ZISOFS_ALGORITHM_ZLIB = "pz"
+	// ZISOFS2_ALGORITHM_ZLIB is the algorithm identifier of zisofs2 using zlib
+	ZISOFS2_ALGORITHM_ZLIB = "PZ"
 )
 
+// ZisofsInfo holds the contents of a "ZF" (or "Z2") entry which marks the file data as zisofs compressed.
+type ZisofsInfo struct {
+	// Version is 1 for zisofs and 2 for zisofs2
+	Version uint8 `json:"version"`
+	// Algorithm is the two character compression algorithm identifier, e.g. "pz"
+	Algorithm string `json:"algorithm"`
+	// HeaderSizeDiv4 is the size of the file header in 4-byte words
+	HeaderSizeDiv4 uint8 `json:"header_size_div4"`
+	// Log2BlockSize is the binary logarithm of the compression block size (15, 16 or 17)
+	Log2BlockSize uint8 `json:"log2_block_size"`
+	// UncompressedSize is the logical size of the file content
+	UncompressedSize uint64 `json:"uncompressed_size"`
+}
+
+// ExtensionReference holds the contents of an "ER" entry.
+type ExtensionReference struct {
+	// Identifier names the extension, e.g. RRIP_1991A or "AAIP_0200"
+	Identifier string `json:"identifier"`
+	// Descriptor is a textual description of the extension
+	Descriptor string `json:"descriptor"`
+	// Source identifies where the extension specification can be found
+	Source string `json:"source"`
+	// Version is the extension version
+	Version uint8 `json:"version"`
+}
+
+// ContinuationArea holds the location of further system use entries recorded by a "CE" entry.
+type ContinuationArea struct {
+	// Block is the logical block number of the continuation area
+	Block uint32 `json:"block"`
+	// Offset is the byte offset of the continuation area within the block
+	Offset uint32 `json:"offset"`
+	// Length is the length of the continuation area in bytes
+	Length uint32 `json:"length"`
+}
+
+// SparseFileInfo holds the contents of an "SF" entry which marks the file data as a sparse file table.
+type SparseFileInfo struct {
+	// VirtualSize is the apparent size of the file including holes
+	VirtualSize uint64 `json:"virtual_size"`
+	// TableDepth is the number of levels of block tables recorded ahead of the data
+	TableDepth uint8 `json:"table_depth"`
+}
+
 type NameEntryFlags struct {
 	Continue  bool // Bit 0: Alternate Name continues in the next "NM" entry
 	Current   bool // Bit 1: Alternate Name refers to the current directory ("." in POSIX)
@@ -51,11 +151,26 @@ type NameEntryFlags struct {
 	Reserved5 bool // Bit 7: Unused, reserved for future use
 }
 
+// Marshal encodes the flags of an NM entry, nil flags encode as zero.
+func (f *NameEntryFlags) Marshal() byte {
+	if f == nil {
+		return 0
+	}
+	var flags byte
+	for i, set := range []bool{f.Continue, f.Current, f.Parent, f.Reserved1, f.Reserved2, f.Reserved3, f.Reserved4, f.Reserved5} {
+		if set {
+			flags |= 1 << i
+		}
+	}
+	return flags
+}
+
 type RockRidgeExtensions struct {
 	// PX - POSIX file permissions (UID, GID, Mode)
-	UID         *uint32      // User ID
-	GID         *uint32      // Group ID
-	Permissions *fs.FileMode // File permissions
+	UID          *uint32      // User ID
+	GID          *uint32      // Group ID
+	Permissions  *fs.FileMode // File permissions
+	SerialNumber *uint32      // File serial number (inode), only recorded by RRIP 1.12
 
 	// PN - Device number (if block/char device)
 	Major *uint32
@@ -78,13 +193,75 @@ type RockRidgeExtensions struct {
 	// RE - Relocated directory flag
 	IsRelocated *bool
 
-	// TF - Time stamps (creation, modification, access)
-	CreationTime     *time.Time
-	ModificationTime *time.Time
-	AccessTime       *time.Time
+	// TF - Time stamps
+	CreationTime        *time.Time
+	ModificationTime    *time.Time
+	AccessTime          *time.Time
+	AttributeChangeTime *time.Time
+	BackupTime          *time.Time
+	ExpirationTime      *time.Time
+	EffectiveTime       *time.Time
 
 	// SF - Sparse file info (if applicable)
-	IsSparse *bool
+	Sparse *SparseFileInfo
+
+	// ZF - zisofs compression info (if the file data is compressed)
+	Zisofs *ZisofsInfo
+
+	// AL - AAIP extended attributes and POSIX ACL
+	ExtendedAttributes []ExtendedAttribute
+	ACL                *ACL
+
+	// ER - Extension references, only recorded in the "." record of the root directory
+	ExtensionReferences []ExtensionReference
+
+	// CE - Location of further entries, the caller reads the area and decodes it together with the record
+	Continuation *ContinuationArea
+
+	// AA/BA - Apple ISO 9660 extensions, these are not part of Rock Ridge and are recorded by Mac-authored discs
+	Apple *AppleExtension
+}
+
+// RockRidgeInfo describes the Rock Ridge version detected from the root directory's "." record.
+type RockRidgeInfo struct {
+	// Identifier is the ER identifier, empty when the image uses Rock Ridge entries without recording an ER entry
+	Identifier string `json:"identifier"`
+	// Version is the RRIP version, "1.10" for RRIP_1991A and "1.12" for the IEEE P1282 identifiers
+	Version string `json:"version"`
+	// ExtensionVersion is the version recorded in the ER entry
+	ExtensionVersion uint8 `json:"extensionVersion"`
+	// PXLength is the length of the PX entries written by this version
+	PXLength int `json:"pxLength"`
+	// Extensions lists every ER entry, including non Rock Ridge ones such as AAIP
+	Extensions []ExtensionReference `json:"extensions,omitempty"`
+}
+
+// RockRidgeInfo detects the Rock Ridge version from the ER entries of the root directory's "." record. Images without
+// an ER entry are identified by the length of their PX entry. Nil is returned if no Rock Ridge entries are present.
+func (r *RockRidgeExtensions) RockRidgeInfo() *RockRidgeInfo {
+	info := &RockRidgeInfo{Extensions: r.ExtensionReferences}
+	for _, er := range r.ExtensionReferences {
+		switch er.Identifier {
+		case RRIP_1991A:
+			info.Identifier, info.Version, info.PXLength = er.Identifier, "1.10", 36
+		case IEEE_P1282, IEEE_1282:
+			info.Identifier, info.Version, info.PXLength = er.Identifier, "1.12", 44
+		default:
+			continue
+		}
+		info.ExtensionVersion = er.Version
+		return info
+	}
+
+	if !r.HasRockRidge() {
+		return nil
+	}
+	if r.SerialNumber != nil {
+		info.Version, info.PXLength = "1.12", 44
+	} else {
+		info.Version, info.PXLength = "1.10", 36
+	}
+	return info
 }
 
 // HasRockRidge determines if any Rock Ridge extensions were set.
@@ -93,131 +270,289 @@ func (r *RockRidgeExtensions) HasRockRidge() bool {
 		r.Major != nil || r.Minor != nil || r.SymlinkTarget != nil ||
 		r.AlternateName != nil || r.ChildLinkLBA != nil || r.ParentLinkLBA != nil ||
 		r.IsRelocated != nil || r.CreationTime != nil || r.ModificationTime != nil ||
-		r.AccessTime != nil || r.IsSparse != nil
+		r.AccessTime != nil || r.AttributeChangeTime != nil || r.Sparse != nil
+}
+
+// SymlinkPath decodes the component records of the SL entry into a slash separated path, e.g. "../lib/libc.so". An
+// empty string is returned if the entry is not a symbolic link.
+func (r *RockRidgeExtensions) SymlinkPath() string {
+	if r.SymlinkTarget == nil {
+		return ""
+	}
+
+	data := []byte(*r.SymlinkTarget)
+	var target []byte
+	continued := false
+	for len(data) >= 2 {
+		flags, length := data[0], int(data[1])
+		if 2+length > len(data) {
+			break
+		}
+		if !continued && len(target) > 0 && target[len(target)-1] != '/' {
+			target = append(target, '/')
+		}
+		switch {
+		case flags&SL_ROOT != 0:
+			target = append(target[:0], '/')
+		case flags&SL_CURRENT != 0:
+			target = append(target, '.')
+		case flags&SL_PARENT != 0:
+			target = append(target, ".."...)
+		default:
+			target = append(target, data[2:2+length]...)
+		}
+		continued = flags&SL_CONTINUE != 0
+		data = data[2+length:]
+	}
+	return string(target)
 }
 
-func UnmarshalRockRidge(data []byte) (*RockRidgeExtensions, error) {
+// UnmarshalRockRidge decodes the system use entries of a directory record. The contents of continuation areas
+// referenced by CE entries are passed as further arguments and decoded into the same result.
+func UnmarshalRockRidge(data []byte, continuation ...[]byte) (*RockRidgeExtensions, error) {
 	if len(data) < 2 {
 		return nil, errors.New("invalid Rock Ridge data")
 	}
 
 	rr := &RockRidgeExtensions{}
-	reader := bytes.NewReader(data)
+	var aaip []byte
+
+	for _, area := range append([][]byte{data}, continuation...) {
+		reader := bytes.NewReader(area)
+		for reader.Len() >= 4 {
+			// Read signature (2-byte identifier)
+			var sig [2]byte
+			if err := binary.Read(reader, binary.LittleEndian, &sig); err != nil {
+				return nil, err
+			}
+			entryType := string(sig[:])
 
-	for reader.Len() > 4 {
-		// Read signature (2-byte identifier)
-		var sig [2]byte
-		if err := binary.Read(reader, binary.LittleEndian, &sig); err != nil {
-			return nil, err
-		}
-		entryType := string(sig[:])
+			// Read length (1 byte)
+			var length byte
+			if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
+				return nil, err
+			}
 
-		// Read length (1 byte)
-		var length byte
-		if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
-			return nil, err
-		}
+			// Read version (1 byte)
+			var version byte
+			if err := binary.Read(reader, binary.LittleEndian, &version); err != nil {
+				return nil, err
+			}
 
-		// Read version (1 byte)
-		var version byte
-		if err := binary.Read(reader, binary.LittleEndian, &version); err != nil {
-			return nil, err
-		}
+			// A length below the entry header size means padding or corrupt data, no further entries can be read
+			if length < 4 {
+				break
+			}
 
-		// Read payload
-		payloadLen := int(length) - 4
-		payload := make([]byte, payloadLen)
-		if _, err := reader.Read(payload); err != nil {
-			return nil, err
-		}
+			// Read payload
+			payloadLen := int(length) - 4
+			payload := make([]byte, payloadLen)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return nil, err
+			}
 
-		switch RockRidgeEntryType(entryType) {
-		case POSIX_FILE_PERMS: // PX (POSIX permissions)
-			if len(payload) >= 32 {
-				// Payload is the bytes from offset 4 to 36 (32 bytes) ... technically there are another 8 bytes for the
-				// file serial number but that generally hasn't been present so it is ignored here.
-				// Decode 8-byte File Mode (Permissions)
-				mode, err := encoding.UnmarshalUint32LSBMSB([8]byte(payload[0:8]))
-				if err == nil {
-					permissions := parseFileMode(mode)
-					rr.Permissions = &permissions
+			switch RockRidgeEntryType(entryType) {
+			case POSIX_FILE_PERMS: // PX (POSIX permissions)
+				if len(payload) >= 32 {
+					// Payload is the bytes from offset 4 to 36 (32 bytes), RRIP 1.12 adds another 8 bytes for the file
+					// serial number.
+					// Decode 8-byte File Mode (Permissions)
+					mode, err := encoding.UnmarshalUint32LSBMSB([8]byte(payload[0:8]))
+					if err == nil {
+						permissions := parseFileMode(mode)
+						rr.Permissions = &permissions
+					}
+
+					// Decode 8-byte Number of Links
+					_, err = encoding.UnmarshalUint32LSBMSB([8]byte(payload[8:16]))
+					if err != nil {
+						return nil, errors.New("failed to parse PX link count")
+					}
+
+					// Decode 8-byte UID
+					uid, err := encoding.UnmarshalUint32LSBMSB([8]byte(payload[16:24]))
+					if err == nil {
+						rr.UID = &uid
+					}
+
+					// Decode 8-byte GID
+					gid, err := encoding.UnmarshalUint32LSBMSB([8]byte(payload[24:32]))
+					if err == nil {
+						rr.GID = &gid
+					}
+
+					// RRIP 1.12 adds the 8-byte file serial number
+					if len(payload) >= 40 {
+						serial, err := encoding.UnmarshalUint32LSBMSB([8]byte(payload[32:40]))
+						if err == nil {
+							rr.SerialNumber = &serial
+						}
+					}
 				}
-
-				// Decode 8-byte Number of Links
-				_, err = encoding.UnmarshalUint32LSBMSB([8]byte(payload[8:16]))
-				if err != nil {
-					return nil, errors.New("failed to parse PX link count")
+			case TIME_STAMPS: // TF (Timestamps)
+				if len(payload) < 1 {
+					continue
+				}
+				flags := payload[0]
+				size := 7
+				if flags&TF_LONG_FORM != 0 {
+					size = 17
+				}
+				fields := []struct {
+					flag   byte
+					target **time.Time
+				}{
+					{TF_CREATION, &rr.CreationTime},
+					{TF_MODIFY, &rr.ModificationTime},
+					{TF_ACCESS, &rr.AccessTime},
+					{TF_ATTRIBUTES, &rr.AttributeChangeTime},
+					{TF_BACKUP, &rr.BackupTime},
+					{TF_EXPIRATION, &rr.ExpirationTime},
+					{TF_EFFECTIVE, &rr.EffectiveTime},
+				}
+				offset := 1
+				for _, field := range fields {
+					if flags&field.flag == 0 {
+						continue
+					}
+					if offset+size > len(payload) {
+						break
+					}
+					var t time.Time
+					var err error
+					if size == 17 {
+						t, err = encoding.UnmarshalDateTime([17]byte(payload[offset : offset+17]))
+					} else {
+						t, err = encoding.UnmarshalRecordingDateTime([7]byte(payload[offset : offset+7]))
+					}
+					offset += size
+					if err != nil || t.IsZero() {
+						continue
+					}
+					*field.target = &t
 				}
 
-				// Decode 8-byte UID
-				uid, err := encoding.UnmarshalUint32LSBMSB([8]byte(payload[16:24]))
-				if err == nil {
-					rr.UID = &uid
+			case EXTENSION_REFERENCE: // ER (Extension reference)
+				if len(payload) < 4 {
+					continue
+				}
+				idLen, desLen, srcLen := int(payload[0]), int(payload[1]), int(payload[2])
+				if 4+idLen+desLen+srcLen > len(payload) {
+					continue
+				}
+				text := payload[4:]
+				rr.ExtensionReferences = append(rr.ExtensionReferences, ExtensionReference{
+					Identifier: string(text[:idLen]),
+					Descriptor: string(text[idLen : idLen+desLen]),
+					Source:     string(text[idLen+desLen : idLen+desLen+srcLen]),
+					Version:    payload[3],
+				})
+
+			case CONTINUATION_AREA: // CE (Continuation area)
+				if len(payload) < 24 {
+					continue
+				}
+				block, err1 := encoding.UnmarshalUint32LSBMSB([8]byte(payload[0:8]))
+				offset, err2 := encoding.UnmarshalUint32LSBMSB([8]byte(payload[8:16]))
+				length, err3 := encoding.UnmarshalUint32LSBMSB([8]byte(payload[16:24]))
+				if err1 != nil || err2 != nil || err3 != nil {
+					return nil, errors.New("failed to parse CE entry")
 				}
+				rr.Continuation = &ContinuationArea{Block: block, Offset: offset, Length: length}
+
+			case ALTERNATE_NAME: // NM (Alternate name)
+				// Flags (NM Flags) - 8-bit number. The following bits are defined:
+				// 	 Bit 0: Continuation - If set to 1, the Name Content record is continued in the next "NM" entry.
+				//   Bit 1: Current - If set to 1, the Name Content record refers to the current directory.
+				//   Bit 2: Parent - If set to 1, the Name Content record refers to the parent directory.
+				//   Bit 3: Reserved - Should be set to 0.
+				//   Bit 4: Reserved - Should be set to 0.
+				//   Bit 5: Historical - Historically contains the network node name.
+				//   Bit 6: Reserved - Should be set to 0.
+				//   Bit 7: Reserved - Should be set to 0.
+				flags := payload[0]
+				rr.AlternateNameFlags = &NameEntryFlags{
+					Continue:  flags&0x01 > 0,
+					Current:   flags&0x02 > 0,
+					Parent:    flags&0x04 > 0,
+					Reserved1: flags&0x08 > 0,
+					Reserved2: flags&0x10 > 0,
+					Reserved3: flags&0x20 > 0,
+					Reserved4: flags&0x40 > 0,
+					Reserved5: flags&0x80 > 0,
+				}
+				rr.AlternateName = new(string)
+				*rr.AlternateName = string(payload[1:])
 
-				// Decode 8-byte GID
-				gid, err := encoding.UnmarshalUint32LSBMSB([8]byte(payload[24:32]))
-				if err == nil {
-					rr.GID = &gid
+			case ZISOFS, ZISOFS2: // ZF / Z2 (zisofs compression)
+				if len(payload) < 12 {
+					continue
 				}
-			}
-		case TIME_STAMPS: // TF (Timestamps)
-			// Rock Ridge TF entry uses a **variable-length encoding**
-			offset := 0
-			for offset < len(payload) {
-				flag := payload[offset]
-				offset++
-
-				if flag&0x01 != 0 && offset+7 <= len(payload) { // Creation time
-					seconds := int64(binary.LittleEndian.Uint32(payload[offset : offset+4]))
-					rr.CreationTime = new(time.Time)
-					*rr.CreationTime = time.Unix(seconds, 0)
-					offset += 7
+				zf := &ZisofsInfo{
+					Version:        version,
+					Algorithm:      string(payload[0:2]),
+					HeaderSizeDiv4: payload[2],
+					Log2BlockSize:  payload[3],
+				}
+				if RockRidgeEntryType(entryType) == ZISOFS2 {
+					zf.Version = 2
+				}
+				if zf.Version >= 2 {
+					// zisofs2 records a 64-bit little-endian size so files larger than 4 GiB can be described
+					zf.UncompressedSize = binary.LittleEndian.Uint64(payload[4:12])
+				} else {
+					size, err := encoding.UnmarshalUint32LSBMSB([8]byte(payload[4:12]))
+					if err != nil {
+						return nil, errors.New("failed to parse ZF uncompressed size")
+					}
+					zf.UncompressedSize = uint64(size)
 				}
+				rr.Zisofs = zf
 
-				if flag&0x02 != 0 && offset+7 <= len(payload) { // Modification time
-					seconds := int64(binary.LittleEndian.Uint32(payload[offset : offset+4]))
-					rr.ModificationTime = new(time.Time)
-					*rr.ModificationTime = time.Unix(seconds, 0)
-					offset += 7
+			case SPARSE_FILE: // SF (Sparse file)
+				// RRIP 1.12 records the virtual size as high and low 32-bit halves followed by the table depth, RRIP 1.10
+				// only recorded a single 32-bit size.
+				if len(payload) < 8 {
+					continue
 				}
+				sf := &SparseFileInfo{TableDepth: 1}
+				low, err := encoding.UnmarshalUint32LSBMSB([8]byte(payload[0:8]))
+				if err != nil {
+					return nil, errors.New("failed to parse SF virtual file size")
+				}
+				sf.VirtualSize = uint64(low)
+				if len(payload) >= 17 {
+					high := low
+					if low, err = encoding.UnmarshalUint32LSBMSB([8]byte(payload[8:16])); err != nil {
+						return nil, errors.New("failed to parse SF virtual file size")
+					}
+					sf.VirtualSize = uint64(high)<<32 | uint64(low)
+					sf.TableDepth = payload[16]
+				}
+				rr.Sparse = sf
 
-				if flag&0x04 != 0 && offset+7 <= len(payload) { // Access time
-					seconds := int64(binary.LittleEndian.Uint32(payload[offset : offset+4]))
-					rr.AccessTime = new(time.Time)
-					*rr.AccessTime = time.Unix(seconds, 0)
-					offset += 7
+			case AAIP_ATTRIBUTES: // AL (AAIP attributes), the component records are decoded once all entries are collected
+				if len(payload) > 1 {
+					aaip = append(aaip, payload[1:]...)
 				}
-			}
 
-		case ALTERNATE_NAME: // NM (Alternate name)
-			// Flags (NM Flags) - 8-bit number. The following bits are defined:
-			// 	 Bit 0: Continuation - If set to 1, the Name Content record is continued in the next "NM" entry.
-			//   Bit 1: Current - If set to 1, the Name Content record refers to the current directory.
-			//   Bit 2: Parent - If set to 1, the Name Content record refers to the parent directory.
-			//   Bit 3: Reserved - Should be set to 0.
-			//   Bit 4: Reserved - Should be set to 0.
-			//   Bit 5: Historical - Historically contains the network node name.
-			//   Bit 6: Reserved - Should be set to 0.
-			//   Bit 7: Reserved - Should be set to 0.
-			flags := payload[0]
-			rr.AlternateNameFlags = &NameEntryFlags{
-				Continue:  flags&0x01 > 0,
-				Current:   flags&0x02 > 0,
-				Parent:    flags&0x04 > 0,
-				Reserved1: flags&0x08 > 0,
-				Reserved2: flags&0x10 > 0,
-				Reserved3: flags&0x20 > 0,
-				Reserved4: flags&0x40 > 0,
-				Reserved5: flags&0x80 > 0,
+			case APPLE_EXTENSION, APPLE_EXTENSION_BA: // AA/BA (Apple extensions), the version byte holds the system use id
+				rr.Apple = unmarshalApple(version, payload)
+
+			case SYMBOLIC_LINK: // SL (Symbolic link)
+				rr.SymlinkTarget = new(string)
+				*rr.SymlinkTarget = string(payload[1:]) // Skip flags byte
 			}
-			rr.AlternateName = new(string)
-			*rr.AlternateName = string(payload[1:])
+		}
+	}
 
-		case SYMBOLIC_LINK: // SL (Symbolic link)
-			rr.SymlinkTarget = new(string)
-			*rr.SymlinkTarget = string(payload[1:]) // Skip flags byte
+	if len(aaip) > 0 {
+		attrs, acl, err := unmarshalAAIP(aaip)
+		if err != nil {
+			return nil, err
 		}
+		rr.ExtendedAttributes = attrs
+		rr.ACL = acl
 	}
 
 	return rr, nil
@@ -228,13 +563,27 @@ func MarshalRockRidge(rr *RockRidgeExtensions) ([]byte, error) {
 	var buf bytes.Buffer
 
 	//TODO: Fix this whole function, there were a lot of errors with sizes and offsets
+	if rr.Apple != nil {
+		buf.Write(marshalApple(rr.Apple))
+	}
+
 	if rr.UID != nil && rr.GID != nil && rr.Permissions != nil {
-		buf.Write([]byte("PX"))           // Signature
-		buf.WriteByte(10 + 4)             // Length (10 bytes data + header)
+		// RRIP 1.10 PX entries are 36 bytes, RRIP 1.12 appends the file serial number for 44 bytes
+		length := 36
+		if rr.SerialNumber != nil {
+			length = 44
+		}
+		buf.Write([]byte(POSIX_FILE_PERMS)) // Signature
+		buf.WriteByte(byte(length))
 		buf.WriteByte(ROCK_RIDGE_VERSION) // Version
-		binary.Write(&buf, binary.LittleEndian, *rr.UID)
-		binary.Write(&buf, binary.LittleEndian, *rr.GID)
-		binary.Write(&buf, binary.LittleEndian, *rr.Permissions)
+		fields := []uint32{formatFileMode(*rr.Permissions), 1, *rr.UID, *rr.GID}
+		if rr.SerialNumber != nil {
+			fields = append(fields, *rr.SerialNumber)
+		}
+		for _, field := range fields {
+			b := encoding.MarshalBothByteOrders32(field)
+			buf.Write(b[:])
+		}
 	}
 
 	if rr.Major != nil && rr.Minor != nil {
@@ -246,16 +595,28 @@ func MarshalRockRidge(rr *RockRidgeExtensions) ([]byte, error) {
 	}
 
 	if rr.SymlinkTarget != nil {
+		if len(*rr.SymlinkTarget) > 250 {
+			return nil, errors.New("SL entry exceeds 255 bytes")
+		}
+		var flags byte
+		if rr.SymlinkFlags != nil {
+			flags = *rr.SymlinkFlags
+		}
 		buf.Write([]byte("SL")) // Signature
-		buf.WriteByte(byte(len(*rr.SymlinkTarget) + 4))
+		buf.WriteByte(byte(len(*rr.SymlinkTarget) + 5))
 		buf.WriteByte(ROCK_RIDGE_VERSION)
+		buf.WriteByte(flags)
 		buf.WriteString(*rr.SymlinkTarget)
 	}
 
 	if rr.AlternateName != nil {
+		if len(*rr.AlternateName) > 250 {
+			return nil, fmt.Errorf("NM entry for %q exceeds 255 bytes", *rr.AlternateName)
+		}
 		buf.Write([]byte("NM")) // Signature
-		buf.WriteByte(byte(len(*rr.AlternateName) + 4))
+		buf.WriteByte(byte(len(*rr.AlternateName) + 5))
 		buf.WriteByte(ROCK_RIDGE_VERSION)
+		buf.WriteByte(rr.AlternateNameFlags.Marshal())
 		buf.WriteString(*rr.AlternateName)
 	}
 
@@ -279,19 +640,143 @@ func MarshalRockRidge(rr *RockRidgeExtensions) ([]byte, error) {
 		buf.WriteByte(ROCK_RIDGE_VERSION)
 	}
 
-	if rr.CreationTime != nil {
-		buf.Write([]byte("TF")) // Signature
-		buf.WriteByte(7 + 4)
-		buf.WriteByte(ROCK_RIDGE_VERSION)
-		binary.Write(&buf, binary.LittleEndian, uint32(rr.CreationTime.Unix()))
+	tf, err := marshalTimeStamps(rr)
+	if err != nil {
+		return nil, err
 	}
+	buf.Write(tf)
 
-	if rr.IsSparse != nil && *rr.IsSparse {
-		buf.Write([]byte("SF")) // Signature
-		buf.WriteByte(4)
+	if rr.Sparse != nil {
+		buf.Write([]byte(SPARSE_FILE)) // Signature
+		buf.WriteByte(17 + 4)
 		buf.WriteByte(ROCK_RIDGE_VERSION)
+		high := encoding.MarshalBothByteOrders32(uint32(rr.Sparse.VirtualSize >> 32))
+		low := encoding.MarshalBothByteOrders32(uint32(rr.Sparse.VirtualSize))
+		buf.Write(high[:])
+		buf.Write(low[:])
+		buf.WriteByte(rr.Sparse.TableDepth)
+	}
+
+	if rr.Zisofs != nil {
+		if rr.Zisofs.Version >= 2 {
+			buf.Write([]byte(ZISOFS2)) // Signature
+			buf.WriteByte(12 + 4)
+			buf.WriteByte(2)
+			buf.WriteString(rr.Zisofs.Algorithm)
+			buf.WriteByte(rr.Zisofs.HeaderSizeDiv4)
+			buf.WriteByte(rr.Zisofs.Log2BlockSize)
+			binary.Write(&buf, binary.LittleEndian, rr.Zisofs.UncompressedSize)
+		} else {
+			if rr.Zisofs.UncompressedSize > uint64(^uint32(0)) {
+				return nil, errors.New("ZF entry cannot describe files larger than 4 GiB")
+			}
+			buf.Write([]byte(ZISOFS)) // Signature
+			buf.WriteByte(12 + 4)
+			buf.WriteByte(ROCK_RIDGE_VERSION)
+			buf.WriteString(rr.Zisofs.Algorithm)
+			buf.WriteByte(rr.Zisofs.HeaderSizeDiv4)
+			buf.WriteByte(rr.Zisofs.Log2BlockSize)
+			size := encoding.MarshalBothByteOrders32(uint32(rr.Zisofs.UncompressedSize))
+			buf.Write(size[:])
+		}
+	}
+
+	if len(rr.ExtendedAttributes) > 0 || rr.ACL != nil {
+		buf.Write(marshalAAIP(rr.ExtendedAttributes, rr.ACL))
 	}
 
+	for _, er := range rr.ExtensionReferences {
+		length := 8 + len(er.Identifier) + len(er.Descriptor) + len(er.Source)
+		if length > 255 {
+			return nil, fmt.Errorf("ER entry for %s exceeds 255 bytes", er.Identifier)
+		}
+		buf.Write([]byte(EXTENSION_REFERENCE)) // Signature
+		buf.WriteByte(byte(length))
+		buf.WriteByte(1)
+		buf.WriteByte(byte(len(er.Identifier)))
+		buf.WriteByte(byte(len(er.Descriptor)))
+		buf.WriteByte(byte(len(er.Source)))
+		buf.WriteByte(er.Version)
+		buf.WriteString(er.Identifier)
+		buf.WriteString(er.Descriptor)
+		buf.WriteString(er.Source)
+	}
+
+	if rr.Continuation != nil {
+		buf.Write([]byte(CONTINUATION_AREA)) // Signature
+		buf.WriteByte(24 + 4)
+		buf.WriteByte(1)
+		for _, field := range []uint32{rr.Continuation.Block, rr.Continuation.Offset, rr.Continuation.Length} {
+			b := encoding.MarshalBothByteOrders32(field)
+			buf.Write(b[:])
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// MarshalSharingProtocol encodes the SUSP "SP" entry which has to be the first system use entry of the root
+// directory's "." record, it announces that the directory records of the volume carry system use entries.
+func MarshalSharingProtocol() []byte {
+	return append([]byte(SHARING_PROTOCOL), 7, 1, 0xBE, 0xEF, 0)
+}
+
+// marshalTimeStamps encodes the set timestamps as a TF entry. The 7-byte short form is used unless a timestamp lies
+// outside of the years it can represent.
+func marshalTimeStamps(rr *RockRidgeExtensions) ([]byte, error) {
+	times := []struct {
+		flag byte
+		t    *time.Time
+	}{
+		{TF_CREATION, rr.CreationTime},
+		{TF_MODIFY, rr.ModificationTime},
+		{TF_ACCESS, rr.AccessTime},
+		{TF_ATTRIBUTES, rr.AttributeChangeTime},
+		{TF_BACKUP, rr.BackupTime},
+		{TF_EXPIRATION, rr.ExpirationTime},
+		{TF_EFFECTIVE, rr.EffectiveTime},
+	}
+
+	var flags byte
+	for _, ts := range times {
+		if ts.t == nil {
+			continue
+		}
+		flags |= ts.flag
+		if year := ts.t.Year(); year < 1900 || year > 2155 {
+			flags |= TF_LONG_FORM
+		}
+	}
+	if flags == 0 {
+		return nil, nil
+	}
+
+	var payload bytes.Buffer
+	for _, ts := range times {
+		if ts.t == nil {
+			continue
+		}
+		if flags&TF_LONG_FORM != 0 {
+			b, err := encoding.MarshalDateTime(*ts.t)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal TF timestamp: %w", err)
+			}
+			payload.Write(b[:])
+		} else {
+			b, err := encoding.MarshalRecordingDateTime(*ts.t)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal TF timestamp: %w", err)
+			}
+			payload.Write(b[:])
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Write([]byte(TIME_STAMPS)) // Signature
+	buf.WriteByte(byte(payload.Len() + 5))
+	buf.WriteByte(ROCK_RIDGE_VERSION)
+	buf.WriteByte(flags)
+	buf.Write(payload.Bytes())
 	return buf.Bytes(), nil
 }
 
@@ -359,3 +844,37 @@ func parseFileMode(mode uint32) fs.FileMode {
 
 	return fileMode
 }
+
+// formatFileMode converts an fs.FileMode into the POSIX st_mode value recorded in PX entries
+func formatFileMode(mode fs.FileMode) uint32 {
+	var out uint32
+
+	switch {
+	case mode&fs.ModeSocket != 0:
+		out = 0xC000
+	case mode&fs.ModeSymlink != 0:
+		out = 0xA000
+	case mode&fs.ModeCharDevice != 0:
+		out = 0x2000
+	case mode&fs.ModeDevice != 0:
+		out = 0x6000
+	case mode&fs.ModeDir != 0:
+		out = 0x4000
+	case mode&fs.ModeNamedPipe != 0:
+		out = 0x1000
+	default:
+		out = 0x8000
+	}
+
+	out |= uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		out |= 0x0800
+	}
+	if mode&os.ModeSetgid != 0 {
+		out |= 0x0400
+	}
+	if mode&os.ModeSticky != 0 {
+		out |= 0x0200
+	}
+	return out
+}
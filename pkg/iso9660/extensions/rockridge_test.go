@@ -0,0 +1,109 @@
+package extensions
+
+import (
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeStamps(t *testing.T) {
+	zone := time.FixedZone("", 3600)
+	modify := time.Date(2024, 5, 17, 12, 30, 45, 0, zone)
+	access := time.Date(2025, 1, 2, 3, 4, 5, 0, zone)
+	change := time.Date(2025, 1, 2, 3, 4, 6, 0, zone)
+	expire := time.Date(2200, 1, 1, 0, 0, 0, 0, zone)
+
+	// Short form
+	data, err := MarshalRockRidge(&RockRidgeExtensions{ModificationTime: &modify, AccessTime: &access, AttributeChangeTime: &change})
+	require.NoError(t, err)
+	require.Equal(t, byte(5+3*7), data[2])
+	require.Equal(t, byte(TF_MODIFY|TF_ACCESS|TF_ATTRIBUTES), data[4])
+
+	rr, err := UnmarshalRockRidge(data)
+	require.NoError(t, err)
+	require.Nil(t, rr.CreationTime)
+	require.True(t, modify.Equal(*rr.ModificationTime))
+	require.True(t, access.Equal(*rr.AccessTime))
+	require.True(t, change.Equal(*rr.AttributeChangeTime))
+
+	// The expiration year does not fit the short form so every timestamp is written in the long form
+	data, err = MarshalRockRidge(&RockRidgeExtensions{ModificationTime: &modify, ExpirationTime: &expire})
+	require.NoError(t, err)
+	require.Equal(t, byte(5+2*17), data[2])
+	require.Equal(t, byte(TF_MODIFY|TF_EXPIRATION|TF_LONG_FORM), data[4])
+
+	rr, err = UnmarshalRockRidge(data)
+	require.NoError(t, err)
+	require.True(t, modify.Equal(*rr.ModificationTime))
+	require.True(t, expire.Equal(*rr.ExpirationTime))
+}
+
+func TestRockRidgeInfo(t *testing.T) {
+	uid, gid := uint32(1000), uint32(100)
+	mode := fs.FileMode(0755) | fs.ModeDir
+
+	for _, tc := range []struct {
+		identifier string
+		version    string
+		pxLength   int
+	}{
+		{RRIP_1991A, "1.10", 36},
+		{IEEE_P1282, "1.12", 44},
+		{IEEE_1282, "1.12", 44},
+	} {
+		t.Run(tc.identifier, func(t *testing.T) {
+			rr := &RockRidgeExtensions{
+				UID: &uid, GID: &gid, Permissions: &mode,
+				ExtensionReferences: []ExtensionReference{{
+					Identifier: tc.identifier,
+					Descriptor: "THE ROCK RIDGE INTERCHANGE PROTOCOL",
+					Source:     "PLEASE CONTACT THE IEEE",
+					Version:    1,
+				}},
+			}
+			if tc.pxLength == 44 {
+				serial := uint32(42)
+				rr.SerialNumber = &serial
+			}
+
+			data, err := MarshalRockRidge(rr)
+			require.NoError(t, err)
+			require.Equal(t, byte(tc.pxLength), data[2])
+
+			got, err := UnmarshalRockRidge(data)
+			require.NoError(t, err)
+			require.Equal(t, mode, *got.Permissions)
+			require.Equal(t, rr.ExtensionReferences, got.ExtensionReferences)
+			require.Equal(t, rr.SerialNumber, got.SerialNumber)
+
+			info := got.RockRidgeInfo()
+			require.NotNil(t, info)
+			require.Equal(t, tc.identifier, info.Identifier)
+			require.Equal(t, tc.version, info.Version)
+			require.Equal(t, tc.pxLength, info.PXLength)
+		})
+	}
+
+	require.Nil(t, (&RockRidgeExtensions{}).RockRidgeInfo())
+}
+
+func TestAppleExtension(t *testing.T) {
+	// An HFS "AA" entry followed by a Rock Ridge NM entry
+	data := []byte{'A', 'A', 14, APPLE_HFS, 'T', 'E', 'X', 'T', 't', 't', 'x', 't', 0x40, 0x00}
+	data = append(data, 'N', 'M', 8, 1, 0, 'a', 'b', 'c')
+
+	rr, err := UnmarshalRockRidge(data)
+	require.NoError(t, err)
+	require.Equal(t, &AppleExtension{SystemUseID: APPLE_HFS, Type: "TEXT", Creator: "ttxt", FinderFlags: 0x4000}, rr.Apple)
+	require.Equal(t, "abc", *rr.AlternateName)
+	require.Equal(t, data[:14], marshalApple(rr.Apple))
+
+	// ProDOS entries carry the file and auxiliary type
+	rr, err = UnmarshalRockRidge([]byte{'B', 'A', 7, APPLE_PRODOS, 0x06, 0x00, 0x20})
+	require.NoError(t, err)
+	require.Equal(t, uint8(0x06), rr.Apple.ProDOSType)
+	require.Equal(t, uint16(0x2000), rr.Apple.ProDOSAuxType)
+	require.Equal(t, []byte{'p', 0x06, 0x20, 0x00, 'p', 'd', 'o', 's'}, rr.Apple.FinderInfo()[:8])
+}
@@ -0,0 +1,115 @@
+package extensions
+
+import (
+	"encoding/binary"
+	"os"
+)
+
+// CD-ROM XA discs record a 14 byte XA record at the start of the system use field of every directory record. It is
+// not a SUSP entry, Rock Ridge entries follow it.
+//
+//	BP 1 - 2:   Owner group id (big-endian)
+//	BP 3 - 4:   Owner user id (big-endian)
+//	BP 5 - 6:   Attributes (big-endian)
+//	BP 7 - 8:   Signature "XA"
+//	BP 9:       File number, the interleaved file selected by the sector subheaders
+//	BP 10 - 14: Reserved
+const (
+	XA_SIGNATURE   = "XA"
+	XA_RECORD_SIZE = 14
+
+	XA_ATTR_OWNER_READ    = 0x0001
+	XA_ATTR_OWNER_EXECUTE = 0x0004
+	XA_ATTR_GROUP_READ    = 0x0010
+	XA_ATTR_GROUP_EXECUTE = 0x0040
+	XA_ATTR_WORLD_READ    = 0x0100
+	XA_ATTR_WORLD_EXECUTE = 0x0400
+	XA_ATTR_MODE2         = 0x0800
+	XA_ATTR_MODE2_FORM2   = 0x1000
+	XA_ATTR_INTERLEAVED   = 0x2000
+	XA_ATTR_CDDA          = 0x4000
+	XA_ATTR_DIRECTORY     = 0x8000
+)
+
+// XARecord holds the CD-ROM XA system use information of a directory record.
+type XARecord struct {
+	// GroupID and UserID identify the owner of the file
+	GroupID uint16 `json:"groupId"`
+	UserID  uint16 `json:"userId"`
+	// Attributes holds the permission and sector format flags, see the XA_ATTR constants
+	Attributes uint16 `json:"attributes"`
+	// FileNumber identifies the file's sectors among interleaved data
+	FileNumber uint8 `json:"fileNumber"`
+}
+
+// UnmarshalXA decodes the XA record at the start of a system use field, false is returned if there is none.
+func UnmarshalXA(systemUse []byte) (*XARecord, bool) {
+	if len(systemUse) < XA_RECORD_SIZE || string(systemUse[6:8]) != XA_SIGNATURE {
+		return nil, false
+	}
+	return &XARecord{
+		GroupID:    binary.BigEndian.Uint16(systemUse[0:2]),
+		UserID:     binary.BigEndian.Uint16(systemUse[2:4]),
+		Attributes: binary.BigEndian.Uint16(systemUse[4:6]),
+		FileNumber: systemUse[8],
+	}, true
+}
+
+// Marshal encodes the XA record.
+func (xa *XARecord) Marshal() []byte {
+	buf := make([]byte, XA_RECORD_SIZE)
+	binary.BigEndian.PutUint16(buf[0:2], xa.GroupID)
+	binary.BigEndian.PutUint16(buf[2:4], xa.UserID)
+	binary.BigEndian.PutUint16(buf[4:6], xa.Attributes)
+	copy(buf[6:8], XA_SIGNATURE)
+	buf[8] = xa.FileNumber
+	return buf
+}
+
+// IsForm1 returns true if the file is recorded in Mode 2 Form 1 sectors.
+func (xa *XARecord) IsForm1() bool {
+	return xa.Attributes&XA_ATTR_MODE2 != 0
+}
+
+// IsForm2 returns true if the file is recorded in Mode 2 Form 2 sectors holding 2324 bytes of user data each.
+func (xa *XARecord) IsForm2() bool {
+	return xa.Attributes&XA_ATTR_MODE2_FORM2 != 0
+}
+
+// IsInterleaved returns true if the file's sectors are interleaved with those of other files.
+func (xa *XARecord) IsInterleaved() bool {
+	return xa.Attributes&XA_ATTR_INTERLEAVED != 0
+}
+
+// IsCDDA returns true if the file refers to CD-DA audio sectors.
+func (xa *XARecord) IsCDDA() bool {
+	return xa.Attributes&XA_ATTR_CDDA != 0
+}
+
+// IsDirectory returns true if the record describes a directory.
+func (xa *XARecord) IsDirectory() bool {
+	return xa.Attributes&XA_ATTR_DIRECTORY != 0
+}
+
+// Permissions returns the permission bits of the attributes. XA has no write permissions, read permissions are mapped
+// to read and execute permissions to execute.
+func (xa *XARecord) Permissions() os.FileMode {
+	var mode os.FileMode
+	bits := []struct {
+		attr uint16
+		mode os.FileMode
+	}{
+		{XA_ATTR_OWNER_READ, 0400}, {XA_ATTR_OWNER_EXECUTE, 0100},
+		{XA_ATTR_GROUP_READ, 0040}, {XA_ATTR_GROUP_EXECUTE, 0010},
+		{XA_ATTR_WORLD_READ, 0004}, {XA_ATTR_WORLD_EXECUTE, 0001},
+	}
+	for _, bit := range bits {
+		if xa.Attributes&bit.attr != 0 {
+			mode |= bit.mode
+		}
+	}
+	if xa.IsDirectory() {
+		mode |= os.ModeDir
+	}
+	return mode
+}
@@ -0,0 +1,30 @@
+package extensions
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestXARecord(t *testing.T) {
+	xa := &XARecord{
+		GroupID:    1,
+		UserID:     2,
+		Attributes: XA_ATTR_OWNER_READ | XA_ATTR_GROUP_READ | XA_ATTR_WORLD_READ | XA_ATTR_MODE2_FORM2 | XA_ATTR_INTERLEAVED,
+		FileNumber: 1,
+	}
+	data := xa.Marshal()
+	require.Len(t, data, XA_RECORD_SIZE)
+
+	got, ok := UnmarshalXA(append(data, 'R', 'R', 5, 1, 0x81))
+	require.True(t, ok)
+	require.Equal(t, xa, got)
+	require.True(t, got.IsForm2())
+	require.False(t, got.IsForm1())
+	require.True(t, got.IsInterleaved())
+	require.Equal(t, os.FileMode(0444), got.Permissions())
+
+	_, ok = UnmarshalXA([]byte("RR\x05\x01\x81"))
+	require.False(t, ok)
+}
@@ -13,7 +13,13 @@ type FileExtent struct {
 	Joliet         bool   `json:"joliet"`
 	LocationOfFile uint32 `json:"location_of_file"`
 	SizeOfFile     uint32 `json:"size_of_file"`
-	Reader         io.ReaderAt
+	// FileUnitSize and InterleaveGapSize are the unit and gap sizes in logical blocks of a file recorded in
+	// interleaved mode, zero otherwise
+	FileUnitSize      uint8 `json:"file_unit_size"`
+	InterleaveGapSize uint8 `json:"interleave_gap_size"`
+	// LogicalBlockSize is the logical block size of the volume, zero means ISO9660_SECTOR_SIZE
+	LogicalBlockSize uint16 `json:"logical_block_size"`
+	Reader           io.ReaderAt
 }
 
 func (f FileExtent) Type() string {
@@ -29,18 +35,32 @@ func (f FileExtent) Description() string {
 }
 
 func (f FileExtent) Properties() map[string]interface{} {
-	return map[string]interface{}{
+	properties := map[string]interface{}{
 		"LocationOfFile": f.LocationOfFile,
 		"SizeOfFile":     f.SizeOfFile,
 	}
+	if f.FileUnitSize > 0 {
+		properties["FileUnitSize"] = f.FileUnitSize
+		properties["InterleaveGapSize"] = f.InterleaveGapSize
+	}
+	return properties
 }
 
 func (f FileExtent) Offset() int64 {
-	return int64(f.LocationOfFile * consts.ISO9660_SECTOR_SIZE)
+	return int64(f.LocationOfFile) * f.blockSize()
 }
 
+// Size returns the number of bytes spanned by the extent, including the gaps of an interleaved file.
 func (f FileExtent) Size() int {
-	return int(f.SizeOfFile)
+	return int(InterleavedSize(int64(f.SizeOfFile), f.FileUnitSize, f.InterleaveGapSize, int(f.blockSize())))
+}
+
+// blockSize returns the logical block size of the volume in bytes.
+func (f FileExtent) blockSize() int64 {
+	if f.LogicalBlockSize == 0 {
+		return consts.ISO9660_SECTOR_SIZE
+	}
+	return int64(f.LogicalBlockSize)
 }
 
 func (f FileExtent) GetObjects() []info.ImageObject {
@@ -48,8 +68,8 @@ func (f FileExtent) GetObjects() []info.ImageObject {
 }
 
 func (f FileExtent) Marshal() ([]byte, error) {
-	// Allocate a buffer of the file's size
-	buf := make([]byte, f.SizeOfFile)
+	// Allocate a buffer of the extent's size, interleaved files are copied as recorded including their gaps
+	buf := make([]byte, f.Size())
 
 	// Read from the Reader at the specified offset
 	n, err := f.Reader.ReadAt(buf, f.Offset())
@@ -58,8 +78,8 @@ func (f FileExtent) Marshal() ([]byte, error) {
 	}
 
 	// Ensure we read the expected number of bytes
-	if uint32(n) != f.SizeOfFile {
-		return nil, fmt.Errorf("unexpected read size for %s: got %d, expected %d", f.FileIdentifier, n, f.SizeOfFile)
+	if n != len(buf) {
+		return nil, fmt.Errorf("unexpected read size for %s: got %d, expected %d", f.FileIdentifier, n, len(buf))
 	}
 
 	return buf, nil
@@ -0,0 +1,69 @@
+package extent
+
+import (
+	"errors"
+	"io"
+)
+
+// InterleavedReader is an io.ReaderAt over the data of a File Section recorded in interleaved mode. The extent holds
+// File Units of unitSize logical blocks, each followed by an Interleave Gap of gapSize logical blocks that belongs to
+// other data and is skipped.
+type InterleavedReader struct {
+	reader   io.ReaderAt
+	start    int64
+	size     int64
+	unitSize int64
+	gapSize  int64
+}
+
+// NewInterleavedReader returns a reader for size bytes of interleaved data starting at byte offset start of the reader.
+func NewInterleavedReader(r io.ReaderAt, start, size int64, unitSize, gapSize uint8, blockSize int) *InterleavedReader {
+	return &InterleavedReader{
+		reader:   r,
+		start:    start,
+		size:     size,
+		unitSize: int64(unitSize) * int64(blockSize),
+		gapSize:  int64(gapSize) * int64(blockSize),
+	}
+}
+
+// InterleavedSize returns the number of bytes spanned by size bytes of data recorded in file units of unitSize logical
+// blocks separated by gaps of gapSize logical blocks. The gap after the last file unit is not counted.
+func InterleavedSize(size int64, unitSize, gapSize uint8, blockSize int) int64 {
+	unit := int64(unitSize) * int64(blockSize)
+	if unit == 0 || size == 0 {
+		return size
+	}
+	units := (size - 1) / unit
+	return size + units*int64(gapSize)*int64(blockSize)
+}
+
+// Size returns the size of the file data.
+func (r *InterleavedReader) Size() int64 {
+	return r.size
+}
+
+func (r *InterleavedReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("interleave: negative offset")
+	}
+
+	n := 0
+	for n < len(p) && off < r.size {
+		// Map the offset to its file unit, a read never crosses a gap
+		unit, within := off/r.unitSize, off%r.unitSize
+		position := r.start + unit*(r.unitSize+r.gapSize) + within
+		chunk := min(int64(len(p)-n), r.unitSize-within, r.size-off)
+
+		m, err := r.reader.ReadAt(p[n:n+int(chunk)], position)
+		n += m
+		off += int64(m)
+		if err != nil && !(err == io.EOF && int64(m) == chunk) {
+			return n, err
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
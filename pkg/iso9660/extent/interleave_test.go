@@ -0,0 +1,34 @@
+package extent
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterleavedReader(t *testing.T) {
+	const blockSize = 4
+	// Two block file units separated by one block gaps, the data starts one block into the image
+	image := []byte("XXXX" + "abcdefgh" + "----" + "ijklmnop" + "----" + "qr")
+	want := []byte("abcdefghijklmnopqr")
+
+	r := NewInterleavedReader(bytes.NewReader(image), blockSize, int64(len(want)), 2, 1, blockSize)
+	got, err := io.ReadAll(io.NewSectionReader(r, 0, r.Size()))
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	// Reads starting inside a unit and spanning a gap
+	buf := make([]byte, 6)
+	n, err := r.ReadAt(buf, 5)
+	require.NoError(t, err)
+	require.Equal(t, "fghijk", string(buf[:n]))
+
+	n, err = r.ReadAt(buf, 16)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, "qr", string(buf[:n]))
+
+	require.Equal(t, int64(len(image)-blockSize), InterleavedSize(int64(len(want)), 2, 1, blockSize))
+	require.Equal(t, int64(len(want)), InterleavedSize(int64(len(want)), 0, 0, blockSize))
+}
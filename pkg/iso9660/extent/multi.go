@@ -0,0 +1,56 @@
+package extent
+
+import (
+	"errors"
+	"io"
+)
+
+// MultiExtentReader is an io.ReaderAt over the data of a file recorded in several extents, the File Sections of the
+// extents are read one after the other as a single stream.
+type MultiExtentReader struct {
+	sections []*io.SectionReader
+	size     int64
+}
+
+// NewMultiExtentReader returns a reader over the concatenated data of the given File Sections.
+func NewMultiExtentReader(sections ...*io.SectionReader) *MultiExtentReader {
+	r := &MultiExtentReader{sections: sections}
+	for _, section := range sections {
+		r.size += section.Size()
+	}
+	return r
+}
+
+// Size returns the size of the file data.
+func (r *MultiExtentReader) Size() int64 {
+	return r.size
+}
+
+func (r *MultiExtentReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("multi-extent: negative offset")
+	}
+
+	n := 0
+	for _, section := range r.sections {
+		if n == len(p) {
+			break
+		}
+		if off >= section.Size() {
+			off -= section.Size()
+			continue
+		}
+
+		chunk := min(int64(len(p)-n), section.Size()-off)
+		m, err := section.ReadAt(p[n:n+int(chunk)], off)
+		n += m
+		if err != nil && !(err == io.EOF && int64(m) == chunk) {
+			return n, err
+		}
+		off = 0
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -1,16 +1,26 @@
 package iso9660
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"github.com/rstms/iso-kit/pkg/compressed"
 	"github.com/rstms/iso-kit/pkg/consts"
 	"github.com/rstms/iso-kit/pkg/filesystem"
+	"github.com/rstms/iso-kit/pkg/hfs"
 	"github.com/rstms/iso-kit/pkg/iso9660/boot"
 	"github.com/rstms/iso-kit/pkg/iso9660/descriptor"
+	"github.com/rstms/iso-kit/pkg/iso9660/directory"
+	"github.com/rstms/iso-kit/pkg/iso9660/encoding"
+	"github.com/rstms/iso-kit/pkg/iso9660/extensions"
 	"github.com/rstms/iso-kit/pkg/iso9660/info"
 	"github.com/rstms/iso-kit/pkg/iso9660/parser"
 	"github.com/rstms/iso-kit/pkg/iso9660/pathtable"
+	"github.com/rstms/iso-kit/pkg/iso9660/sparse"
 	"github.com/rstms/iso-kit/pkg/iso9660/systemarea"
+	"github.com/rstms/iso-kit/pkg/iso9660/validation"
+	"github.com/rstms/iso-kit/pkg/iso9660/xattr"
+	"github.com/rstms/iso-kit/pkg/iso9660/zisofs"
 	"github.com/rstms/iso-kit/pkg/logging"
 	"github.com/rstms/iso-kit/pkg/option"
 	"github.com/rstms/iso-kit/pkg/version"
@@ -35,6 +45,7 @@ func Open(isoReader io.ReaderAt, opts ...option.OpenOption) (*ISO9660, error) {
 		StripVersionInfo:           true,
 		RockRidgeEnabled:           true,
 		ElToritoEnabled:            true,
+		ZisofsEnabled:              true,
 		PreferJoliet:               false,
 		BootFileExtractLocation:    "[BOOT]",
 		ExtractionProgressCallback: emptyCallback,
@@ -78,6 +89,13 @@ func Open(isoReader io.ReaderAt, opts ...option.OpenOption) (*ISO9660, error) {
 		return nil, err
 	}
 
+	// Extents of a multi-volume set may be recorded on any of its volumes
+	volumes, err := readVolumeSet(pvd, openOptions)
+	if err != nil {
+		return nil, err
+	}
+	p.SetVolumeSet(volumes)
+
 	// Read the supplementary volume descriptors
 	svds, err := p.GetSupplementaryVolumeDescriptors()
 	if err != nil {
@@ -102,6 +120,12 @@ func Open(isoReader io.ReaderAt, opts ...option.OpenOption) (*ISO9660, error) {
 		return nil, err
 	}
 
+	// The root directory record in the PVD has no room for system use entries, Rock Ridge is announced by the SP and
+	// ER entries of the root directory's "." record
+	if len(pvd.DirectoryRecords) > 0 && pvd.DirectoryRecords[0].FileIdentifier == "\x00" {
+		pvd.RootDirectoryRecord.RockRidge = pvd.DirectoryRecords[0].RockRidge
+	}
+
 	// Handle walking the svd directory records
 	for _, svd := range svds {
 		svd.DirectoryRecords, err = p.WalkDirectoryRecords(svd.RootDirectoryRecord)
@@ -112,13 +136,26 @@ func Open(isoReader io.ReaderAt, opts ...option.OpenOption) (*ISO9660, error) {
 
 	// Handle processing volume descriptor
 	var filesystemEntries []*filesystem.FileSystemEntry
-	if openOptions.PreferJoliet && len(svds) > 0 {
-		// Open the Joliet filesystem
-		filesystemEntries, err = p.BuildFileSystemEntries(svds[0].RootDirectoryRecord, false)
+	if svd := selectSupplementary(svds, openOptions); svd != nil {
+		// Open the Joliet or enhanced filesystem
+		filesystemEntries, err = p.BuildFileSystemEntries(svd.RootDirectoryRecord, false)
 	} else {
 		filesystemEntries, err = p.BuildFileSystemEntries(pvd.RootDirectoryRecord, openOptions.RockRidgeEnabled)
 	}
 
+	// Hybrid Mac/PC discs describe an HFS or HFS+ volume with an Apple Partition Map in the system area
+	var hfsVolume *hfs.Volume
+	if partition, err := hfs.FindHFSPartition(isoReader); err == nil {
+		hfsVolume, err = hfs.Open(isoReader, partition.Offset)
+		if err != nil {
+			openOptions.Logger.Error(err, "Failed to open HFS partition", "offset", partition.Offset)
+			hfsVolume = nil
+		}
+	}
+	if openOptions.PreferHFS && hfsVolume != nil {
+		filesystemEntries = hfsVolume.FileSystemEntries()
+	}
+
 	// Handle the path tables
 	tables, err := p.GetPathTables(pvd)
 	if err != nil {
@@ -142,12 +179,14 @@ func Open(isoReader io.ReaderAt, opts ...option.OpenOption) (*ISO9660, error) {
 
 	iso := &ISO9660{
 		isoReader:           isoReader,
+		volumes:             volumes,
 		openOptions:         openOptions, //TODO: Work on making composite options that limit users ability to create based on context but have a single set behind the scenes
 		systemArea:          sa,
 		volumeDescriptorSet: volumeDescSet,
 		pathTables:          tables,
 		filesystemEntries:   filesystemEntries,
 		elTorito:            et,
+		hfsVolume:           hfsVolume,
 		logger:              openOptions.Logger,
 		isPacked:            true,
 		pendingFiles:        make(map[string][]byte),
@@ -156,22 +195,102 @@ func Open(isoReader io.ReaderAt, opts ...option.OpenOption) (*ISO9660, error) {
 	return iso, nil
 }
 
+// OpenVolumeSet opens a multi-volume set from its volumes given in any order. The directory hierarchy is read from the
+// volume with the highest volume sequence number, which describes the files of all volumes before it.
+func OpenVolumeSet(volumes []io.ReaderAt, opts ...option.OpenOption) (*ISO9660, error) {
+	if len(volumes) == 0 {
+		return nil, errors.New("no volumes given")
+	}
+
+	last, lastSequence := 0, uint16(0)
+	for i, volume := range volumes {
+		pvd, err := parser.NewParser(volume, &option.OpenOptions{Logger: logging.DefaultLogger()}).GetPrimaryVolumeDescriptor()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read primary volume descriptor of volume %d: %w", i, err)
+		}
+		if pvd.VolumeSequenceNumber >= lastSequence {
+			last, lastSequence = i, pvd.VolumeSequenceNumber
+		}
+	}
+
+	others := append(append([]io.ReaderAt{}, volumes[:last]...), volumes[last+1:]...)
+	return Open(volumes[last], append(opts, option.WithVolumeSet(others...))...)
+}
+
+// readVolumeSet maps the volumes given with WithVolumeSet to their volume sequence numbers.
+func readVolumeSet(pvd *descriptor.PrimaryVolumeDescriptor, openOptions *option.OpenOptions) (map[uint16]io.ReaderAt, error) {
+	if len(openOptions.VolumeSet) == 0 {
+		return nil, nil
+	}
+
+	volumes := make(map[uint16]io.ReaderAt)
+	for _, volume := range openOptions.VolumeSet {
+		member, err := parser.NewParser(volume, openOptions).GetPrimaryVolumeDescriptor()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read primary volume descriptor of volume set member: %w", err)
+		}
+		if member.VolumeSetIdentifier() != pvd.VolumeSetIdentifier() {
+			return nil, fmt.Errorf("volume %d belongs to volume set %q, not %q", member.VolumeSequenceNumber, member.VolumeSetIdentifier(), pvd.VolumeSetIdentifier())
+		}
+		if _, exists := volumes[member.VolumeSequenceNumber]; exists || member.VolumeSequenceNumber == pvd.VolumeSequenceNumber {
+			return nil, fmt.Errorf("duplicate volume sequence number %d in volume set", member.VolumeSequenceNumber)
+		}
+		if member.VolumeSequenceNumber > pvd.VolumeSetSize {
+			openOptions.Logger.Info("Volume sequence number exceeds the volume set size", "sequence", member.VolumeSequenceNumber, "size", pvd.VolumeSetSize)
+		}
+		volumes[member.VolumeSequenceNumber] = volume
+	}
+	return volumes, nil
+}
+
 func Create(name string, opts ...option.CreateOption) (*ISO9660, error) {
 	// Set default create options
 	createOptions := &option.CreateOptions{
-		Preparer: fmt.Sprintf("iso-kit %s %s (%s) %s", version.Version(), version.Revision(), version.Branch(), version.Date()),
+		Preparer:        fmt.Sprintf("iso-kit %s %s (%s) %s", version.Version(), version.Revision(), version.Branch(), version.Date()),
+		ZisofsBlockSize:  1 << zisofs.DEFAULT_LOG2_BLOCK_SIZE,
+		LogicalBlockSize: consts.ISO9660_SECTOR_SIZE,
 	}
 
 	for _, opt := range opts {
 		opt(createOptions)
 	}
+	if createOptions.Logger == nil {
+		createOptions.Logger = logging.DefaultLogger()
+	}
+
+	if createOptions.ZisofsEnabled {
+		if _, err := zisofs.Log2BlockSize(createOptions.ZisofsBlockSize); err != nil {
+			return nil, err
+		}
+		for _, pattern := range createOptions.ZisofsPatterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid zisofs pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	if createOptions.MaxVolumeSize < 0 || (createOptions.MaxVolumeSize > 0 && createOptions.MaxVolumeSize < 64*consts.ISO9660_SECTOR_SIZE) {
+		return nil, fmt.Errorf("invalid maximum volume size %d", createOptions.MaxVolumeSize)
+	}
+
+	if err := validation.ValidateLogicalBlockSize(createOptions.LogicalBlockSize); err != nil {
+		return nil, err
+	}
+	// Logical block numbers of the volume are counted in logical blocks, sectors hold one or more of them
+	blocksPerSector := uint32(consts.ISO9660_SECTOR_SIZE / createOptions.LogicalBlockSize)
+
+	switch createOptions.Compression {
+	case compressed.FORMAT_NONE, compressed.FORMAT_CSO, compressed.FORMAT_ZSTD:
+	default:
+		return nil, fmt.Errorf("unsupported output compression %q", createOptions.Compression)
+	}
 
 	// Create a root directory record
 	rootDir := &directory.DirectoryRecord{
 		FileIdentifier:                "\x00",
 		LengthOfDirectoryRecord:       34, // Standard size for root directory
 		ExtendedAttributeRecordLength: 0,
-		LocationOfExtent:              18, // Usually starts at sector 18
+		LocationOfExtent:              18 * blocksPerSector,       // Usually starts at sector 18
 		DataLength:                    consts.ISO9660_SECTOR_SIZE, // One sector for root directory
 		RecordingDateAndTime:          time.Now(),
 		FileFlags:                     directory.FileFlags{Directory: true},
@@ -191,10 +310,10 @@ func Create(name string, opts ...option.CreateOption) (*ISO9660, error) {
 		PrimaryVolumeDescriptorBody: descriptor.PrimaryVolumeDescriptorBody{
 			SystemIdentifier:              "",
 			VolumeIdentifier:              name,
-			VolumeSpaceSize:               19, // Initially small - will grow as files are added
+			VolumeSpaceSize:               19 * blocksPerSector, // Initially small - will grow as files are added
 			VolumeSetSize:                 1,
 			VolumeSequenceNumber:          1,
-			LogicalBlockSize:              consts.ISO9660_SECTOR_SIZE,
+			LogicalBlockSize:              uint16(createOptions.LogicalBlockSize),
 			RootDirectoryRecord:           rootDir,
 			VolumeSetIdentifier:           "",
 			PublisherIdentifier:           "",
@@ -224,7 +343,8 @@ func Create(name string, opts ...option.CreateOption) (*ISO9660, error) {
 				VolumeFlags:                   0,
 				SystemIdentifier:              "",
 				VolumeIdentifier:              name,
-				VolumeSpaceSize:               [8]byte{19, 0, 0, 0, 0, 0, 0, 19}, // BothByteOrder
+				VolumeSpaceSize:               encoding.MarshalBothByteOrders32(19 * blocksPerSector),
+				LogicalBlockSize:              encoding.MarshalBothByteOrders16(uint16(createOptions.LogicalBlockSize)),
 				RootDirectoryRecord:           rootDir,
 				VolumeSetIdentifier:           "",
 				PublisherIdentifier:           "",
@@ -242,6 +362,31 @@ func Create(name string, opts ...option.CreateOption) (*ISO9660, error) {
 		svds = append(svds, svd)
 	}
 
+	// The ISO 9660:1999 enhanced volume descriptor records a directory hierarchy with identifiers of up to 207 bytes
+	// of any value, without version suffixes and without a depth limit
+	if createOptions.EnhancedVolumeDescriptor {
+		svd := &descriptor.SupplementaryVolumeDescriptor{
+			VolumeDescriptorHeader: descriptor.VolumeDescriptorHeader{
+				VolumeDescriptorType:    descriptor.TYPE_SUPPLEMENTARY_DESCRIPTOR,
+				StandardIdentifier:      consts.ISO9660_STD_IDENTIFIER,
+				VolumeDescriptorVersion: consts.ISO9660_ENHANCED_VOLUME_DESC_VERSION,
+			},
+			SupplementaryVolumeDescriptorBody: descriptor.SupplementaryVolumeDescriptorBody{
+				VolumeIdentifier:              name,
+				VolumeSpaceSize:               encoding.MarshalBothByteOrders32(19 * blocksPerSector),
+				LogicalBlockSize:              encoding.MarshalBothByteOrders16(uint16(createOptions.LogicalBlockSize)),
+				RootDirectoryRecord:           rootDir,
+				DataPreparerIdentifier:        createOptions.Preparer,
+				VolumeCreationDateAndTime:     time.Now(),
+				VolumeModificationDateAndTime: time.Now(),
+				VolumeExpirationDateAndTime:   time.Time{}, // No expiration
+				VolumeEffectiveDateAndTime:    time.Now(),
+				FileStructureVersion:          consts.ISO9660_ENHANCED_FILE_STRUCTURE_VERSION,
+			},
+		}
+		svds = append(svds, svd)
+	}
+
 	// Create volume descriptor set
 	volumeDescSet := &descriptor.VolumeDescriptorSet{
 		Primary:       pvd,
@@ -295,28 +440,34 @@ type ISO9660 struct {
 	pathTables []*pathtable.PathTable
 	// ElTorito Boot Record
 	elTorito *boot.ElTorito
+	// HFS volume of hybrid Mac/PC discs
+	hfsVolume *hfs.Volume
+	// Other volumes of a multi-volume set by volume sequence number
+	volumes map[uint16]io.ReaderAt
 	// FileSystemEntries
 	filesystemEntries []*filesystem.FileSystemEntry
 	// Logger
 	logger *logging.Logger
 	// isPacked represents if the ISO9660 filesystem is packed and ready to write to disk
 	isPacked bool
+	// layout holds the locations assigned by Pack, nil for images that were opened and not modified
+	layout *imageLayout
 	// pendingFiles stores data for newly added files that haven't been written to disk yet
 	pendingFiles map[string][]byte
 }
 
 // GetVolumeID returns the volume identifier of the ISO9660 filesystem.
 func (iso *ISO9660) GetVolumeID() string {
-	if iso.openOptions.PreferJoliet && iso.volumeDescriptorSet.Supplementary != nil {
-		return iso.volumeDescriptorSet.Supplementary[0].VolumeIdentifier()
+	if svd := iso.supplementary(); svd != nil {
+		return svd.VolumeIdentifier()
 	}
 	return iso.volumeDescriptorSet.Primary.VolumeIdentifier()
 }
 
 // GetSystemID returns the system identifier of the ISO9660 filesystem.
 func (iso *ISO9660) GetSystemID() string {
-	if iso.openOptions.PreferJoliet && iso.volumeDescriptorSet.Supplementary != nil {
-		return iso.volumeDescriptorSet.Supplementary[0].SystemIdentifier()
+	if svd := iso.supplementary(); svd != nil {
+		return svd.SystemIdentifier()
 	}
 	return iso.volumeDescriptorSet.Primary.SystemIdentifier()
 }
@@ -326,90 +477,100 @@ func (iso *ISO9660) GetVolumeSize() uint32 {
 	return iso.volumeDescriptorSet.Primary.VolumeSpaceSize
 }
 
+// GetVolumeSetSize returns the number of volumes in the volume set of the ISO9660 filesystem.
+func (iso *ISO9660) GetVolumeSetSize() uint16 {
+	return iso.volumeDescriptorSet.Primary.VolumeSetSize
+}
+
+// GetVolumeSequenceNumber returns the sequence number of this volume within its volume set.
+func (iso *ISO9660) GetVolumeSequenceNumber() uint16 {
+	return iso.volumeDescriptorSet.Primary.VolumeSequenceNumber
+}
+
 // GetVolumeSetID returns the volume set identifier of the ISO9660 filesystem.
 func (iso *ISO9660) GetVolumeSetID() string {
-	if iso.openOptions.PreferJoliet && iso.volumeDescriptorSet.Supplementary != nil {
-		return iso.volumeDescriptorSet.Supplementary[0].VolumeSetIdentifier()
+	if svd := iso.supplementary(); svd != nil {
+		return svd.VolumeSetIdentifier()
 	}
 	return iso.volumeDescriptorSet.Primary.VolumeSetIdentifier()
 }
 
 // GetPublisherID returns the publisher identifier of the ISO9660 filesystem.
 func (iso *ISO9660) GetPublisherID() string {
-	if iso.openOptions.PreferJoliet && iso.volumeDescriptorSet.Supplementary != nil {
-		return iso.volumeDescriptorSet.Supplementary[0].PublisherIdentifier()
+	if svd := iso.supplementary(); svd != nil {
+		return svd.PublisherIdentifier()
 	}
 	return iso.volumeDescriptorSet.Primary.PublisherIdentifier()
 }
 
 // GetDataPreparerID returns the data preparer identifier of the ISO9660 filesystem.
 func (iso *ISO9660) GetDataPreparerID() string {
-	if iso.openOptions.PreferJoliet && iso.volumeDescriptorSet.Supplementary != nil {
-		return iso.volumeDescriptorSet.Supplementary[0].DataPreparerIdentifier()
+	if svd := iso.supplementary(); svd != nil {
+		return svd.DataPreparerIdentifier()
 	}
 	return iso.volumeDescriptorSet.Primary.DataPreparerIdentifier()
 }
 
 // GetApplicationID returns the application identifier of the ISO9660 filesystem.
 func (iso *ISO9660) GetApplicationID() string {
-	if iso.openOptions.PreferJoliet && iso.volumeDescriptorSet.Supplementary != nil {
-		return iso.volumeDescriptorSet.Supplementary[0].ApplicationIdentifier()
+	if svd := iso.supplementary(); svd != nil {
+		return svd.ApplicationIdentifier()
 	}
 	return iso.volumeDescriptorSet.Primary.ApplicationIdentifier()
 }
 
 // GetCopyrightID returns the copyright identifier of the ISO9660 filesystem.
 func (iso *ISO9660) GetCopyrightID() string {
-	if iso.openOptions.PreferJoliet && iso.volumeDescriptorSet.Supplementary != nil {
-		return iso.volumeDescriptorSet.Supplementary[0].CopyrightFileIdentifier()
+	if svd := iso.supplementary(); svd != nil {
+		return svd.CopyrightFileIdentifier()
 	}
 	return iso.volumeDescriptorSet.Primary.CopyrightFileIdentifier()
 }
 
 // GetAbstractID returns the abstract identifier of the ISO9660 filesystem.
 func (iso *ISO9660) GetAbstractID() string {
-	if iso.openOptions.PreferJoliet && iso.volumeDescriptorSet.Supplementary != nil {
-		return iso.volumeDescriptorSet.Supplementary[0].AbstractFileIdentifier()
+	if svd := iso.supplementary(); svd != nil {
+		return svd.AbstractFileIdentifier()
 	}
 	return iso.volumeDescriptorSet.Primary.AbstractFileIdentifier()
 }
 
 // GetBibliographicID returns the bibliographic identifier of the ISO9660 filesystem.
 func (iso *ISO9660) GetBibliographicID() string {
-	if iso.openOptions.PreferJoliet && iso.volumeDescriptorSet.Supplementary != nil {
-		return iso.volumeDescriptorSet.Supplementary[0].BibliographicFileIdentifier()
+	if svd := iso.supplementary(); svd != nil {
+		return svd.BibliographicFileIdentifier()
 	}
 	return iso.volumeDescriptorSet.Primary.BibliographicFileIdentifier()
 }
 
 // GetCreationDateTime returns the creation date and time of the ISO9660 filesystem.
 func (iso *ISO9660) GetCreationDateTime() time.Time {
-	if iso.openOptions.PreferJoliet && iso.volumeDescriptorSet.Supplementary != nil {
-		return iso.volumeDescriptorSet.Supplementary[0].VolumeCreationDateTime()
+	if svd := iso.supplementary(); svd != nil {
+		return svd.VolumeCreationDateTime()
 	}
 	return iso.volumeDescriptorSet.Primary.VolumeCreationDateTime()
 }
 
 // GetModificationDateTime returns the modification date and time of the ISO9660 filesystem.
 func (iso *ISO9660) GetModificationDateTime() time.Time {
-	if iso.openOptions.PreferJoliet && iso.volumeDescriptorSet.Supplementary != nil {
-		return iso.volumeDescriptorSet.Supplementary[0].VolumeModificationDateTime()
+	if svd := iso.supplementary(); svd != nil {
+		return svd.VolumeModificationDateTime()
 	}
 	return iso.volumeDescriptorSet.Primary.VolumeModificationDateTime()
 }
 
 // GetExpirationDateTime returns the expiration date and time of the ISO9660 filesystem.
 func (iso *ISO9660) GetExpirationDateTime() time.Time {
-	if iso.openOptions.PreferJoliet && iso.volumeDescriptorSet.Supplementary != nil {
-		return iso.volumeDescriptorSet.Supplementary[0].VolumeExpirationDateTime()
+	if svd := iso.supplementary(); svd != nil {
+		return svd.VolumeExpirationDateTime()
 	}
 	return iso.volumeDescriptorSet.Primary.VolumeExpirationDateTime()
 }
 
 // GetEffectiveDateTime returns the effective date and time of the ISO9660 filesystem.
 func (iso *ISO9660) GetEffectiveDateTime() time.Time {
-	if iso.openOptions.PreferJoliet && iso.volumeDescriptorSet.Supplementary != nil {
-		return iso.volumeDescriptorSet.Supplementary[0].VolumeEffectiveDateTime()
+	if svd := iso.supplementary(); svd != nil {
+		return svd.VolumeEffectiveDateTime()
 	}
 	return iso.volumeDescriptorSet.Primary.VolumeEffectiveDateTime()
 }
@@ -424,11 +585,77 @@ func (iso *ISO9660) HasJoliet() bool {
 	return false
 }
 
+// HasEnhanced returns true if the ISO9660 filesystem has an ISO 9660:1999 enhanced volume descriptor.
+func (iso *ISO9660) HasEnhanced() bool {
+	for _, svd := range iso.volumeDescriptorSet.Supplementary {
+		if svd.IsEnhanced() {
+			return true
+		}
+	}
+	return false
+}
+
+// supplementary returns the supplementary volume descriptor selected by the open options, nil when the primary volume
+// descriptor is used.
+func (iso *ISO9660) supplementary() *descriptor.SupplementaryVolumeDescriptor {
+	if iso.openOptions == nil {
+		return nil
+	}
+	return selectSupplementary(iso.volumeDescriptorSet.Supplementary, iso.openOptions)
+}
+
+// selectSupplementary returns the enhanced volume descriptor when PreferEnhanced is set and the Joliet volume
+// descriptor when PreferJoliet is set, the enhanced descriptor takes precedence when both are.
+func selectSupplementary(svds []*descriptor.SupplementaryVolumeDescriptor, opts *option.OpenOptions) *descriptor.SupplementaryVolumeDescriptor {
+	if opts.PreferEnhanced {
+		for _, svd := range svds {
+			if svd.IsEnhanced() {
+				return svd
+			}
+		}
+	}
+	if opts.PreferJoliet {
+		for _, svd := range svds {
+			if svd.IsJoliet() {
+				return svd
+			}
+		}
+	}
+	return nil
+}
+
 // HasRockRidge returns true if the ISO9660 filesystem has Rock Ridge extensions.
 func (iso *ISO9660) HasRockRidge() bool {
 	return iso.volumeDescriptorSet.Primary.HasRockRidge()
 }
 
+// RockRidgeInfo returns the Rock Ridge version details of the ISO9660 filesystem, nil if Rock Ridge is not present.
+func (iso *ISO9660) RockRidgeInfo() *extensions.RockRidgeInfo {
+	root := iso.volumeDescriptorSet.Primary.RootDirectoryRecord
+	if root == nil || root.RockRidge == nil {
+		return nil
+	}
+	return root.RockRidge.RockRidgeInfo()
+}
+
+// RockRidgeVersion returns the detected Rock Ridge version ("1.10" or "1.12"), empty if Rock Ridge is not present.
+func (iso *ISO9660) RockRidgeVersion() string {
+	if info := iso.RockRidgeInfo(); info != nil {
+		return info.Version
+	}
+	return ""
+}
+
+// HasHFS returns true if the image is a hybrid disc with an HFS or HFS+ volume.
+func (iso *ISO9660) HasHFS() bool {
+	return iso.hfsVolume != nil
+}
+
+// HFSVolume returns the HFS or HFS+ volume of a hybrid disc, nil if there is none.
+func (iso *ISO9660) HFSVolume() *hfs.Volume {
+	return iso.hfsVolume
+}
+
 // HasElTorito returns true if the ISO9660 filesystem has El Torito boot extensions.
 func (iso *ISO9660) HasElTorito() bool {
 	return iso.elTorito != nil
@@ -436,8 +663,8 @@ func (iso *ISO9660) HasElTorito() bool {
 
 // RootDirectoryLocation returns the location of the root directory in the ISO9660 filesystem.
 func (iso *ISO9660) RootDirectoryLocation() uint32 {
-	if iso.openOptions.PreferJoliet && iso.volumeDescriptorSet.Supplementary != nil {
-		return iso.volumeDescriptorSet.Supplementary[0].RootDirectoryRecord.LocationOfExtent
+	if svd := iso.supplementary(); svd != nil {
+		return svd.RootDirectoryRecord.LocationOfExtent
 	}
 	return iso.volumeDescriptorSet.Primary.RootDirectoryRecord.LocationOfExtent
 }
@@ -475,16 +702,9 @@ func (iso *ISO9660) ReadFile(path string) ([]byte, error) {
 	// Normalize the path by removing leading slash
 	normalizedPath := strings.TrimPrefix(path, "/")
 	
-	// Check if it's a pending file first
-	if iso.pendingFiles != nil {
-		if data, exists := iso.pendingFiles[normalizedPath]; exists {
-			return data, nil
-		}
-	}
-	
 	// Find the file in our filesystem entries
 	for _, entry := range iso.filesystemEntries {
-		if entry.FullPath == normalizedPath && !entry.IsDir {
+		if strings.TrimPrefix(entry.FullPath, "/") == normalizedPath && !entry.IsDir {
 			return entry.GetBytes()
 		}
 	}
@@ -492,14 +712,39 @@ func (iso *ISO9660) ReadFile(path string) ([]byte, error) {
 	return nil, fmt.Errorf("file not found: %s", path)
 }
 
+// OpenFile returns an io.ReadSeekCloser over the contents of the file at path. Unlike ReadFile the file is streamed
+// from the image, including every extent of multi-extent files, instead of being read into memory.
+func (iso *ISO9660) OpenFile(path string) (io.ReadSeekCloser, error) {
+	normalizedPath := strings.TrimPrefix(path, "/")
+	for _, entry := range iso.filesystemEntries {
+		if strings.TrimPrefix(entry.FullPath, "/") == normalizedPath && !entry.IsDir {
+			return entry.Open()
+		}
+	}
+
+	return nil, fmt.Errorf("file not found: %s", path)
+}
+
+// FS returns an io/fs view of the files and directories in the ISO9660 filesystem, so the image can be used with
+// fs.WalkDir, http.FS and fs.ReadLink.
+func (iso *ISO9660) FS() (*filesystem.FS, error) {
+	return filesystem.NewFS(iso.filesystemEntries), nil
+}
+
 func (iso *ISO9660) AddFile(path string, data []byte) error {
+	_, err := iso.addFile(path, data, 0644)
+	return err
+}
+
+// addFile adds a pending file and returns its entry so callers can attach further metadata.
+func (iso *ISO9660) addFile(path string, data []byte, mode os.FileMode) (*filesystem.FileSystemEntry, error) {
 	// Normalize the path by removing leading slash
 	normalizedPath := strings.TrimPrefix(path, "/")
 	
 	// Check if file already exists
 	for _, entry := range iso.filesystemEntries {
 		if entry.FullPath == normalizedPath {
-			return fmt.Errorf("file already exists: %s", path)
+			return nil, fmt.Errorf("file already exists: %s", path)
 		}
 	}
 	
@@ -508,23 +753,79 @@ func (iso *ISO9660) AddFile(path string, data []byte) error {
 		iso.pendingFiles = make(map[string][]byte)
 	}
 	
-	// Store the file data
-	iso.pendingFiles[normalizedPath] = data
-	
+	// Identifiers of the enhanced directory hierarchy are limited in length only
+	if iso.createOptions != nil && iso.createOptions.EnhancedVolumeDescriptor {
+		for _, component := range strings.Split(normalizedPath, "/") {
+			if len(component) > consts.ISO9660_ENHANCED_MAX_FILE_IDENTIFIER_SIZE {
+				return nil, fmt.Errorf("identifier %q exceeds %d bytes", component, consts.ISO9660_ENHANCED_MAX_FILE_IDENTIFIER_SIZE)
+			}
+		}
+	}
+
 	// Create a new file system entry
 	fileName := filepath.Base(normalizedPath)
 	
 	// Create directory record for the new file
 	record := &directory.DirectoryRecord{
-		DataLength:              uint32(len(data)),
 		RecordingDateAndTime:    time.Now(),
 		FileFlags:               directory.FileFlags{}, // Regular file
 		FileIdentifier:          fileName,
 		LocationOfExtent:        0, // Will be set during packing/save
 		ExtendedAttributeRecordLength: 0,
 	}
-	
-	// Create filesystem entry
+
+	// The extent starts with the Extended Attribute Record when requested, the file data follows it
+	var earData []byte
+	if iso.createOptions != nil && iso.createOptions.ExtendedAttributeRecords {
+		ear, encoded, err := newExtendedAttributeRecord(mode, record.RecordingDateAndTime, iso.logicalBlockSize())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create extended attribute record for %s: %w", path, err)
+		}
+		earData = encoded
+		record.ExtendedAttributeRecord = ear
+		record.ExtendedAttributeRecordLength = uint8(len(earData) / iso.logicalBlockSize())
+		record.FileFlags.Protection = ear.Permissions.Marshal()&0x5555 != 0
+	}
+
+	// Compress the file if requested, the pending data holds what will be written to the image
+	stored, zf, err := iso.compressFile(normalizedPath, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+
+	// Record files with holes as sparse files. The block tables refer to the pending extent at location 0 and are
+	// rebased onto the location assigned to the extent when the image is packed.
+	var sf *extensions.SparseFileInfo
+	if zf == nil && iso.createOptions != nil && iso.createOptions.SparseFiles {
+		base := record.DataLocation()
+		if encoded, depth, ok := sparse.Encode(data, iso.logicalBlockSize(), base); ok {
+			stored = encoded
+			sf = &extensions.SparseFileInfo{VirtualSize: uint64(len(data)), TableDepth: depth}
+		}
+	}
+
+	if zf != nil || sf != nil {
+		record.RockRidge = &extensions.RockRidgeExtensions{Zisofs: zf, Sparse: sf}
+		if record.SystemUse, err = extensions.MarshalRockRidge(record.RockRidge); err != nil {
+			return nil, fmt.Errorf("failed to encode Rock Ridge entries for %s: %w", path, err)
+		}
+	}
+
+	// Store the file data
+	iso.pendingFiles[normalizedPath] = stored
+	record.DataLength = uint32(len(stored))
+	extentData := append(earData, stored...)
+
+	// Place the file on a volume of the volume set
+	if iso.createOptions != nil && iso.createOptions.MaxVolumeSize > 0 {
+		sequenceNumber, err := iso.assignVolume(int64(len(extentData)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to place %s: %w", path, err)
+		}
+		record.VolumeSequenceNumber = sequenceNumber
+	}
+
+	// Create filesystem entry. Until the image is saved the entry reads from the pending extent at location 0.
 	entry := filesystem.NewFileSystemEntry(
 		fileName,
 		normalizedPath,
@@ -533,12 +834,16 @@ func (iso *ISO9660) AddFile(path string, data []byte) error {
 		0, // location will be set during packing
 		nil, // uid
 		nil, // gid
-		0644, // default file mode
+		mode,
 		time.Now(), // create time
 		time.Now(), // mod time
 		record,
-		nil, // reader will be set during save
+		bytes.NewReader(extentData),
 	)
+	entry.LogicalBlockSize = uint16(iso.logicalBlockSize())
+	entry.Zisofs = zf
+	entry.Sparse = sf
+	entry.Size = uint64(len(data))
 	
 	// Add it to the filesystem entries
 	iso.filesystemEntries = append(iso.filesystemEntries, entry)
@@ -546,9 +851,197 @@ func (iso *ISO9660) AddFile(path string, data []byte) error {
 	// Mark as unpacked since we've added a new file
 	iso.isPacked = false
 	
+	return entry, nil
+}
+
+// logicalBlockSize returns the logical block size of the volume in bytes.
+func (iso *ISO9660) logicalBlockSize() int {
+	if pvd := iso.volumeDescriptorSet.Primary; pvd != nil && pvd.LogicalBlockSize != 0 {
+		return int(pvd.LogicalBlockSize)
+	}
+	return consts.ISO9660_SECTOR_SIZE
+}
+
+// assignVolume returns the sequence number of the first volume of the volume set with room for an extent of size bytes,
+// a new volume is added to the set when none has.
+func (iso *ISO9660) assignVolume(size int64) (uint16, error) {
+	const sectorSize = consts.ISO9660_SECTOR_SIZE
+	blockSize := int64(iso.logicalBlockSize())
+	blocks := func(n int64) int64 {
+		return (n + blockSize - 1) / blockSize * blockSize
+	}
+
+	// The system area and the volume descriptor set are recorded on every volume
+	descriptors := 2 + len(iso.volumeDescriptorSet.Supplementary) + len(iso.volumeDescriptorSet.Partition)
+	if iso.volumeDescriptorSet.Boot != nil {
+		descriptors++
+	}
+	capacity := iso.createOptions.MaxVolumeSize - int64(consts.ISO9660_SYSTEM_AREA_SECTORS+descriptors)*sectorSize
+	if blocks(size) > capacity {
+		return 0, fmt.Errorf("%d bytes do not fit on a volume of %d bytes", size, iso.createOptions.MaxVolumeSize)
+	}
+
+	used := make(map[uint16]int64)
+	for _, entry := range iso.filesystemEntries {
+		if record := entry.DirectoryRecord(); record != nil && record.VolumeSequenceNumber > 0 {
+			extentSize := int64(record.DataLength) + int64(record.ExtendedAttributeRecordLength)*blockSize
+			used[record.VolumeSequenceNumber] += blocks(extentSize)
+		}
+	}
+
+	pvd := iso.volumeDescriptorSet.Primary
+	sequenceNumber := uint16(1)
+	for ; sequenceNumber <= pvd.VolumeSetSize; sequenceNumber++ {
+		if used[sequenceNumber]+blocks(size) <= capacity {
+			return sequenceNumber, nil
+		}
+	}
+	if sequenceNumber == 0 {
+		return 0, errors.New("volume set exceeds 65535 volumes")
+	}
+	pvd.VolumeSetSize = sequenceNumber
+	return sequenceNumber, nil
+}
+
+// newExtendedAttributeRecord creates an Extended Attribute Record carrying the file permissions and returns it along
+// with its encoding padded to whole logical blocks of blockSize bytes.
+func newExtendedAttributeRecord(mode os.FileMode, recorded time.Time, blockSize int) (*xattr.ExtendedAttributeRecord, []byte, error) {
+	ear := &xattr.ExtendedAttributeRecord{
+		Permissions:                    xattr.NewExtendedAttrPermissions(mode),
+		FileCreationDateAndTime:        recorded,
+		FileModificationDateAndTime:    recorded,
+		ExtendedAttributeRecordVersion: 1,
+	}
+	data, err := ear.Marshal()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blocks := (len(data) + blockSize - 1) / blockSize
+	padded := make([]byte, blocks*blockSize)
+	copy(padded, data)
+	ear.ObjectSize = uint32(len(padded))
+
+	return ear, padded, nil
+}
+
+// AddPartition records a Volume Partition Descriptor for a partition of size logical blocks starting at logical block
+// location. The content of the partition is not interpreted by ISO9660, systemID identifies the system that can act
+// upon it and systemUse is recorded in the descriptor's system use area. The partition has to follow the directory
+// hierarchies and file data when the image is packed, its blocks are saved zeroed.
+func (iso *ISO9660) AddPartition(systemID, partitionID string, location, size uint32, systemUse []byte) error {
+	for _, vpd := range iso.volumeDescriptorSet.Partition {
+		if vpd.VolumePartitionIdentifier == partitionID {
+			return fmt.Errorf("partition already exists: %s", partitionID)
+		}
+	}
+
+	vpd, err := descriptor.NewVolumePartitionDescriptor(systemID, partitionID, location, size, systemUse)
+	if err != nil {
+		return fmt.Errorf("failed to create volume partition descriptor: %w", err)
+	}
+	iso.volumeDescriptorSet.Partition = append(iso.volumeDescriptorSet.Partition, vpd)
+	iso.isPacked = false
 	return nil
 }
 
+// SetExtendedAttributes replaces the extended attributes and POSIX ACL of a file or directory of the image. They are
+// written as AAIP "AL" entries in its directory records, announced by an "AAIP_0200" ER entry.
+func (iso *ISO9660) SetExtendedAttributes(path string, attrs []extensions.ExtendedAttribute, acl *extensions.ACL) error {
+	normalizedPath := strings.TrimPrefix(path, "/")
+
+	var entry *filesystem.FileSystemEntry
+	for _, e := range iso.filesystemEntries {
+		if strings.TrimPrefix(e.FullPath, "/") == normalizedPath {
+			entry = e
+			break
+		}
+	}
+	if entry == nil {
+		return fmt.Errorf("file not found: %s", path)
+	}
+
+	// The records of directories are created when the image is packed, their attributes are kept with the entry
+	if entry.IsDir {
+		if _, err := extensions.MarshalRockRidge(&extensions.RockRidgeExtensions{ExtendedAttributes: attrs, ACL: acl}); err != nil {
+			return fmt.Errorf("failed to encode extended attributes for %s: %w", path, err)
+		}
+		entry.ExtendedAttributes = attrs
+		entry.ACL = acl
+		iso.isPacked = false
+		return nil
+	}
+
+	record := entry.DirectoryRecord()
+	if record == nil {
+		return fmt.Errorf("file not found: %s", path)
+	}
+	if record.RockRidge == nil {
+		record.RockRidge = &extensions.RockRidgeExtensions{}
+	}
+	record.RockRidge.ExtendedAttributes = attrs
+	record.RockRidge.ACL = acl
+
+	// Entries that do not fit into the directory record are moved to a continuation area when the image is packed
+	systemUse, err := extensions.MarshalRockRidge(record.RockRidge)
+	if err != nil {
+		record.RockRidge.ExtendedAttributes = entry.ExtendedAttributes
+		record.RockRidge.ACL = entry.ACL
+		return fmt.Errorf("failed to encode extended attributes for %s: %w", path, err)
+	}
+	record.SystemUse = systemUse
+
+	entry.ExtendedAttributes = attrs
+	entry.ACL = acl
+	iso.isPacked = false
+
+	return nil
+}
+
+// compressFile applies zisofs compression to a file being added when it is enabled and the path matches the configured
+// patterns. The data is returned unchanged, with no ZF entry, when compression does not save any logical blocks.
+func (iso *ISO9660) compressFile(path string, data []byte) ([]byte, *extensions.ZisofsInfo, error) {
+	if iso.createOptions == nil || !iso.createOptions.ZisofsEnabled || len(data) == 0 {
+		return data, nil, nil
+	}
+
+	matched := len(iso.createOptions.ZisofsPatterns) == 0
+	for _, pattern := range iso.createOptions.ZisofsPatterns {
+		nameMatch, _ := filepath.Match(pattern, filepath.Base(path))
+		pathMatch, _ := filepath.Match(strings.TrimPrefix(pattern, "/"), path)
+		if nameMatch || pathMatch {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return data, nil, nil
+	}
+
+	log2BlockSize, err := zisofs.Log2BlockSize(iso.createOptions.ZisofsBlockSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	compressed, err := zisofs.Compress(data, log2BlockSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blockSize := iso.logicalBlockSize()
+	blocks := func(n int) int { return (n + blockSize - 1) / blockSize }
+	if blocks(len(compressed)) >= blocks(len(data)) {
+		return data, nil, nil
+	}
+
+	return compressed, &extensions.ZisofsInfo{
+		Version:          1,
+		Algorithm:        extensions.ZISOFS_ALGORITHM_ZLIB,
+		HeaderSizeDiv4:   zisofs.HEADER_SIZE_V1 / 4,
+		Log2BlockSize:    log2BlockSize,
+		UncompressedSize: uint64(len(data)),
+	}, nil
+}
+
 func (iso *ISO9660) RemoveFile(path string) error {
 	// Normalize the path by removing leading slash
 	normalizedPath := strings.TrimPrefix(path, "/")
@@ -614,7 +1107,7 @@ func (iso *ISO9660) AddDirectory(sourcePath, targetPath string) error {
 		if info.IsDir() {
 			// For directories, we could create them explicitly, but ISO9660 
 			// typically creates them implicitly when files are added
-			return nil
+			return iso.captureDirectoryXattrs(path, isoPath, info)
 		} else {
 			// Read the file content and add it to the ISO
 			data, err := os.ReadFile(path)
@@ -622,11 +1115,45 @@ func (iso *ISO9660) AddDirectory(sourcePath, targetPath string) error {
 				return fmt.Errorf("failed to read file %s: %w", path, err)
 			}
 			
-			return iso.AddFile(isoPath, data)
+			if _, err := iso.addFile(isoPath, data, info.Mode().Perm()); err != nil {
+				return err
+			}
+
+			// Capture extended attributes and ACLs (e.g. SELinux labels) from the source file
+			if iso.createOptions != nil && iso.createOptions.CaptureXattrs {
+				attrs, acl, err := filesystem.ReadExtendedAttributes(path)
+				if err != nil {
+					return err
+				}
+				if len(attrs) > 0 || acl != nil {
+					return iso.SetExtendedAttributes(isoPath, attrs, acl)
+				}
+			}
+			return nil
 		}
 	})
 }
 
+// captureDirectoryXattrs records a directory added by AddDirectory explicitly when extended attributes and ACLs are
+// captured and the source directory carries any, directories without attributes are created implicitly.
+func (iso *ISO9660) captureDirectoryXattrs(sourcePath, isoPath string, info os.FileInfo) error {
+	if iso.createOptions == nil || !iso.createOptions.CaptureXattrs {
+		return nil
+	}
+	attrs, acl, err := filesystem.ReadExtendedAttributes(sourcePath)
+	if err != nil {
+		return err
+	}
+	if len(attrs) == 0 && acl == nil {
+		return nil
+	}
+
+	entry := filesystem.NewFileSystemEntry(filepath.Base(isoPath), isoPath, true, 0, 0, nil, nil, info.Mode().Perm(),
+		info.ModTime(), info.ModTime(), nil, nil)
+	iso.filesystemEntries = append(iso.filesystemEntries, entry)
+	return iso.SetExtendedAttributes(isoPath, attrs, acl)
+}
+
 // CreateDirectories creates all directories from the ISO in the specified path.
 func (iso *ISO9660) CreateDirectories(path string) error {
 	// Ensure output directory exists
@@ -644,6 +1171,11 @@ func (iso *ISO9660) CreateDirectories(path string) error {
 		if err := os.MkdirAll(dirPath, entry.Mode); err != nil {
 			return fmt.Errorf("failed to create directory %s: %w", dirPath, err)
 		}
+		if iso.openOptions != nil && iso.openOptions.RestoreXattrs {
+			if err := filesystem.WriteExtendedAttributes(dirPath, entry.ExtendedAttributes, entry.ACL); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -682,7 +1214,7 @@ func (iso *ISO9660) Extract(path string) error {
 		}
 
 		// if the option to strip version info is enabled, enhanced and rr are not enabled then strip the version info
-		if iso.openOptions.StripVersionInfo && !iso.openOptions.RockRidgeEnabled && !iso.openOptions.PreferJoliet {
+		if iso.openOptions.StripVersionInfo && !iso.openOptions.RockRidgeEnabled && iso.supplementary() == nil {
 			outputPath = strings.TrimRight(outputPath, ";1")
 		}
 
@@ -693,8 +1225,11 @@ func (iso *ISO9660) Extract(path string) error {
 		}
 		defer outFile.Close()
 
-		// Stream the file from the ISO
-		startOffset := int64(entry.Location) * int64(consts.ISO9660_SECTOR_SIZE)
+		// Stream the file from the ISO, compressed files are decompressed on the fly
+		content, err := entry.ContentReader()
+		if err != nil {
+			return fmt.Errorf("failed to open file %s from ISO: %w", entry.FullPath, err)
+		}
 		size := int64(entry.Size)
 		bufferSize := 4096 // 4KB buffer
 		buffer := make([]byte, bufferSize)
@@ -707,7 +1242,7 @@ func (iso *ISO9660) Extract(path string) error {
 				bytesToRead = int(remaining)
 			}
 
-			n, err := entry.ReadAt(buffer[:bytesToRead], startOffset+bytesTransferred)
+			n, err := content.ReadAt(buffer[:bytesToRead], bytesTransferred)
 			if err != nil && err != io.EOF {
 				return fmt.Errorf("failed to read file %s from ISO: %w", entry.FullPath, err)
 			}
@@ -716,8 +1251,12 @@ func (iso *ISO9660) Extract(path string) error {
 				break // Reached EOF
 			}
 
-			// Write chunk to file
-			if _, err := outFile.Write(buffer[:n]); err != nil {
+			// Write chunk to file, zero chunks of sparse files are skipped to leave a hole in the output
+			if entry.Sparse != nil && isZero(buffer[:n]) {
+				if _, err := outFile.Seek(int64(n), io.SeekCurrent); err != nil {
+					return fmt.Errorf("failed to seek in file %s: %w", outputPath, err)
+				}
+			} else if _, err := outFile.Write(buffer[:n]); err != nil {
 				return fmt.Errorf("failed to write to file %s: %w", outputPath, err)
 			}
 
@@ -730,14 +1269,39 @@ func (iso *ISO9660) Extract(path string) error {
 			}
 		}
 
+		// A sparse file ending in a hole needs its size set explicitly
+		if entry.Sparse != nil {
+			if err := outFile.Truncate(bytesTransferred); err != nil {
+				return fmt.Errorf("failed to set size of file %s: %w", outputPath, err)
+			}
+		}
+
 		// Set correct file permissions
 		if err := os.Chmod(outputPath, entry.Mode); err != nil {
 			return fmt.Errorf("failed to set permissions on %s: %w", outputPath, err)
 		}
 
-		// Set timestamps
+		// Restore extended attributes and ACLs, this happens after chmod since setting an ACL also updates the mode
+		if iso.openOptions.RestoreXattrs {
+			if err := filesystem.WriteExtendedAttributes(outputPath, entry.ExtendedAttributes, entry.ACL); err != nil {
+				return err
+			}
+		}
+
+		// Write the Finder info and resource fork of Mac files beside them
+		if iso.openOptions.AppleDouble && entry.HasAppleDouble() {
+			if err := entry.WriteAppleDouble(outputPath); err != nil {
+				return err
+			}
+		}
+
+		// Set timestamps, the modification time stands in for the access time if none was recorded
 		if !entry.ModTime.IsZero() {
-			if err := os.Chtimes(outputPath, entry.ModTime, entry.ModTime); err != nil {
+			atime := entry.AccessTime
+			if atime.IsZero() {
+				atime = entry.ModTime
+			}
+			if err := os.Chtimes(outputPath, atime, entry.ModTime); err != nil {
 				return fmt.Errorf("failed to set timestamps on %s: %w", outputPath, err)
 			}
 		}
@@ -746,6 +1310,16 @@ func (iso *ISO9660) Extract(path string) error {
 	return nil
 }
 
+// isZero checks if a buffer only contains zero bytes.
+func isZero(buf []byte) bool {
+	for _, b := range buf {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // SetLogger sets the logger for the ISO9660 filesystem.
 func (iso *ISO9660) SetLogger(logger *logging.Logger) {
 	iso.logger = logger
@@ -804,22 +1378,64 @@ func (iso *ISO9660) GetObjects() []info.ImageObject {
 	return objects
 }
 
-// Pack prepares the ISO for writing by calculating file locations and preparing data structures
+// Pack prepares the ISO for writing by laying out the volume descriptors, path tables, directory hierarchies and file
+// extents of the image and assigning their locations.
 func (iso *ISO9660) Pack() error {
 	if iso.isPacked {
 		return nil // Already packed
 	}
-	
-	// This is a simplified packing implementation
-	// In a full implementation, this would calculate proper sector locations,
-	// build path tables, create directory records, etc.
-	
-	// For now, just mark as packed to allow Save to work
+
+	layout, err := iso.pack()
+	if err != nil {
+		return err
+	}
+	iso.layout = layout
 	iso.isPacked = true
+	iso.logger.Debug("Packed image", "volumes", layout.lastVolume(), "blocks", layout.volumeSpaceSizes, "files", len(layout.files))
 	return nil
 }
 
+// Save writes the image, compressed if an output compression was chosen when it was created.
 func (iso *ISO9660) Save(writer io.WriterAt) error {
+	return iso.write(writer, iso.save)
+}
+
+// write writes an image with save, compressed if an output compression was chosen when the image was created.
+func (iso *ISO9660) write(writer io.WriterAt, save func(io.WriterAt) error) error {
+	if iso.createOptions == nil || iso.createOptions.Compression == compressed.FORMAT_NONE {
+		return save(writer)
+	}
+
+	// The image is written to a temporary file first as the compressors read it sequentially
+	tmp, err := os.CreateTemp("", "iso-kit-*.iso")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary image: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := save(tmp); err != nil {
+		return err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return err
+	}
+
+	switch iso.createOptions.Compression {
+	case compressed.FORMAT_CSO:
+		err = compressed.WriteCSO(writer, tmp, info.Size(), compressed.CSO_BLOCK_SIZE)
+	case compressed.FORMAT_ZSTD:
+		err = compressed.WriteZstd(io.NewOffsetWriter(writer, 0), io.NewSectionReader(tmp, 0, info.Size()), compressed.ZSTD_FRAME_SIZE)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s image: %w", iso.createOptions.Compression, err)
+	}
+	return nil
+}
+
+// save writes the image at the locations assigned by Pack.
+func (iso *ISO9660) save(writer io.WriterAt) error {
 	// Ensure the ISO is packed and all objects have been assigned locations
 	if !iso.isPacked {
 		err := iso.Pack()
@@ -828,311 +1444,197 @@ func (iso *ISO9660) Save(writer io.WriterAt) error {
 		}
 	}
 
-	// Get all objects
-	objects := iso.GetObjects()
+	// Images that were opened and not modified are written from the objects read from them
+	if iso.layout == nil {
+		objects := iso.GetObjects()
+		slices.SortFunc(objects, func(a, b info.ImageObject) int {
+			return int(a.Offset() - b.Offset())
+		})
+		for _, obj := range objects {
+			data, err := obj.Marshal()
+			if err != nil {
+				return fmt.Errorf("failed to marshal object %s: %w", obj.Name(), err)
+			}
+			if _, err = writer.WriteAt(data, obj.Offset()); err != nil {
+				return fmt.Errorf("failed to write object %s at offset %d: %w", obj.Name(), obj.Offset(), err)
+			}
+		}
+		return nil
+	}
 
-	// Sort objects by offset before writing
-	slices.SortFunc(objects, func(a, b info.ImageObject) int {
-		return int(a.Offset() - b.Offset())
-	})
+	if volumes := iso.layout.lastVolume(); volumes > 1 {
+		return fmt.Errorf("image is a volume set of %d volumes, save it with SaveVolumeSet", volumes)
+	}
+	return iso.saveVolume(writer, 1)
+}
 
-	// Write each object at its assigned offset
-	for _, obj := range objects {
-		// Get raw data for the object
-		data, err := obj.Marshal()
-		if err != nil {
-			return fmt.Errorf("failed to marshal object %s: %w", obj.Name(), err)
+// saveVolume writes a volume of the image at the locations assigned by Pack, the last volume of a volume set records
+// the path tables and directory hierarchies.
+func (iso *ISO9660) saveVolume(writer io.WriterAt, sequenceNumber uint16) error {
+	last := sequenceNumber == iso.layout.lastVolume()
+
+	// 1: System area
+	if _, err := writer.WriteAt(iso.systemArea.Contents[:], 0); err != nil {
+		return fmt.Errorf("failed to write system area: %w", err)
+	}
+
+	// 2: Volume descriptor set
+	for _, vd := range iso.layout.descriptors {
+		if err := writeDescriptor(writer, vd, vd.Offset()); err != nil {
+			return fmt.Errorf("failed to write %s: %w", vd.Name(), err)
 		}
+	}
 
-		// Write data at the correct offset
-		_, err = writer.WriteAt(data, obj.Offset())
-		if err != nil {
-			return fmt.Errorf("failed to write object %s at offset %d: %w", obj.Name(), obj.Offset(), err)
+	// 3: Path tables (Little & Big Endian versions)
+	if last {
+		if err := iso.writePathTables(writer); err != nil {
+			return err
+		}
+	}
+
+	// 4: Directory records of every hierarchy and their continuation areas
+	if last {
+		if err := iso.writeDirectoryRecords(writer); err != nil {
+			return err
 		}
 	}
 
+	// 5: File contents, padded to whole logical blocks
+	if err := iso.writeFileData(writer, sequenceNumber); err != nil {
+		return err
+	}
+
+	// 6: Partitions read from the opened image
+	if last {
+		return iso.writePartitions(writer)
+	}
+	return nil
+}
+
+// writePartitions copies the content of the partitions of an opened image, partitions added to a created image are
+// left zeroed for the caller to fill.
+func (iso *ISO9660) writePartitions(writer io.WriterAt) error {
+	if iso.isoReader == nil {
+		return nil
+	}
+	blockSize := int64(iso.layout.blockSize)
+	for _, vpd := range iso.volumeDescriptorSet.Partition {
+		offset := int64(vpd.VolumePartitionLocation) * blockSize
+		partition := io.NewSectionReader(iso.isoReader, offset, int64(vpd.VolumePartitionSize)*blockSize)
+		if _, err := io.Copy(io.NewOffsetWriter(writer, offset), partition); err != nil {
+			return fmt.Errorf("failed to write partition %s: %w", vpd.VolumePartitionIdentifier, err)
+		}
+	}
 	return nil
+}
 
-	//sectorSize := int64(consts.ISO9660_SECTOR_SIZE)
-	//saOffset := int64(0)
-	//
-	//// Calculate offsets for descriptors
-	//pvdSize := sectorSize
-	//bootSize := int64(0)
-	//if iso.bootRecord != nil {
-	//	bootSize = sectorSize
-	//}
-	//svdSize := int64(len(iso.svds)) * sectorSize
-	//ptvdSize := int64(len(iso.partitionvds)) * sectorSize
-	//
-	//pvdOffset := saOffset + consts.ISO9660_SYSTEM_AREA_SECTORS*sectorSize
-	//bootOffset := pvdOffset + pvdSize
-	//svdOffset := bootOffset + bootSize
-	//ptvdOffset := svdOffset + svdSize
-	//termOffset := ptvdOffset + ptvdSize
-	//
-	//type descriptorSetEntry struct {
-	//	descriptor descriptor.VolumeDescriptor
-	//	offset     int64
-	//}
-	//descriptorSet := []*descriptorSetEntry{
-	//	{descriptor: iso.pvd, offset: pvdOffset},
-	//}
-	//if iso.bootRecord != nil {
-	//	descriptorSet = append(descriptorSet,
-	//		&descriptorSetEntry{descriptor: iso.bootRecord, offset: bootOffset},
-	//	)
-	//}
-	//for i, svd := range iso.svds {
-	//	descriptorSet = append(descriptorSet,
-	//		&descriptorSetEntry{descriptor: svd, offset: svdOffset + int64(i)*sectorSize},
-	//	)
-	//}
-	//for i, ptvd := range iso.partitionvds {
-	//	descriptorSet = append(descriptorSet,
-	//		&descriptorSetEntry{descriptor: ptvd, offset: ptvdOffset + int64(i)*sectorSize},
-	//	)
-	//}
-	//descriptorSet = append(descriptorSet,
-	//	&descriptorSetEntry{descriptor: descriptor.NewVolumeDescriptorSetTerminator(), offset: termOffset})
-	//
-	//// Write system area
-	//_, err := writer.WriteAt(iso.systemArea.Contents[:], 0)
-	//if err != nil {
-	//	return err
-	//}
-	//
-	//// Write descriptor set
-	//for _, entry := range descriptorSet {
-	//	if err = writeDescriptor(writer, entry.descriptor, entry.offset); err != nil {
-	//		return err
-	//	}
-	//}
-	//
-	//// Write path tables according to their location in the volume descriptors
-	//err = iso.writePathTables(writer)
-	//if err != nil {
-	//	return err
-	//}
-
-	// Write directory records
-
-	//pathTableOffset := svdOffset + (len(iso.svds) * sectorSize)
-	//// Directory Record offsets should be
-	//
-	//
-
-	//// TODO: Clean up all of the offset calculations, instead this should be calculated somewhere
-	////       else and the locations should just be used to write the data or this should be wrapped
-	////       nicely in a function.
-	//// Write volume descriptor set
-	//pvdOffset := int64(16 * sectorSize)
-	//err = writeDescriptor(writer, iso.pvd, pvdOffset)
-	//if err != nil {
-	//	return err
-	//}
-	//
-	//svdOffset := pvdOffset + int64(1*sectorSize)
-	//for i, svd := range iso.svds {
-	//	err = writeDescriptor(writer, svd, svdOffset)
-	//	if err != nil {
-	//		return err
-	//	}
-	//	svdOffset = svdOffset+int64((i+1)*sectorSize)
-	//}
-	//
-	//ptvdOffset := svdOffset
-	//for i, pvd := range iso.partitionvds {
-	//	if err = writeDescriptor(writer, pvd, ptvdOffset); err != nil {
-	//		return err
-	//	}
-	//	ptvdOffset = ptvdOffset+int64((i+1)*sectorSize)
-	//}
-	//
-	//bootOffset := ptvdOffset
-	//if iso.bootRecord != nil {
-	//	if err = writeDescriptor(writer, iso.bootRecord, bootOffset); err != nil {
-	//		return err
-	//	}
-	//	bootOffset = bootOffset + int64(1*sectorSize)
-	//}
-	//
-	//tr := descriptor.NewVolumeDescriptorSetTerminator()
-	//if err := writeDescriptor(writer, tr, bootOffset); err != nil {
-	//	return err
-	//}
-
-	//// 3: Write path tables (Little & Big Endian versions)
-	//if err = iso.writePathTables(writer); err != nil {
-	//	return err
-	//}
-	//
-	//// 4: Write directory records (Root & Subdirectories)
-	//totalRecords := append(iso.pvdDirectoryRecords, iso.svdDirectoryRecords...)
-	//for i, dr := range totalRecords {
-	//	drOffset :=
-	//}
-	//
-	//
-	//// 5: Write file contents (Ensuring correct logical block placement)
-	//if err := iso.writeFileData(writer); err != nil {
-	//	return err
-	//}
-	//
-	//// 6: Align to sector size (Padding to 2048-byte boundaries)
-	//if err := padToSector(writer); err != nil {
-	//	return err
-	//}
+// SaveVolumeSet writes every volume of a volume set created with WithMaxVolumeSize, one writer per volume in sequence
+// order. Each volume records its own sequence number in its volume descriptors.
+func (iso *ISO9660) SaveVolumeSet(writers []io.WriterAt) error {
+	pvd := iso.volumeDescriptorSet.Primary
+	if len(writers) != int(pvd.VolumeSetSize) {
+		return fmt.Errorf("volume set has %d volumes, got %d writers", pvd.VolumeSetSize, len(writers))
+	}
+
+	// Every volume is written from the layout, also when the image was opened and not modified
+	if iso.layout == nil {
+		iso.isPacked = false
+	}
+	if err := iso.Pack(); err != nil {
+		return fmt.Errorf("failed to pack ISO: %w", err)
+	}
 
+	defer iso.setVolume(iso.layout, iso.layout.lastVolume())
+	for i, writer := range writers {
+		sequenceNumber := uint16(i + 1)
+		iso.setVolume(iso.layout, sequenceNumber)
+		err := iso.write(writer, func(w io.WriterAt) error {
+			return iso.saveVolume(w, sequenceNumber)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save volume %d: %w", sequenceNumber, err)
+		}
+	}
 	return nil
 }
 
 // Close closes the ISO9660 filesystem.
 func (iso *ISO9660) Close() error {
-	if f, ok := iso.isoReader.(*os.File); ok {
-		return f.Close()
+	for _, volume := range iso.volumes {
+		if c, ok := volume.(io.Closer); ok {
+			c.Close()
+		}
+	}
+	if c, ok := iso.isoReader.(io.Closer); ok {
+		return c.Close()
 	}
 	return nil
 }
 
-// writePathTables writes the path tables to the ISO9660 filesystem.
+// writePathTables writes the path tables of every directory hierarchy.
 func (iso *ISO9660) writePathTables(writer io.WriterAt) error {
-	//if iso.pvd == nil {
-	//	return errors.New("PVD is missing")
-	//}
-	//
-	//buf, err := iso.pvdLPathTable.Marshal(true)
-	//if err != nil {
-	//	return err
-	//}
-	//
-	//if _, err = writer.WriteAt(buf, int64(iso.pvd.LocationOfTypeLPathTable)*consts.ISO9660_SECTOR_SIZE); err != nil {
-	//	return err
-	//}
-	//
-	//buf, err = iso.pvdMPathTable.Marshal(false)
-	//if err != nil {
-	//	return err
-	//}
-	//
-	//if _, err = writer.WriteAt(buf, int64(iso.pvd.LocationOfTypeMPathTable)*consts.ISO9660_SECTOR_SIZE); err != nil {
-	//	return err
-	//}
-	//
-	//if iso.svdLPathTable != nil {
-	//	buf, err = iso.svdLPathTable.Marshal(true)
-	//	if err != nil {
-	//		return err
-	//	}
-	//
-	//	if _, err = writer.WriteAt(buf, int64(iso.svds[0].LocationOfTypeLPathTable)*consts.ISO9660_SECTOR_SIZE); err != nil {
-	//		return err
-	//	}
-	//}
-	//
-	//if iso.svdMPathTable != nil {
-	//	buf, err = iso.svdMPathTable.Marshal(false)
-	//	if err != nil {
-	//		return err
-	//	}
-	//
-	//	if _, err = writer.WriteAt(buf, int64(iso.svds[0].LocationOfTypeMPathTable)*consts.ISO9660_SECTOR_SIZE); err != nil {
-	//		return err
-	//	}
-	//}
-
+	for _, pt := range iso.pathTables {
+		data, err := pt.Marshal()
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", pt.Name(), err)
+		}
+		if _, err := writer.WriteAt(data, pt.Offset()); err != nil {
+			return fmt.Errorf("failed to write %s: %w", pt.Name(), err)
+		}
+	}
 	return nil
 }
 
+// writeDirectoryRecords writes the directory extents of every directory hierarchy and the continuation areas of their
+// system use entries.
 func (iso *ISO9660) writeDirectoryRecords(writer io.WriterAt) error {
+	blockSize := int64(iso.layout.blockSize)
+	for _, h := range iso.layout.hierarchies {
+		for _, dir := range h.directories {
+			if _, err := writer.WriteAt(dir.data, int64(dir.location)*blockSize); err != nil {
+				return fmt.Errorf("failed to write directory %s: %w", dir.node.name, err)
+			}
+		}
+	}
+	if len(iso.layout.continuationData) > 0 {
+		if _, err := writer.WriteAt(iso.layout.continuationData, int64(iso.layout.continuation)*blockSize); err != nil {
+			return fmt.Errorf("failed to write continuation areas: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeFileData copies the file extents recorded on a volume into the image, the last block of each is padded with
+// zeros.
+func (iso *ISO9660) writeFileData(writer io.WriterAt, sequenceNumber uint16) error {
+	blockSize := int64(iso.layout.blockSize)
+	end := int64(0)
+	for _, file := range iso.layout.files {
+		size := file.extent.Size()
+		if size == 0 || file.volume != sequenceNumber {
+			continue
+		}
+		offset := int64(file.location) * blockSize
+		if _, err := io.Copy(io.NewOffsetWriter(writer, offset), io.NewSectionReader(file.extent, 0, size)); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.entry.FullPath, err)
+		}
+		end = (offset + size + blockSize - 1) / blockSize * blockSize
+		if err := writeZeros(writer, offset+size, end-offset-size); err != nil {
+			return fmt.Errorf("failed to pad %s: %w", file.entry.FullPath, err)
+		}
+	}
+
+	// Pad the image to the size of the volume
+	if size := int64(iso.layout.volumeSpaceSizes[sequenceNumber-1]) * blockSize; end < size {
+		last := make([]byte, blockSize)
+		if _, err := writer.WriteAt(last, size-blockSize); err != nil {
+			return fmt.Errorf("failed to pad image: %w", err)
+		}
+	}
+	return nil
+}
 
-	//var rootDirOffset uint32
-	//
-	//// TODO: Write PVD Directory records starting with the root
-	//rootDirOffset = iso.pvd.RootDirectoryRecord.LocationOfExtent
-
-	// TODO: Write SVD Directory records starting with the root
-
-	//sectorSize := consts.ISO9660_SECTOR_SIZE
-	//
-	//// Root Directory
-	//rootDirData, err := iso.
-	//if err != nil {
-	//	return err
-	//}
-	//if _, err := writer.Write(rootDirData[:]); err != nil {
-	//	return err
-	//}
-	//
-	//// Write Subdirectories
-	//for _, dir := range iso.directories {
-	//	dirData, err := dir.Marshal()
-	//	if err != nil {
-	//		return err
-	//	}
-	//
-	//	// Ensure correct sector alignment
-	//	padding := sectorSize - (len(dirData) % sectorSize)
-	//	if padding < sectorSize {
-	//		dirData = append(dirData, make([]byte, padding)...)
-	//	}
-	//
-	//	if _, err := writer.Write(dirData[:]); err != nil {
-	//		return err
-	//	}
-	//}
-	//
-	//return nil
-	return errors.New("not implemented")
-}
-
-func (iso *ISO9660) writeFileData(writer io.Writer) error {
-	//sectorSize := consts.ISO9660_SECTOR_SIZE
-	//
-	//for _, file := range iso. {
-	//	fileData, err := file.ReadData()
-	//	if err != nil {
-	//		return err
-	//	}
-	//
-	//	if _, err := writer.Write(fileData); err != nil {
-	//		return err
-	//	}
-	//
-	//	// Align to sector size
-	//	padding := sectorSize - (len(fileData) % sectorSize)
-	//	if padding < sectorSize {
-	//		if _, err := writer.Write(make([]byte, padding)); err != nil {
-	//			return err
-	//		}
-	//	}
-	//}
-	//
-	//return nil
-	return errors.New("not implemented")
-}
-
-func padToSector(writer io.Writer) error {
-	//sectorSize := consts.ISO9660_SECTOR_SIZE
-	//
-	//// Get current file position
-	//offset, err := writer.Seek(0, io.SeekCurrent)
-	//if err != nil {
-	//	return err
-	//}
-	//
-	//// Compute padding
-	//padding := sectorSize - (offset % sectorSize)
-	//if padding < sectorSize {
-	//	_, err := writer.Write(make([]byte, padding))
-	//	return err
-	//}
-	//
-	//return nil
-	return errors.New("not implemented")
-}
-
-func writeDescriptor(writer io.WriterAt, descriptor descriptor.VolumeDescriptor, offset int64) error {
+func writeDescriptor(writer io.WriterAt, descriptor info.ImageObject, offset int64) error {
 	data, err := descriptor.Marshal()
 	if err != nil {
 		return err
@@ -1148,16 +1650,3 @@ func writeDescriptor(writer io.WriterAt, descriptor descriptor.VolumeDescriptor,
 
 	return nil
 }
-
-func writePathTable(writer io.Writer, location uint32, littleEndian bool) error {
-	//if location == 0 {
-	//	return nil // Skip if no path table is set
-	//}
-	//
-	//pathTable := generatePathTable(littleEndian) // Implement the path table generator
-	//data := pathTable.Marshal()
-	//
-	//_, err := writer.Write(data)
-	//return err
-	return errors.New("not implemented")
-}
@@ -0,0 +1,428 @@
+package iso9660
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rstms/iso-kit/pkg/filesystem"
+	"github.com/rstms/iso-kit/pkg/iso9660/extensions"
+	"github.com/rstms/iso-kit/pkg/iso9660/parser"
+	"github.com/rstms/iso-kit/pkg/logging"
+	"github.com/rstms/iso-kit/pkg/option"
+	"github.com/stretchr/testify/require"
+)
+
+// saveAndOpen saves the image to a temporary file and opens it again.
+func saveAndOpen(t *testing.T, img *ISO9660, opts ...option.OpenOption) *ISO9660 {
+	path := filepath.Join(t.TempDir(), "image.iso")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, img.Save(file))
+	require.NoError(t, file.Close())
+
+	reader, err := os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { reader.Close() })
+
+	opened, err := Open(reader, opts...)
+	require.NoError(t, err)
+	return opened
+}
+
+func TestCreateSaveOpen_Zisofs(t *testing.T) {
+	img, err := Create("ZISOFS", option.WithCreateRockRidgeEnabled(true), option.WithZisofsCompression())
+	require.NoError(t, err)
+
+	data := bytes.Repeat([]byte("compressible data "), 10000)
+	require.NoError(t, img.AddFile("dir/big.bin", data))
+	require.NoError(t, img.AddFile("small.txt", []byte("hello")))
+
+	opened := saveAndOpen(t, img)
+	require.True(t, opened.HasRockRidge())
+	require.Equal(t, "1.10", opened.RockRidgeVersion())
+
+	got, err := opened.ReadFile("dir/big.bin")
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+
+	got, err = opened.ReadFile("small.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), got)
+
+	files, err := opened.ListFiles()
+	require.NoError(t, err)
+	for _, file := range files {
+		if file.FullPath == "/dir/big.bin" {
+			require.NotNil(t, file.Zisofs)
+			require.Equal(t, uint64(len(data)), file.Zisofs.UncompressedSize)
+		}
+	}
+}
+
+func TestCreateSaveOpen_Joliet(t *testing.T) {
+	img, err := Create("JOLIET", option.WithJolietEnabled(true))
+	require.NoError(t, err)
+	require.NoError(t, img.AddFile("Some Long File Name.txt", []byte("joliet")))
+
+	opened := saveAndOpen(t, img, option.WithPreferJoliet(true))
+	require.True(t, opened.HasJoliet())
+
+	got, err := opened.ReadFile("Some Long File Name.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("joliet"), got)
+}
+
+func TestCreateSaveOpen_ExtendedAttributes(t *testing.T) {
+	img, err := Create("XATTR")
+	require.NoError(t, err)
+	require.NoError(t, img.AddFile("labeled.txt", []byte("labeled")))
+
+	attrs := []extensions.ExtendedAttribute{
+		{Name: "user.comment", Value: []byte("hello")},
+		// Too large for the directory record, it is recorded in a continuation area
+		{Name: "user.large", Value: bytes.Repeat([]byte("x"), 300)},
+	}
+	acl := &extensions.ACL{Access: []extensions.ACLEntry{
+		{Tag: extensions.ACL_USER_OBJ, Perm: 6},
+		{Tag: extensions.ACL_USER_N, Perm: 4, ID: 1000},
+		{Tag: extensions.ACL_GROUP_OBJ, Perm: 4},
+		{Tag: extensions.ACL_MASK, Perm: 4},
+		{Tag: extensions.ACL_OTHER, Perm: 4},
+	}}
+	require.NoError(t, img.SetExtendedAttributes("labeled.txt", attrs, acl))
+
+	opened := saveAndOpen(t, img)
+	require.True(t, opened.HasRockRidge())
+
+	var identifiers []string
+	for _, er := range opened.RockRidgeInfo().Extensions {
+		identifiers = append(identifiers, er.Identifier)
+	}
+	require.Contains(t, identifiers, extensions.AAIP_IDENTIFIER)
+
+	files, err := opened.ListFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, attrs, files[0].ExtendedAttributes)
+	require.Equal(t, acl, files[0].ACL)
+
+	got, err := opened.ReadFile("labeled.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("labeled"), got)
+}
+
+func TestAddDirectory_ExtendedAttributes(t *testing.T) {
+	source := t.TempDir()
+	labeled := filepath.Join(source, "labeled")
+	require.NoError(t, os.Mkdir(labeled, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(labeled, "file.txt"), []byte("labeled"), 0644))
+
+	attr := extensions.ExtendedAttribute{Name: "user.label", Value: []byte("directory")}
+	if err := filesystem.WriteExtendedAttributes(labeled, []extensions.ExtendedAttribute{attr}, nil); err != nil {
+		t.Skipf("extended attributes are not supported: %v", err)
+	}
+
+	img, err := Create("XATTRDIR", option.WithCaptureXattrs(true))
+	require.NoError(t, err)
+	require.NoError(t, img.AddDirectory(source, "/"))
+
+	opened := saveAndOpen(t, img)
+	dirs, err := opened.ListDirectories()
+	require.NoError(t, err)
+	var dir *filesystem.FileSystemEntry
+	for _, entry := range dirs {
+		if strings.Trim(entry.FullPath, "/") == "labeled" {
+			dir = entry
+		}
+	}
+	require.NotNil(t, dir)
+	require.Contains(t, dir.ExtendedAttributes, attr)
+
+	got, err := opened.ReadFile("labeled/file.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("labeled"), got)
+}
+
+func TestCreateSaveOpen_Sparse(t *testing.T) {
+	for _, ear := range []bool{false, true} {
+		img, err := Create("SPARSE", option.WithSparseFiles(true), option.WithExtendedAttributeRecords(ear))
+		require.NoError(t, err)
+
+		data := make([]byte, 600*2048)
+		copy(data[10*2048:], "data between holes")
+		copy(data[len(data)-4:], "tail")
+		require.NoError(t, img.AddFile("first.txt", []byte("pushes the sparse extent away from block 0")))
+		require.NoError(t, img.AddFile("holes.bin", data))
+
+		opened := saveAndOpen(t, img)
+		files, err := opened.ListFiles()
+		require.NoError(t, err)
+		for _, file := range files {
+			if file.FullPath == "/holes.bin" {
+				require.NotNil(t, file.Sparse)
+				require.NotZero(t, file.Location)
+			}
+		}
+
+		got, err := opened.ReadFile("holes.bin")
+		require.NoError(t, err)
+		require.Equal(t, data, got)
+
+		// Saving the opened image again moves the extent
+		require.NoError(t, opened.AddFile("another.txt", []byte("another")))
+		reopened := saveAndOpen(t, opened)
+		got, err = reopened.ReadFile("holes.bin")
+		require.NoError(t, err)
+		require.Equal(t, data, got)
+	}
+}
+
+func TestCreateSaveOpen_Enhanced(t *testing.T) {
+	img, err := Create("ENHANCED", option.WithEnhancedVolumeDescriptor(true))
+	require.NoError(t, err)
+	const name = "Mixed Case/a long name; with punctuation.tar.gz"
+	require.NoError(t, img.AddFile(name, []byte("enhanced")))
+
+	opened := saveAndOpen(t, img, option.WithPreferEnhanced(true))
+	require.True(t, opened.HasEnhanced())
+
+	files, err := opened.ListFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, "/"+name, files[0].FullPath)
+
+	got, err := opened.ReadFile(name)
+	require.NoError(t, err)
+	require.Equal(t, []byte("enhanced"), got)
+}
+
+// markAssociated turns the directory record of the file recorded as identifier into an associated file of the file
+// recorded as owner. The writer does not record associated files, the identifiers must have the same length.
+func markAssociated(t *testing.T, image []byte, identifier, owner string) {
+	index := bytes.Index(image, []byte(identifier))
+	require.Positive(t, index)
+	record := image[index-33:]
+	require.Equal(t, byte(len(identifier)), record[32])
+	record[25] |= 0x04 // Associated file flag
+	copy(record[33:], owner)
+}
+
+func TestOpen_AssociatedFile(t *testing.T) {
+	img, err := Create("MAC", option.WithCreateRockRidgeEnabled(true))
+	require.NoError(t, err)
+	entry, err := img.addFile("DATA.TXT", []byte("data fork"), 0644)
+	require.NoError(t, err)
+	apple := &extensions.AppleExtension{SystemUseID: extensions.APPLE_HFS, Type: "TEXT", Creator: "ttxt", FinderFlags: 0x0100}
+	entry.DirectoryRecord().RockRidge = &extensions.RockRidgeExtensions{Apple: apple}
+	require.NoError(t, img.AddFile("RSRC.TXT", []byte("resource fork")))
+	require.NoError(t, img.AddFile("LONE.TXT", []byte("associated file without a file")))
+
+	path := filepath.Join(t.TempDir(), "image.iso")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, img.Save(file))
+	require.NoError(t, file.Close())
+	image, err := os.ReadFile(path)
+	require.NoError(t, err)
+	markAssociated(t, image, "RSRC.TXT;1", "DATA.TXT;1")
+	markAssociated(t, image, "LONE.TXT;1", "LONE.TXT;1")
+
+	opened, err := Open(bytes.NewReader(image), option.WithAppleDouble(true))
+	require.NoError(t, err)
+
+	// The resource fork is linked to the file sharing its identifier and not listed on its own, an associated file
+	// without a file is kept
+	files, err := opened.ListFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.Equal(t, "/DATA.TXT", files[0].FullPath)
+	require.Equal(t, apple, files[0].Apple)
+	require.NotNil(t, files[0].AssociatedFile)
+	require.Equal(t, uint64(len("resource fork")), files[0].AssociatedFile.Size)
+	require.Equal(t, "/LONE.TXT", files[1].FullPath)
+	require.Nil(t, files[1].AssociatedFile)
+	require.False(t, files[1].HasAppleDouble())
+
+	// The AppleDouble file holds the Finder info and the resource fork
+	expected := []byte{
+		0x00, 0x05, 0x16, 0x07, 0x00, 0x02, 0x00, 0x00, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x00, 0x02,
+		0, 0, 0, 9, 0, 0, 0, 50, 0, 0, 0, 32,
+		0, 0, 0, 2, 0, 0, 0, 82, 0, 0, 0, 13,
+		'T', 'E', 'X', 'T', 't', 't', 'x', 't', 0x01, 0x00, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0,
+	}
+	expected = append(expected, "resource fork"...)
+	data, err := files[0].MarshalAppleDouble()
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+
+	dir := t.TempDir()
+	require.NoError(t, opened.Extract(dir))
+	data, err = os.ReadFile(filepath.Join(dir, "._DATA.TXT"))
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+	data, err = os.ReadFile(filepath.Join(dir, "DATA.TXT"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("data fork"), data)
+	require.NoFileExists(t, filepath.Join(dir, "RSRC.TXT"))
+	require.NoFileExists(t, filepath.Join(dir, "._LONE.TXT"))
+}
+
+func TestCreateSaveOpen_Partition(t *testing.T) {
+	img, err := Create("PARTITION", option.WithCreateRockRidgeEnabled(true))
+	require.NoError(t, err)
+	require.NoError(t, img.AddFile("file.txt", []byte("outside the partition")))
+	require.NoError(t, img.AddPartition("SYSTEM", "DATA", 100, 8, []byte("system use")))
+
+	path := filepath.Join(t.TempDir(), "partition.iso")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, img.Save(file))
+
+	// The caller fills the partition after saving
+	_, err = file.WriteAt([]byte("partition content"), 100*2048)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	reader, err := os.Open(path)
+	require.NoError(t, err)
+	defer reader.Close()
+	opened, err := Open(reader)
+	require.NoError(t, err)
+
+	require.Len(t, opened.volumeDescriptorSet.Partition, 1)
+	vpd := opened.volumeDescriptorSet.Partition[0]
+	require.Equal(t, "DATA", vpd.VolumePartitionIdentifier)
+	require.Equal(t, uint32(100), vpd.VolumePartitionLocation)
+	require.Equal(t, uint32(8), vpd.VolumePartitionSize)
+	require.Equal(t, uint32(108), opened.GetVolumeSize())
+
+	// The partition descriptor and the terminator precede the path tables and the root directory
+	require.Greater(t, opened.RootDirectoryLocation(), uint32(19))
+	got, err := opened.ReadFile("file.txt")
+	require.NoError(t, err)
+	require.Equal(t, []byte("outside the partition"), got)
+
+	// Saving the opened image keeps the partition content
+	require.NoError(t, opened.AddFile("another.txt", []byte("another")))
+	reopened := saveAndOpen(t, opened)
+	require.Len(t, reopened.volumeDescriptorSet.Partition, 1)
+	content := make([]byte, len("partition content"))
+	_, err = reopened.isoReader.ReadAt(content, 100*2048)
+	require.NoError(t, err)
+	require.Equal(t, []byte("partition content"), content)
+
+	// A partition overlapping the file data cannot be packed
+	require.NoError(t, img.AddPartition("SYSTEM", "OVERLAP", 20, 8, nil))
+	require.Error(t, img.Pack())
+}
+
+// saveVolumeSet saves the volumes of a volume set to temporary files and returns them opened for reading.
+func saveVolumeSet(t *testing.T, img *ISO9660) []io.ReaderAt {
+	dir := t.TempDir()
+	var files []*os.File
+	var writers []io.WriterAt
+	for i := 0; i < int(img.GetVolumeSetSize()); i++ {
+		file, err := os.Create(filepath.Join(dir, fmt.Sprintf("volume%d.iso", i+1)))
+		require.NoError(t, err)
+		t.Cleanup(func() { file.Close() })
+		files = append(files, file)
+		writers = append(writers, file)
+	}
+	require.NoError(t, img.SaveVolumeSet(writers))
+
+	var readers []io.ReaderAt
+	for _, file := range files {
+		readers = append(readers, file)
+	}
+	return readers
+}
+
+// createVolumeSet creates a volume set identified by setID whose files spread over three volumes.
+func createVolumeSet(t *testing.T, setID string, opts ...option.CreateOption) (*ISO9660, map[string][]byte) {
+	opts = append([]option.CreateOption{option.WithCreateRockRidgeEnabled(true), option.WithMaxVolumeSize(64 * 2048)}, opts...)
+	img, err := Create("VOLUMES", opts...)
+	require.NoError(t, err)
+	img.volumeDescriptorSet.Primary.PrimaryVolumeDescriptorBody.VolumeSetIdentifier = setID
+
+	files := make(map[string][]byte)
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("dir/file%d.bin", i)
+		files[name] = bytes.Repeat([]byte{byte('a' + i)}, 20*2048)
+		require.NoError(t, img.AddFile(name, files[name]))
+	}
+	require.Equal(t, uint16(3), img.GetVolumeSetSize())
+	return img, files
+}
+
+func TestSaveOpenVolumeSet(t *testing.T) {
+	img, files := createVolumeSet(t, "SET_A")
+	volumes := saveVolumeSet(t, img)
+	require.ErrorContains(t, img.Save(volumes[0].(*os.File)), "SaveVolumeSet")
+
+	// Only the last volume records the directory hierarchy, the others hold file data only
+	for i, volume := range volumes[:2] {
+		pvd, err := parser.NewParser(volume, &option.OpenOptions{Logger: logging.DefaultLogger()}).GetPrimaryVolumeDescriptor()
+		require.NoError(t, err)
+		require.Equal(t, uint16(i+1), pvd.VolumeSequenceNumber)
+		require.Equal(t, uint32(18+40), pvd.VolumeSpaceSize)
+	}
+
+	// Volumes are given in any order
+	opened, err := OpenVolumeSet([]io.ReaderAt{volumes[1], volumes[2], volumes[0]})
+	require.NoError(t, err)
+	require.Equal(t, uint16(3), opened.GetVolumeSequenceNumber())
+
+	entries, err := opened.ListFiles()
+	require.NoError(t, err)
+	require.Len(t, entries, len(files))
+	sequenceNumbers := make(map[uint16]int)
+	for _, entry := range entries {
+		sequenceNumbers[entry.DirectoryRecord().VolumeSequenceNumber]++
+		data, err := entry.GetBytes()
+		require.NoError(t, err)
+		require.Equal(t, files[strings.TrimPrefix(entry.FullPath, "/")], data)
+	}
+	require.Equal(t, map[uint16]int{1: 2, 2: 2, 3: 1}, sequenceNumbers)
+}
+
+func TestSaveOpenVolumeSet_ExtendedAttributeRecords(t *testing.T) {
+	img, files := createVolumeSet(t, "SET_A", option.WithExtendedAttributeRecords(true))
+	opened, err := OpenVolumeSet(saveVolumeSet(t, img))
+	require.NoError(t, err)
+
+	// The Extended Attribute Records are read from the volume recording the file extent
+	entries, err := opened.ListFiles()
+	require.NoError(t, err)
+	require.Len(t, entries, len(files))
+	for _, entry := range entries {
+		record := entry.DirectoryRecord()
+		require.Equal(t, uint8(1), record.ExtendedAttributeRecordLength)
+		require.NotNil(t, record.ExtendedAttributeRecord, entry.FullPath)
+		require.Equal(t, uint8(1), record.ExtendedAttributeRecord.ExtendedAttributeRecordVersion)
+		data, err := entry.GetBytes()
+		require.NoError(t, err)
+		require.Equal(t, files[strings.TrimPrefix(entry.FullPath, "/")], data)
+	}
+}
+
+func TestOpenVolumeSet_Errors(t *testing.T) {
+	img, _ := createVolumeSet(t, "SET_A")
+	volumes := saveVolumeSet(t, img)
+	other, _ := createVolumeSet(t, "SET_B")
+	others := saveVolumeSet(t, other)
+
+	_, err := OpenVolumeSet([]io.ReaderAt{volumes[2], others[0], volumes[1]})
+	require.ErrorContains(t, err, "belongs to volume set")
+
+	_, err = OpenVolumeSet([]io.ReaderAt{volumes[2], volumes[0], volumes[0]})
+	require.ErrorContains(t, err, "duplicate volume sequence number")
+
+	_, err = OpenVolumeSet(nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,852 @@
+package iso9660
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/rstms/iso-kit/pkg/consts"
+	"github.com/rstms/iso-kit/pkg/filesystem"
+	"github.com/rstms/iso-kit/pkg/iso9660/descriptor"
+	"github.com/rstms/iso-kit/pkg/iso9660/directory"
+	"github.com/rstms/iso-kit/pkg/iso9660/encoding"
+	"github.com/rstms/iso-kit/pkg/iso9660/extensions"
+	"github.com/rstms/iso-kit/pkg/iso9660/info"
+	"github.com/rstms/iso-kit/pkg/iso9660/pathtable"
+	"github.com/rstms/iso-kit/pkg/iso9660/sparse"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The image is laid out in the order it is written: the system area and the volume descriptor set, the path tables and
+// the directory extents of every directory hierarchy, the continuation areas holding system use entries that did not
+// fit into their directory records and finally the file extents. The primary volume descriptor and
+// every supplementary volume descriptor describe their own directory hierarchy, the file extents are shared.
+//
+// The files of a multi-volume set are recorded on the volume named by the volume sequence number of their directory
+// record. Every volume records the volume descriptor set, the path tables and directory hierarchies are only recorded
+// on the last volume, which describes the files of all volumes.
+
+const (
+	// MAX_JOLIET_IDENTIFIER_LENGTH is the number of UCS-2 characters of a Joliet file identifier
+	MAX_JOLIET_IDENTIFIER_LENGTH = 64
+	// MAX_DIRECTORY_RECORD_LENGTH is the largest length recordable in a directory record
+	MAX_DIRECTORY_RECORD_LENGTH = 255
+)
+
+// imageLayout records where Pack placed the parts of the image, save writes them there.
+type imageLayout struct {
+	// blockSize is the logical block size of the volume
+	blockSize int
+	// descriptors are the volume descriptors in the order they are recorded from sector 16
+	descriptors []info.ImageObject
+	// hierarchies are the directory hierarchies of the primary and supplementary volume descriptors
+	hierarchies []*packedHierarchy
+	// continuation is the logical block of the continuation areas and continuationData their contents
+	continuation     uint32
+	continuationData []byte
+	// files are the files whose extents are recorded
+	files []*packedNode
+	// volumeSpaceSizes are the number of logical blocks of each volume by volume sequence number minus one, the last
+	// volume records the directory hierarchies
+	volumeSpaceSizes []uint32
+}
+
+// lastVolume returns the volume sequence number of the volume recording the directory hierarchies.
+func (layout *imageLayout) lastVolume() uint16 {
+	return uint16(len(layout.volumeSpaceSizes))
+}
+
+// packedNode is a file or directory of the image being packed.
+type packedNode struct {
+	name     string
+	entry    *filesystem.FileSystemEntry
+	parent   *packedNode
+	children []*packedNode
+	isDir    bool
+	// extent holds the Extended Attribute Record, when earBlocks is not zero, followed by the recorded file data
+	extent     *io.SectionReader
+	earBlocks  uint8
+	dataLength uint32
+	protection bool
+	// rr holds the system use entries carried over from the file's directory record, e.g. ZF, SF and AL
+	rr *extensions.RockRidgeExtensions
+	// location is the logical block of the file extent on the volume with sequence number volume
+	location uint32
+	volume   uint16
+}
+
+// packedHierarchy is the directory hierarchy described by one volume descriptor.
+type packedHierarchy struct {
+	// joliet records identifiers as UCS-2 for a Joliet supplementary volume descriptor
+	joliet bool
+	// enhanced records identifiers of any value without version numbers for an enhanced volume descriptor
+	enhanced bool
+	// rockRidge records Rock Ridge entries with the POSIX attributes and names of the files
+	rockRidge bool
+	// extensions are announced by ER entries in the "." record of the root directory when rockRidge is set
+	extensions []extensions.ExtensionReference
+	// directories are in path table order
+	directories []*packedDirectory
+	byNode      map[*packedNode]*packedDirectory
+	// pathTableSize is the size of each path table in bytes, they are recorded at pathTableL and pathTableM
+	pathTableSize uint32
+	pathTableL    uint32
+	pathTableM    uint32
+}
+
+// packedDirectory is the extent of a directory in a directory hierarchy.
+type packedDirectory struct {
+	node       *packedNode
+	identifier string
+	// parent is the path table number of the parent directory
+	parent  uint16
+	records []*packedRecord
+	// location is the logical block of the extent, size its length in bytes and data its contents
+	location uint32
+	size     uint32
+	data     []byte
+}
+
+// packedRecord is a directory record of a directory extent.
+type packedRecord struct {
+	record *directory.DirectoryRecord
+	// target is the file or directory described by the record
+	target *packedNode
+	// prefix is recorded ahead of the Rock Ridge entries, the SP entry of the root directory's "." record
+	prefix []byte
+	// rr holds the system use entries of the record, nil if none are recorded
+	rr *extensions.RockRidgeExtensions
+	// continuation locates the entries that did not fit into the record, its length is zero if all did
+	continuation extensions.ContinuationArea
+}
+
+// pack lays out the image and assigns the location of every volume descriptor, path table, directory and file extent.
+func (iso *ISO9660) pack() (*imageLayout, error) {
+	vds := iso.volumeDescriptorSet
+	if vds.Boot != nil || iso.elTorito != nil {
+		return nil, errors.New("packing images with a boot record is not supported")
+	}
+
+	blockSize := iso.logicalBlockSize()
+	blocksPerSector := uint32(consts.ISO9660_SECTOR_SIZE / blockSize)
+	layout := &imageLayout{blockSize: blockSize, volumeSpaceSizes: make([]uint32, max(vds.Primary.VolumeSetSize, 1))}
+
+	root, files, err := iso.packedTree()
+	if err != nil {
+		return nil, err
+	}
+	layout.files = files
+
+	// Files are recorded on the volume named by their directory record, the last volume when it names none
+	for _, file := range files {
+		file.volume = layout.lastVolume()
+		if record := file.entry.DirectoryRecord(); record != nil && record.VolumeSequenceNumber > 0 &&
+			record.VolumeSequenceNumber < file.volume {
+			file.volume = record.VolumeSequenceNumber
+		}
+	}
+
+	// The volume descriptor set
+	layout.descriptors = append(layout.descriptors, vds.Primary)
+	for _, svd := range vds.Supplementary {
+		layout.descriptors = append(layout.descriptors, svd)
+	}
+	for _, vpd := range vds.Partition {
+		layout.descriptors = append(layout.descriptors, vpd)
+	}
+	layout.descriptors = append(layout.descriptors, vds.Terminator)
+
+	// The directory hierarchies
+	primary := &packedHierarchy{rockRidge: iso.recordsRockRidge(root, files)}
+	if primary.rockRidge {
+		primary.extensions = []extensions.ExtensionReference{{
+			Identifier: extensions.ROCK_RIDGE_IDENTIFIER,
+			Descriptor: extensions.ROCK_RIDGE_DESCRIPTOR,
+			Source:     extensions.ROCK_RIDGE_SOURCE,
+			Version:    extensions.ROCK_RIDGE_VERSION,
+		}}
+		if recordsAttributes(root) {
+			primary.extensions = append(primary.extensions, extensions.ExtensionReference{
+				Identifier: extensions.AAIP_IDENTIFIER,
+				Descriptor: extensions.AAIP_DESCRIPTOR,
+				Source:     extensions.AAIP_SOURCE,
+				Version:    extensions.AAIP_VERSION,
+			})
+		}
+	}
+	layout.hierarchies = append(layout.hierarchies, primary)
+	for _, svd := range vds.Supplementary {
+		layout.hierarchies = append(layout.hierarchies, &packedHierarchy{joliet: svd.IsJoliet(), enhanced: svd.IsEnhanced()})
+	}
+	for _, h := range layout.hierarchies {
+		if err := h.build(root, blockSize); err != nil {
+			return nil, err
+		}
+	}
+
+	// Path tables follow the volume descriptor set
+	descriptorsEnd := uint32(consts.ISO9660_SYSTEM_AREA_SECTORS+len(layout.descriptors)) * blocksPerSector
+	next := descriptorsEnd
+	for _, h := range layout.hierarchies {
+		sectors := max((h.pathTableSize+consts.ISO9660_SECTOR_SIZE-1)/consts.ISO9660_SECTOR_SIZE, 1)
+		h.pathTableL = next
+		next += sectors * blocksPerSector
+		h.pathTableM = next
+		next += sectors * blocksPerSector
+	}
+
+	// Directory extents start on a sector so their records do not cross logical sector boundaries
+	for _, h := range layout.hierarchies {
+		for _, dir := range h.directories {
+			dir.location = next
+			next += dir.size / uint32(blockSize)
+		}
+	}
+
+	// Continuation areas follow the directories as sequential readers only look ahead, they are packed into logical
+	// blocks and an area does not cross a block boundary
+	layout.continuation = next
+	var blocks, offset uint32
+	for _, h := range layout.hierarchies {
+		for _, dir := range h.directories {
+			for _, pr := range dir.records {
+				if pr.continuation.Length == 0 {
+					continue
+				}
+				if blocks == 0 || offset+pr.continuation.Length > uint32(blockSize) {
+					blocks++
+					offset = 0
+				}
+				pr.continuation.Block = next + blocks - 1
+				pr.continuation.Offset = offset
+				offset += pr.continuation.Length
+			}
+		}
+	}
+	layout.continuationData = make([]byte, int(blocks)*blockSize)
+	next += blocks
+
+	// File extents follow the volume descriptor set on the other volumes, empty files have no extent
+	sizes := layout.volumeSpaceSizes
+	for i := range sizes {
+		sizes[i] = descriptorsEnd
+	}
+	sizes[len(sizes)-1] = next
+	for _, file := range files {
+		if file.extent.Size() == 0 {
+			continue
+		}
+		file.location = sizes[file.volume-1]
+		extentBlocks := (file.extent.Size() + int64(blockSize) - 1) / int64(blockSize)
+		if int64(file.location)+extentBlocks > math.MaxUint32 {
+			return nil, errors.New("image exceeds the size of a volume")
+		}
+		if err := file.relocateSparse(blockSize); err != nil {
+			return nil, err
+		}
+		sizes[file.volume-1] += uint32(extentBlocks)
+	}
+
+	// Partitions are recorded on the last volume at the location given by their descriptor, which has to follow
+	// everything else
+	next = sizes[len(sizes)-1]
+	for _, vpd := range vds.Partition {
+		if vpd.VolumePartitionLocation < next {
+			return nil, fmt.Errorf("partition %s at block %d overlaps the directory hierarchies and file data ending at block %d",
+				vpd.VolumePartitionIdentifier, vpd.VolumePartitionLocation, next)
+		}
+		end := uint64(vpd.VolumePartitionLocation) + uint64(vpd.VolumePartitionSize)
+		if end > math.MaxUint32 {
+			return nil, fmt.Errorf("partition %s exceeds the size of a volume", vpd.VolumePartitionIdentifier)
+		}
+		sizes[len(sizes)-1] = max(sizes[len(sizes)-1], uint32(end))
+	}
+
+	// Now that every extent has a location the directory records can be recorded
+	for _, h := range layout.hierarchies {
+		if err := h.record(layout); err != nil {
+			return nil, err
+		}
+	}
+	iso.updateDescriptors(layout)
+
+	return layout, nil
+}
+
+// updateDescriptors records the locations assigned by pack in the volume descriptors and path tables.
+func (iso *ISO9660) updateDescriptors(layout *imageLayout) {
+	vds := iso.volumeDescriptorSet
+	for i, vd := range layout.descriptors {
+		location := int64(consts.ISO9660_SYSTEM_AREA_SECTORS+i) * consts.ISO9660_SECTOR_SIZE
+		switch d := vd.(type) {
+		case *descriptor.PrimaryVolumeDescriptor:
+			d.ObjectLocation, d.ObjectSize = location, consts.ISO9660_SECTOR_SIZE
+		case *descriptor.SupplementaryVolumeDescriptor:
+			d.ObjectLocation, d.ObjectSize = location, consts.ISO9660_SECTOR_SIZE
+		case *descriptor.VolumePartitionDescriptor:
+			d.ObjectLocation, d.ObjectSize = location, consts.ISO9660_SECTOR_SIZE
+		case *descriptor.VolumeDescriptorSetTerminator:
+			d.ObjectLocation, d.ObjectSize = location, consts.ISO9660_SECTOR_SIZE
+		}
+	}
+
+	iso.setVolume(layout, layout.lastVolume())
+
+	iso.pathTables = nil
+	for i, h := range layout.hierarchies {
+		root := h.directories[0]
+		rootRecord := &directory.DirectoryRecord{
+			FileIdentifier:       "\x00",
+			LocationOfExtent:     root.location,
+			DataLength:           root.size,
+			RecordingDateAndTime: root.records[0].record.RecordingDateAndTime,
+			FileFlags:            directory.FileFlags{Directory: true},
+			VolumeSequenceNumber: layout.lastVolume(),
+		}
+
+		var records []*directory.DirectoryRecord
+		for _, dir := range h.directories {
+			for _, pr := range dir.records {
+				records = append(records, pr.record)
+			}
+		}
+
+		if i == 0 {
+			pvd := vds.Primary
+			pvd.PrimaryVolumeDescriptorBody.PathTableSize = h.pathTableSize
+			pvd.LocationOfTypeLPathTable = h.pathTableL
+			pvd.LocationOfTypeMPathTable = h.pathTableM
+			pvd.RootDirectoryRecord = rootRecord
+			pvd.DirectoryRecords = records
+			if h.rockRidge {
+				pvd.RootDirectoryRecord.RockRidge = root.records[0].rr
+			}
+		} else {
+			svd := vds.Supplementary[i-1]
+			svd.SupplementaryVolumeDescriptorBody.PathTableSize = h.pathTableSize
+			svd.LocationOfTypeLPathTable = h.pathTableL
+			svd.LocationOfTypeMPathTable = h.pathTableM
+			svd.RootDirectoryRecord = rootRecord
+			svd.DirectoryRecords = records
+		}
+
+		source := "Primary"
+		if i > 0 {
+			source = "Supplementary"
+		}
+		iso.pathTables = append(iso.pathTables,
+			pathtable.NewPathTableFromRecords(h.pathTableRecords(), h.pathTableL, layout.blockSize, source, true),
+			pathtable.NewPathTableFromRecords(h.pathTableRecords(), h.pathTableM, layout.blockSize, source, false),
+		)
+	}
+}
+
+// packedTree builds the directory tree of the files and directories of the image, directories that are only implied
+// by the paths of files are added. The files are returned in the order their extents are recorded.
+func (iso *ISO9660) packedTree() (*packedNode, []*packedNode, error) {
+	root := &packedNode{isDir: true}
+	nodes := map[string]*packedNode{"": root}
+
+	var dir func(p string) (*packedNode, error)
+	dir = func(p string) (*packedNode, error) {
+		if node, ok := nodes[p]; ok {
+			if !node.isDir {
+				return nil, fmt.Errorf("%s is both a file and a directory", p)
+			}
+			return node, nil
+		}
+		parentPath := path.Dir(p)
+		if parentPath == "." {
+			parentPath = ""
+		}
+		parent, err := dir(parentPath)
+		if err != nil {
+			return nil, err
+		}
+		node := &packedNode{name: path.Base(p), parent: parent, isDir: true}
+		parent.children = append(parent.children, node)
+		nodes[p] = node
+		return node, nil
+	}
+
+	var files []*packedNode
+	for _, entry := range iso.filesystemEntries {
+		p := strings.Trim(entry.FullPath, "/")
+		if p == "" {
+			continue
+		}
+		if entry.IsDir {
+			node, err := dir(p)
+			if err != nil {
+				return nil, nil, err
+			}
+			node.entry = entry
+			if len(entry.ExtendedAttributes) > 0 || entry.ACL != nil {
+				node.rr = &extensions.RockRidgeExtensions{ExtendedAttributes: entry.ExtendedAttributes, ACL: entry.ACL}
+			}
+			continue
+		}
+
+		if _, exists := nodes[p]; exists {
+			return nil, nil, fmt.Errorf("duplicate path %s", p)
+		}
+		parentPath := path.Dir(p)
+		if parentPath == "." {
+			parentPath = ""
+		}
+		parent, err := dir(parentPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		node := &packedNode{name: path.Base(p), entry: entry, parent: parent}
+		if err := node.prepareExtent(iso.logicalBlockSize()); err != nil {
+			return nil, nil, err
+		}
+		parent.children = append(parent.children, node)
+		nodes[p] = node
+		files = append(files, node)
+	}
+
+	return root, files, nil
+}
+
+// prepareExtent sets up the extent recorded for a file. The extent is copied as recorded, compressed and sparse files
+// stay that way, unless the file was read from sections that cannot be recorded as one extent.
+func (n *packedNode) prepareExtent(blockSize int) error {
+	entry := n.entry
+	record := entry.DirectoryRecord()
+	if record != nil && record.RockRidge != nil {
+		rr := *record.RockRidge
+		rr.ExtensionReferences, rr.Continuation = nil, nil
+		rr.ChildLinkLBA, rr.ParentLinkLBA, rr.IsRelocated = nil, nil, nil
+		n.rr = &rr
+	}
+
+	if record == nil || record.IsInterleaved() || entry.StoredSize != uint64(record.DataLength) || entry.Form2Reader() != nil {
+		content, err := entry.SectionReader()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", entry.FullPath, err)
+		}
+		if content.Size() > math.MaxUint32 {
+			return fmt.Errorf("%s exceeds the size of an extent", entry.FullPath)
+		}
+		n.extent = content
+		n.dataLength = uint32(content.Size())
+		if n.rr != nil {
+			n.rr.Zisofs, n.rr.Sparse = nil, nil
+		}
+		return nil
+	}
+
+	entryBlockSize := int64(entry.LogicalBlockSize)
+	if entryBlockSize == 0 {
+		entryBlockSize = consts.ISO9660_SECTOR_SIZE
+	}
+	n.earBlocks = record.ExtendedAttributeRecordLength
+	n.dataLength = record.DataLength
+	n.protection = record.FileFlags.Protection
+	size := int64(n.earBlocks)*int64(blockSize) + int64(n.dataLength)
+	n.extent = io.NewSectionReader(entry, int64(entry.Location)*entryBlockSize, size)
+	return nil
+}
+
+// relocateSparse rewrites the block tables of a sparse file for the location assigned to its extent. The tables hold
+// absolute logical block numbers that refer to where the extent was recorded before, for files added to the image that
+// is the pending extent at location 0.
+func (n *packedNode) relocateSparse(blockSize int) error {
+	if n.rr == nil || n.rr.Sparse == nil {
+		return nil
+	}
+	recorded, err := io.ReadAll(n.extent)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", n.entry.FullPath, err)
+	}
+	from := n.entry.Location + uint32(n.earBlocks)
+	to := n.location + uint32(n.earBlocks)
+	earSize := int(n.earBlocks) * blockSize
+	if err := sparse.Relocate(recorded[earSize:], n.rr.Sparse.TableDepth, blockSize, from, to); err != nil {
+		return fmt.Errorf("failed to relocate sparse file %s: %w", n.entry.FullPath, err)
+	}
+	n.extent = io.NewSectionReader(bytes.NewReader(recorded), 0, int64(len(recorded)))
+	return nil
+}
+
+// mode returns the POSIX mode of the node.
+func (n *packedNode) mode() fs.FileMode {
+	switch {
+	case n.entry != nil && n.isDir:
+		return n.entry.Mode | fs.ModeDir
+	case n.entry != nil:
+		return n.entry.Mode
+	case n.isDir:
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+// modTime returns the modification time of the node, directories implied by file paths use the time of packing.
+func (n *packedNode) modTime() time.Time {
+	if n.entry != nil && !n.entry.ModTime.IsZero() {
+		return n.entry.ModTime
+	}
+	return time.Now()
+}
+
+// recordsRockRidge reports whether Rock Ridge entries are recorded in the primary directory hierarchy. They are when
+// requested on create, when the image was opened with them, when a file carries entries describing its data or when a
+// file or directory carries extended attributes.
+func (iso *ISO9660) recordsRockRidge(root *packedNode, files []*packedNode) bool {
+	if iso.createOptions != nil && iso.createOptions.RockRidgeEnabled {
+		return true
+	}
+	if iso.createOptions == nil && iso.HasRockRidge() {
+		return true
+	}
+	for _, file := range files {
+		if file.rr != nil && (file.rr.Zisofs != nil || file.rr.Sparse != nil) {
+			return true
+		}
+	}
+	return recordsAttributes(root)
+}
+
+// recordsAttributes reports whether the node or any node below it carries AAIP extended attributes or an ACL.
+func recordsAttributes(node *packedNode) bool {
+	if node.rr != nil && (len(node.rr.ExtendedAttributes) > 0 || node.rr.ACL != nil) {
+		return true
+	}
+	for _, child := range node.children {
+		if recordsAttributes(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// build assigns the identifiers and directory records of every directory of the hierarchy in path table order, that
+// is level by level with the directories of each level ordered by parent and identifier.
+func (h *packedHierarchy) build(root *packedNode, blockSize int) error {
+	h.directories = []*packedDirectory{{node: root, identifier: "\x00", parent: 1}}
+	h.byNode = map[*packedNode]*packedDirectory{root: h.directories[0]}
+
+	for i := 0; i < len(h.directories); i++ {
+		dir := h.directories[i]
+		if len(h.directories) > math.MaxUint16 {
+			return errors.New("image has more directories than a path table can record")
+		}
+
+		parent := dir.node
+		if parent.parent != nil {
+			parent = parent.parent
+		}
+		dir.records = []*packedRecord{
+			h.newRecord(dir.node, "\x00", "", i == 0),
+			h.newRecord(parent, "\x01", "", false),
+		}
+
+		children, identifiers := h.identifiers(dir.node)
+		for _, child := range children {
+			dir.records = append(dir.records, h.newRecord(child, identifiers[child], child.name, false))
+			if child.isDir {
+				sub := &packedDirectory{node: child, identifier: identifiers[child], parent: uint16(i + 1)}
+				h.directories = append(h.directories, sub)
+				h.byNode[child] = sub
+			}
+		}
+
+		// Records do not cross logical sector boundaries, the extent is recorded in whole sectors
+		var offset int
+		for _, pr := range dir.records {
+			length, err := pr.length(blockSize)
+			if err != nil {
+				return err
+			}
+			if offset%consts.ISO9660_SECTOR_SIZE+length > consts.ISO9660_SECTOR_SIZE {
+				offset = (offset/consts.ISO9660_SECTOR_SIZE + 1) * consts.ISO9660_SECTOR_SIZE
+			}
+			offset += length
+		}
+		sectors := (offset + consts.ISO9660_SECTOR_SIZE - 1) / consts.ISO9660_SECTOR_SIZE
+		dir.size = uint32(sectors * consts.ISO9660_SECTOR_SIZE)
+
+		h.pathTableSize += uint32((&pathtable.PathTableRecord{DirectoryIdentifier: dir.identifier}).Len())
+	}
+	return nil
+}
+
+// newRecord creates the directory record of a node, name is recorded in the NM entry when it is not empty.
+func (h *packedHierarchy) newRecord(target *packedNode, identifier, name string, isRoot bool) *packedRecord {
+	record := &directory.DirectoryRecord{
+		FileIdentifier:       identifier,
+		RecordingDateAndTime: target.modTime(),
+		FileFlags:            directory.FileFlags{Directory: target.isDir},
+		VolumeSequenceNumber: 1,
+		Joliet:               h.joliet,
+	}
+	if !target.isDir {
+		record.ExtendedAttributeRecordLength = target.earBlocks
+		record.FileFlags.Protection = target.protection
+	}
+
+	pr := &packedRecord{record: record, target: target}
+	pr.rr = h.systemUseEntries(target, name)
+	if isRoot && h.rockRidge {
+		pr.prefix = extensions.MarshalSharingProtocol()
+		pr.rr.ExtensionReferences = h.extensions
+	}
+	return pr
+}
+
+// systemUseEntries returns the system use entries recorded for a node. Entries describing how the file data is
+// recorded (ZF and SF) are recorded in every hierarchy so the data can be read from any of them.
+func (h *packedHierarchy) systemUseEntries(target *packedNode, name string) *extensions.RockRidgeExtensions {
+	var rr extensions.RockRidgeExtensions
+	if target.rr != nil {
+		rr = *target.rr
+	}
+	if !h.rockRidge {
+		if rr.Zisofs == nil && rr.Sparse == nil {
+			return nil
+		}
+		return &extensions.RockRidgeExtensions{Zisofs: rr.Zisofs, Sparse: rr.Sparse}
+	}
+
+	mode := target.mode()
+	var uid, gid uint32
+	if target.entry != nil && target.entry.UID != nil {
+		uid = *target.entry.UID
+	}
+	if target.entry != nil && target.entry.GID != nil {
+		gid = *target.entry.GID
+	}
+	modTime := target.modTime()
+	accessTime := modTime
+	if target.entry != nil && !target.entry.AccessTime.IsZero() {
+		accessTime = target.entry.AccessTime
+	}
+	rr.Permissions, rr.UID, rr.GID = &mode, &uid, &gid
+	rr.ModificationTime, rr.AccessTime, rr.AttributeChangeTime = &modTime, &accessTime, &modTime
+	rr.AlternateName, rr.AlternateNameFlags = nil, nil
+	if name != "" {
+		rr.AlternateName = &name
+	}
+	return &rr
+}
+
+// identifiers assigns the identifiers of the children of a directory, unique within the directory, and returns the
+// children in the order their records are recorded.
+func (h *packedHierarchy) identifiers(dir *packedNode) ([]*packedNode, map[*packedNode]string) {
+	identifiers := make(map[*packedNode]string, len(dir.children))
+	used := make(map[string]bool, len(dir.children))
+	for _, child := range dir.children {
+		identifier := h.identifier(child, 0)
+		for n := 1; used[identifier]; n++ {
+			identifier = h.identifier(child, n)
+		}
+		used[identifier] = true
+		identifiers[child] = identifier
+	}
+
+	children := slices.Clone(dir.children)
+	slices.SortFunc(children, func(a, b *packedNode) int {
+		return strings.Compare(identifiers[a], identifiers[b])
+	})
+	return children, identifiers
+}
+
+// identifier returns the recorded identifier of a node, n is appended to the name to make it unique when not zero.
+func (h *packedHierarchy) identifier(node *packedNode, n int) string {
+	suffix := ""
+	if n > 0 {
+		suffix = "_" + strconv.Itoa(n)
+	}
+
+	if h.joliet {
+		name := []rune(strings.Map(func(r rune) rune {
+			if strings.ContainsRune("*/:;?\\", r) || r > 0xFFFF {
+				return '_'
+			}
+			return r
+		}, node.name))
+		name = name[:min(len(name), MAX_JOLIET_IDENTIFIER_LENGTH-utf8.RuneCountInString(suffix))]
+		return string(encoding.EncodeUCS2BigEndian(string(name) + suffix))
+	}
+
+	// Enhanced identifiers are recorded as is, truncated to whole UTF-8 sequences
+	if h.enhanced {
+		name := node.name
+		for len(name)+len(suffix) > consts.ISO9660_ENHANCED_MAX_FILE_IDENTIFIER_SIZE {
+			_, size := utf8.DecodeLastRuneInString(name)
+			name = name[:len(name)-size]
+		}
+		return name + suffix
+	}
+
+	// Level 2 identifiers of d-characters, file identifiers carry a version number
+	dCharacters := func(s string, size int) string {
+		s = strings.Map(func(r rune) rune {
+			if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+				return r
+			}
+			return '_'
+		}, strings.ToUpper(s))
+		return s[:min(len(s), size)]
+	}
+	if node.isDir {
+		return dCharacters(node.name, 31-len(suffix)) + suffix
+	}
+	base, ext := node.name, ""
+	if i := strings.LastIndex(node.name, "."); i > 0 {
+		base, ext = node.name[:i], node.name[i+1:]
+	}
+	ext = dCharacters(ext, 8)
+	base = dCharacters(base, 30-len(ext)-len(suffix)) + suffix
+	return base + "." + ext + ";1"
+}
+
+// length returns the length of the record once its system use entries have been placed, entries that do not fit are
+// moved to a continuation area whose length is set.
+func (pr *packedRecord) length(blockSize int) (int, error) {
+	systemUse, area, err := pr.systemUse()
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode system use entries of %s: %w", pr.target.name, err)
+	}
+	if len(area) > blockSize {
+		return 0, fmt.Errorf("system use entries of %s exceed a logical block", pr.target.name)
+	}
+	pr.continuation.Length = uint32(len(area))
+	pr.record.SystemUse = systemUse
+	return pr.record.Len(), nil
+}
+
+// systemUse encodes the system use entries recorded in the directory record and in its continuation area. Extension
+// references are moved to the continuation area first, everything else follows if the record is still too long.
+func (pr *packedRecord) systemUse() ([]byte, []byte, error) {
+	if pr.rr == nil {
+		return pr.prefix, nil, nil
+	}
+	room := MAX_DIRECTORY_RECORD_LENGTH - (&directory.DirectoryRecord{FileIdentifier: pr.record.FileIdentifier}).Len()
+
+	all, err := extensions.MarshalRockRidge(pr.rr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(pr.prefix)+len(all) <= room {
+		return slices.Concat(pr.prefix, all), nil, nil
+	}
+
+	ce := pr.continuation
+	if len(pr.rr.ExtensionReferences) > 0 {
+		area, err := extensions.MarshalRockRidge(&extensions.RockRidgeExtensions{ExtensionReferences: pr.rr.ExtensionReferences})
+		if err != nil {
+			return nil, nil, err
+		}
+		head := *pr.rr
+		head.ExtensionReferences = nil
+		head.Continuation = &ce
+		head.Continuation.Length = uint32(len(area))
+		inRecord, err := extensions.MarshalRockRidge(&head)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(pr.prefix)+len(inRecord) <= room {
+			return slices.Concat(pr.prefix, inRecord), area, nil
+		}
+	}
+
+	ce.Length = uint32(len(all))
+	inRecord, err := extensions.MarshalRockRidge(&extensions.RockRidgeExtensions{Continuation: &ce})
+	if err != nil {
+		return nil, nil, err
+	}
+	return slices.Concat(pr.prefix, inRecord), all, nil
+}
+
+// record fills in the locations of the directory records of the hierarchy and encodes its directory extents.
+func (h *packedHierarchy) record(layout *imageLayout) error {
+	blockSize := int64(layout.blockSize)
+	for _, dir := range h.directories {
+		dir.data = make([]byte, dir.size)
+		offset := 0
+		for _, pr := range dir.records {
+			if pr.target.isDir {
+				sub := h.byNode[pr.target]
+				pr.record.LocationOfExtent, pr.record.DataLength = sub.location, sub.size
+				pr.record.VolumeSequenceNumber = layout.lastVolume()
+			} else {
+				pr.record.LocationOfExtent, pr.record.DataLength = pr.target.location, pr.target.dataLength
+				pr.record.VolumeSequenceNumber = pr.target.volume
+			}
+
+			systemUse, area, err := pr.systemUse()
+			if err != nil {
+				return fmt.Errorf("failed to encode system use entries of %s: %w", pr.target.name, err)
+			}
+			pr.record.SystemUse = systemUse
+			if len(area) > 0 {
+				start := int64(pr.continuation.Block-layout.continuation)*blockSize + int64(pr.continuation.Offset)
+				copy(layout.continuationData[start:], area)
+			}
+
+			data, err := pr.record.Marshal()
+			if err != nil {
+				return fmt.Errorf("failed to encode directory record of %s: %w", pr.target.name, err)
+			}
+			if offset%consts.ISO9660_SECTOR_SIZE+len(data) > consts.ISO9660_SECTOR_SIZE {
+				offset = (offset/consts.ISO9660_SECTOR_SIZE + 1) * consts.ISO9660_SECTOR_SIZE
+			}
+			copy(dir.data[offset:], data)
+			pr.record.ObjectLocation = int64(dir.location)*blockSize + int64(offset)
+			pr.record.ObjectSize = uint32(len(data))
+			offset += len(data)
+		}
+	}
+	return nil
+}
+
+// setVolume records the volume sequence number and the size of a volume of the layout in the volume descriptors.
+func (iso *ISO9660) setVolume(layout *imageLayout, sequenceNumber uint16) {
+	vds := iso.volumeDescriptorSet
+	size := layout.volumeSpaceSizes[sequenceNumber-1]
+	vds.Primary.VolumeSequenceNumber = sequenceNumber
+	vds.Primary.VolumeSpaceSize = size
+	for _, svd := range vds.Supplementary {
+		svd.VolumeSequenceNumber = encoding.MarshalBothByteOrders16(sequenceNumber)
+		svd.VolumeSpaceSize = encoding.MarshalBothByteOrders32(size)
+	}
+}
+
+// pathTableRecords returns the path table records of the hierarchy in path table order.
+func (h *packedHierarchy) pathTableRecords() []*pathtable.PathTableRecord {
+	records := make([]*pathtable.PathTableRecord, 0, len(h.directories))
+	for _, dir := range h.directories {
+		records = append(records, &pathtable.PathTableRecord{
+			LengthOfDirectoryIdentifier: uint8(len(dir.identifier)),
+			LocationOfExtent:            dir.location,
+			ParentDirectoryNumber:       dir.parent,
+			DirectoryIdentifier:         dir.identifier,
+		})
+	}
+	return records
+}
+
+// writeZeros writes size zero bytes at offset.
+func writeZeros(writer io.WriterAt, offset, size int64) error {
+	zeros := make([]byte, min(size, consts.ISO9660_SECTOR_SIZE*16))
+	for size > 0 {
+		n := min(size, int64(len(zeros)))
+		if _, err := writer.WriteAt(zeros[:n], offset); err != nil {
+			return err
+		}
+		offset += n
+		size -= n
+	}
+	return nil
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/rstms/iso-kit/pkg/iso9660/extent"
 	"github.com/rstms/iso-kit/pkg/iso9660/info"
 	"github.com/rstms/iso-kit/pkg/iso9660/pathtable"
+	"github.com/rstms/iso-kit/pkg/iso9660/validation"
+	"github.com/rstms/iso-kit/pkg/iso9660/xattr"
 	"github.com/rstms/iso-kit/pkg/logging"
 	"github.com/rstms/iso-kit/pkg/option"
 	"io"
@@ -21,9 +23,10 @@ import (
 // NewParser creates a new Parser object with the provided reader and options.
 func NewParser(reader io.ReaderAt, options *option.OpenOptions) *Parser {
 	return &Parser{
-		reader:  reader,
-		options: options,
-		logger:  options.Logger,
+		reader:    reader,
+		options:   options,
+		logger:    options.Logger,
+		blockSize: consts.ISO9660_SECTOR_SIZE,
 	}
 }
 
@@ -33,6 +36,36 @@ type Parser struct {
 	options *option.OpenOptions
 	logger  *logging.Logger
 	layout  *info.ISOLayout
+	// volumes holds the other volumes of a volume set by volume sequence number
+	volumes map[uint16]io.ReaderAt
+	// blockSize is the logical block size of the volume recorded in the primary volume descriptor
+	blockSize int
+}
+
+// LogicalBlockSize returns the logical block size used to convert logical block numbers into byte offsets. It is
+// ISO9660_SECTOR_SIZE until the primary volume descriptor has been read.
+func (p *Parser) LogicalBlockSize() int {
+	return p.blockSize
+}
+
+// blockOffset returns the byte offset of a logical block.
+func (p *Parser) blockOffset(lba uint32) int64 {
+	return int64(lba) * int64(p.blockSize)
+}
+
+// SetVolumeSet sets the volumes of the volume set by volume sequence number. File extents recorded with the sequence
+// number of one of the volumes are read from it instead of the parser's reader.
+func (p *Parser) SetVolumeSet(volumes map[uint16]io.ReaderAt) {
+	p.volumes = volumes
+}
+
+// volumeReader returns the reader of the volume with the given sequence number, the parser's reader if the volume is
+// not part of the volume set.
+func (p *Parser) volumeReader(sequenceNumber uint16) io.ReaderAt {
+	if r, ok := p.volumes[sequenceNumber]; ok {
+		return r
+	}
+	return p.reader
 }
 
 // GetBootRecord reads and validates the ISO9660 boot record.
@@ -152,6 +185,12 @@ func (p *Parser) GetPrimaryVolumeDescriptor() (*descriptor.PrimaryVolumeDescript
 		return nil, err
 	}
 
+	// Logical block numbers recorded in the volume are counted in the logical block size of the volume
+	if err = validation.ValidateLogicalBlockSize(int(pvd.LogicalBlockSize)); err != nil {
+		return nil, err
+	}
+	p.blockSize = int(pvd.LogicalBlockSize)
+
 	return pvd, nil
 }
 
@@ -242,13 +281,15 @@ func (p *Parser) GetVolumePartitionDescriptors() ([]*descriptor.VolumePartitionD
 
 		// A Volume Descriptor Set Terminator has type 255.
 		if header.VolumeDescriptorType == descriptor.TYPE_TERMINATOR_DESCRIPTOR {
-			p.logger.Debug("No volume partition descriptors found")
+			if len(vpds) == 0 {
+				p.logger.Debug("No volume partition descriptors found")
+			}
 			return vpds, nil
 		}
 
 		// If this is a Volume Partition Descriptor, unmarshal it and add it to the collection.
 		if header.VolumeDescriptorType == descriptor.TYPE_PARTITION_DESCRIPTOR {
-			p.logger.Info("Reading volume partition descriptor", "offset", consts.ISO9660_SYSTEM_AREA_SECTORS*consts.ISO9660_SECTOR_SIZE)
+			p.logger.Info("Reading volume partition descriptor", "offset", offset)
 			vpd := &descriptor.VolumePartitionDescriptor{
 				VolumeDescriptorHeader: header,
 				VolumePartitionDescriptorBody: descriptor.VolumePartitionDescriptorBody{
@@ -324,11 +365,11 @@ func (p *Parser) GetVolumeDescriptorSetTerminator() (*descriptor.VolumeDescripto
 
 // GetPathTables reads and validates the ISO9660 Path Tables.
 func (p *Parser) GetPathTables(vd descriptor.VolumeDescriptor) ([]*pathtable.PathTable, error) {
-	ptL, err := pathtable.NewPathTable(p.reader, vd.LocationOfPathTableL(), int(vd.PathTableSize()), vd.DescriptorType().String(), true)
+	ptL, err := pathtable.NewPathTable(p.reader, vd.LocationOfPathTableL(), int(vd.PathTableSize()), p.blockSize, vd.DescriptorType().String(), true)
 	if err != nil {
 		return nil, err
 	}
-	ptM, err := pathtable.NewPathTable(p.reader, vd.LocationOfPathTableM(), int(vd.PathTableSize()), vd.DescriptorType().String(), false)
+	ptM, err := pathtable.NewPathTable(p.reader, vd.LocationOfPathTableM(), int(vd.PathTableSize()), p.blockSize, vd.DescriptorType().String(), false)
 	if err != nil {
 		return nil, err
 	}
@@ -352,13 +393,31 @@ func (p *Parser) BuildFileSystemEntries(rootDir *directory.DirectoryRecord, Rock
 		visited[dir.LocationOfExtent] = true
 
 		// Read directory records
-		dirRecords, err := p.ReadDirectoryRecords(dir.LocationOfExtent, dir.DataLength, rootDir.Joliet)
+		dirRecords, err := p.ReadDirectoryRecords(dir.DataLocation(), dir.DataLength, rootDir.Joliet)
 		p.logger.Trace("Finished reading directory records", "dir", dir.GetBestName(RockRidgeEnabled), "records", len(dirRecords))
 		if err != nil {
 			return err
 		}
 
+		// Associated files share the identifier of the file they belong to and are linked to it once the whole
+		// directory has been read
+		var dirEntries, associatedEntries []*filesystem.FileSystemEntry
+		associated := make(map[string]*filesystem.FileSystemEntry)
+
+		// Files larger than an extent are recorded in consecutive records with the same identifier, all but the last
+		// with the multi-extent flag set
+		var multiExtent *filesystem.FileSystemEntry
+
 		for _, record := range dirRecords {
+			if multiExtent != nil && multiExtent.DirectoryRecord().FileIdentifier == record.FileIdentifier {
+				multiExtent.AddExtent(record, p.volumeReader(record.VolumeSequenceNumber))
+				if !record.FileFlags.MultiExtent {
+					multiExtent = nil
+				}
+				continue
+			}
+			multiExtent = nil
+
 			// Build full path
 			fullPath := parentPath + "/" + record.GetBestName(RockRidgeEnabled)
 
@@ -367,6 +426,12 @@ func (p *Parser) BuildFileSystemEntries(rootDir *directory.DirectoryRecord, Rock
 			uid, gid := record.GetOwnership(RockRidgeEnabled)
 			creationTime, modificationTime := record.GetTimestamps(RockRidgeEnabled)
 
+			// zisofs compressed files report their uncompressed size
+			var zf *extensions.ZisofsInfo
+			if p.options.ZisofsEnabled && record.RockRidge != nil && record.RockRidge.Zisofs != nil && !record.IsDirectory() {
+				zf = record.RockRidge.Zisofs
+			}
+
 			// Create FileSystemEntry
 			entry := filesystem.NewFileSystemEntry(
 				record.GetBestName(RockRidgeEnabled),
@@ -380,16 +445,47 @@ func (p *Parser) BuildFileSystemEntries(rootDir *directory.DirectoryRecord, Rock
 				creationTime,
 				modificationTime,
 				record,
-				p.reader,
+				p.volumeReader(record.VolumeSequenceNumber),
 			)
+			entry.AccessTime = record.GetAccessTime(RockRidgeEnabled)
+			entry.LogicalBlockSize = uint16(p.blockSize)
+			if zf != nil {
+				entry.Zisofs = zf
+				entry.Size = zf.UncompressedSize
+			}
+			// Sparse files report their apparent size
+			if record.RockRidge != nil && record.RockRidge.Sparse != nil && !record.IsDirectory() {
+				entry.Sparse = record.RockRidge.Sparse
+				entry.Size = entry.Sparse.VirtualSize
+			}
+			if record.RockRidge != nil {
+				entry.ExtendedAttributes = record.RockRidge.ExtendedAttributes
+				entry.ACL = record.RockRidge.ACL
+				entry.Apple = record.RockRidge.Apple
+			}
+			// Form 2 files read from raw images report the size of their full sector payloads
+			entry.XA = record.XA
+			if fr := entry.Form2Reader(); fr != nil {
+				entry.Size = uint64(fr.Size())
+			}
 			p.logger.Trace("Created FileSystemEntry", "path", fullPath, "location", record.LocationOfExtent)
+			if record.FileFlags.MultiExtent && !record.IsDirectory() {
+				multiExtent = entry
+			}
 
 			// Filter out root and parent entries4
 			if len(record.FileIdentifier) == 0 || record.FileIdentifier[0] == 0x00 || record.FileIdentifier[0] == 0x01 {
 				continue
 			}
 
+			if record.FileFlags.AssociatedFile {
+				associated[record.FileIdentifier] = entry
+				associatedEntries = append(associatedEntries, entry)
+				continue
+			}
+
 			entries = append(entries, entry)
+			dirEntries = append(dirEntries, entry)
 
 			// Recursively walk directories
 			if record.IsDirectory() && !record.IsSpecial() {
@@ -398,6 +494,21 @@ func (p *Parser) BuildFileSystemEntries(rootDir *directory.DirectoryRecord, Rock
 				}
 			}
 		}
+
+		for _, entry := range dirEntries {
+			if fork, ok := associated[entry.DirectoryRecord().FileIdentifier]; ok {
+				entry.AssociatedFile = fork
+				delete(associated, entry.DirectoryRecord().FileIdentifier)
+			}
+		}
+		// Associated files without a matching file are kept as ordinary entries so their data is not lost
+		for _, entry := range associatedEntries {
+			if _, ok := associated[entry.DirectoryRecord().FileIdentifier]; !ok {
+				continue
+			}
+			p.logger.Debug("Associated file without a matching file", "path", entry.FullPath)
+			entries = append(entries, entry)
+		}
 		return nil
 	}
 
@@ -430,7 +541,7 @@ func (p *Parser) WalkDirectoryRecords(rootDir *directory.DirectoryRecord) ([]*di
 		visited[dir.LocationOfExtent] = true
 
 		// Read directory records from this LBA
-		dirRecords, err := p.ReadDirectoryRecords(dir.LocationOfExtent, dir.DataLength, rootDir.Joliet)
+		dirRecords, err := p.ReadDirectoryRecords(dir.DataLocation(), dir.DataLength, rootDir.Joliet)
 		if err != nil {
 			return err
 		}
@@ -447,10 +558,13 @@ func (p *Parser) WalkDirectoryRecords(rootDir *directory.DirectoryRecord) ([]*di
 			} else {
 
 				fe := &extent.FileExtent{
-					FileIdentifier: record.GetBestName(p.options.RockRidgeEnabled),
-					LocationOfFile: record.LocationOfExtent,
-					SizeOfFile:     record.DataLength,
-					Reader:         p.reader,
+					FileIdentifier:    record.GetBestName(p.options.RockRidgeEnabled),
+					LocationOfFile:    record.DataLocation(),
+					SizeOfFile:        record.DataLength,
+					FileUnitSize:      record.FileUnitSize,
+					InterleaveGapSize: record.InterleaveGapSize,
+					LogicalBlockSize:  uint16(p.blockSize),
+					Reader:            p.volumeReader(record.VolumeSequenceNumber),
 				}
 				record.FileExtent = fe
 
@@ -472,8 +586,9 @@ func (p *Parser) WalkDirectoryRecords(rootDir *directory.DirectoryRecord) ([]*di
 // and processes Rock Ridge extensions if present.
 func (p *Parser) ReadDirectoryRecords(lba uint32, dataLength uint32, joliet bool) ([]*directory.DirectoryRecord, error) {
 
+	// Directory records do not cross logical sector boundaries, the extent itself starts on a logical block
 	sectorSize := consts.ISO9660_SECTOR_SIZE
-	offset := int64(lba) * int64(sectorSize)
+	offset := p.blockOffset(lba)
 	totalBytes := int(dataLength)
 
 	buf := make([]byte, totalBytes)
@@ -527,11 +642,30 @@ func (p *Parser) ReadDirectoryRecords(lba uint32, dataLength uint32, joliet bool
 		dr.ObjectSize = dr.DataLength
 
 		// **Parse Rock Ridge extensions if present**
+		// CD-ROM XA records precede any SUSP entries
+		systemUse := dr.SystemUse
+		if xa, ok := extensions.UnmarshalXA(systemUse); ok {
+			dr.XA = xa
+			systemUse = systemUse[extensions.XA_RECORD_SIZE:]
+		}
+
 		var rr *extensions.RockRidgeExtensions
-		if len(dr.SystemUse) > 0 {
-			rr, err = extensions.UnmarshalRockRidge(dr.SystemUse)
+		if len(systemUse) > 0 {
+			rr, err = p.readSystemUse(systemUse)
 			if err == nil {
 				dr.RockRidge = rr
+			} else {
+				p.logger.Error(err, "Failed to read system use entries", "identifier", dr.FileIdentifier)
+			}
+		}
+
+		// Extended Attribute Records are recorded in the blocks preceding the data of the extent
+		if dr.ExtendedAttributeRecordLength > 0 && !dr.IsSpecial() {
+			ear, err := p.readExtendedAttributeRecord(dr)
+			if err != nil {
+				p.logger.Error(err, "Failed to read extended attribute record", "identifier", dr.FileIdentifier)
+			} else {
+				dr.ExtendedAttributeRecord = ear
 			}
 		}
 
@@ -545,3 +679,56 @@ func (p *Parser) ReadDirectoryRecords(lba uint32, dataLength uint32, joliet bool
 	p.logger.Debug("Finished reading directory records", "sector", lba, "records", len(records))
 	return records, nil
 }
+
+// readSystemUse decodes the system use entries of a directory record. Entries which did not fit into the record are
+// recorded in continuation areas referenced by CE entries, those are read and decoded together with the record.
+func (p *Parser) readSystemUse(systemUse []byte) (*extensions.RockRidgeExtensions, error) {
+	rr, err := extensions.UnmarshalRockRidge(systemUse)
+	if err != nil {
+		return nil, err
+	}
+
+	var areas [][]byte
+	ce := rr.Continuation
+	// Bound the number of continuation areas so a CE loop in a corrupt image cannot hang the parser
+	for ce != nil && len(areas) < 64 {
+		if ce.Length == 0 || int(ce.Offset+ce.Length) > p.blockSize {
+			return nil, fmt.Errorf("invalid continuation area at LBA %d offset %d length %d", ce.Block, ce.Offset, ce.Length)
+		}
+		area := make([]byte, ce.Length)
+		offset := p.blockOffset(ce.Block) + int64(ce.Offset)
+		if _, err := p.reader.ReadAt(area, offset); err != nil {
+			return nil, fmt.Errorf("failed to read continuation area at LBA %d: %w", ce.Block, err)
+		}
+		areas = append(areas, area)
+
+		next, err := extensions.UnmarshalRockRidge(area)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse continuation area at LBA %d: %w", ce.Block, err)
+		}
+		ce = next.Continuation
+	}
+
+	if len(areas) == 0 {
+		return rr, nil
+	}
+	return extensions.UnmarshalRockRidge(systemUse, areas...)
+}
+
+// readExtendedAttributeRecord reads the Extended Attribute Record recorded at the start of a record's extent.
+func (p *Parser) readExtendedAttributeRecord(dr *directory.DirectoryRecord) (*xattr.ExtendedAttributeRecord, error) {
+	offset := p.blockOffset(dr.LocationOfExtent)
+	buf := make([]byte, int(dr.ExtendedAttributeRecordLength)*p.blockSize)
+	if _, err := p.volumeReader(dr.VolumeSequenceNumber).ReadAt(buf, offset); err != nil {
+		return nil, fmt.Errorf("failed to read extended attribute record at LBA %d: %w", dr.LocationOfExtent, err)
+	}
+
+	ear := &xattr.ExtendedAttributeRecord{}
+	if err := ear.Unmarshal(buf); err != nil {
+		return nil, fmt.Errorf("failed to parse extended attribute record at LBA %d: %w", dr.LocationOfExtent, err)
+	}
+	ear.ObjectLocation = offset
+	ear.ObjectSize = uint32(len(buf))
+
+	return ear, nil
+}
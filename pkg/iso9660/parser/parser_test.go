@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/rstms/iso-kit/pkg/consts"
+	"github.com/rstms/iso-kit/pkg/iso9660/descriptor"
+	"github.com/rstms/iso-kit/pkg/iso9660/directory"
+	"github.com/rstms/iso-kit/pkg/iso9660/extensions"
+	"github.com/rstms/iso-kit/pkg/iso9660/xattr"
+	"github.com/rstms/iso-kit/pkg/logging"
+	"github.com/rstms/iso-kit/pkg/option"
+	"github.com/stretchr/testify/require"
+)
+
+// blockSizeImage builds a volume with 512 byte logical blocks holding a single file whose data does not start on a
+// sector boundary. When ear is set it is recorded in the logical block preceding the file data.
+func blockSizeImage(t *testing.T, content string, ear *xattr.ExtendedAttributeRecord) []byte {
+	const blockSize = 512
+	image := make([]byte, 80*blockSize)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	record := func(identifier string, location, length uint32, dir bool, earLength uint8) []byte {
+		dr := &directory.DirectoryRecord{
+			ExtendedAttributeRecordLength: earLength,
+			LocationOfExtent:              location,
+			DataLength:                    length,
+			RecordingDateAndTime:          now,
+			FileFlags:                     directory.FileFlags{Directory: dir},
+			VolumeSequenceNumber:          1,
+			LengthOfFileIdentifier:        uint8(len(identifier)),
+			FileIdentifier:                identifier,
+		}
+		data, err := dr.Marshal()
+		require.NoError(t, err)
+		return data
+	}
+
+	// The root directory fills sector 18, which is logical block 72
+	root := &directory.DirectoryRecord{
+		LocationOfExtent:     72,
+		DataLength:           consts.ISO9660_SECTOR_SIZE,
+		RecordingDateAndTime: now,
+		FileFlags:            directory.FileFlags{Directory: true},
+		VolumeSequenceNumber: 1,
+		FileIdentifier:       "\x00",
+	}
+	pvd := &descriptor.PrimaryVolumeDescriptor{
+		VolumeDescriptorHeader: descriptor.VolumeDescriptorHeader{
+			VolumeDescriptorType:    descriptor.TYPE_PRIMARY_DESCRIPTOR,
+			StandardIdentifier:      consts.ISO9660_STD_IDENTIFIER,
+			VolumeDescriptorVersion: consts.ISO9660_VOLUME_DESC_VERSION,
+		},
+		PrimaryVolumeDescriptorBody: descriptor.PrimaryVolumeDescriptorBody{
+			VolumeIdentifier:              "BLOCKS",
+			VolumeSpaceSize:               80,
+			VolumeSetSize:                 1,
+			VolumeSequenceNumber:          1,
+			LogicalBlockSize:              blockSize,
+			RootDirectoryRecord:           root,
+			VolumeCreationDateAndTime:     now,
+			VolumeModificationDateAndTime: now,
+			VolumeEffectiveDateAndTime:    now,
+			FileStructureVersion:          1,
+		},
+	}
+	data, err := pvd.Marshal()
+	require.NoError(t, err)
+	copy(image[16*consts.ISO9660_SECTOR_SIZE:], data[:])
+	data, err = descriptor.NewVolumeDescriptorSetTerminator().Marshal()
+	require.NoError(t, err)
+	copy(image[17*consts.ISO9660_SECTOR_SIZE:], data[:])
+
+	dir := append(record("\x00", 72, consts.ISO9660_SECTOR_SIZE, true, 0), record("\x01", 72, consts.ISO9660_SECTOR_SIZE, true, 0)...)
+	if ear != nil {
+		data, err := ear.Marshal()
+		require.NoError(t, err)
+		copy(image[76*blockSize:], data)
+		dir = append(dir, record("FILE.TXT;1", 76, uint32(len(content)), false, 1)...)
+	} else {
+		dir = append(dir, record("FILE.TXT;1", 77, uint32(len(content)), false, 0)...)
+	}
+	copy(image[72*blockSize:], dir)
+	copy(image[77*blockSize:], content)
+	return image
+}
+
+func TestLogicalBlockSize(t *testing.T) {
+	const content = "logical blocks of 512 bytes"
+	p := NewParser(bytes.NewReader(blockSizeImage(t, content, nil)), &option.OpenOptions{Logger: logging.DefaultLogger()})
+	require.Equal(t, consts.ISO9660_SECTOR_SIZE, p.LogicalBlockSize())
+
+	pvd, err := p.GetPrimaryVolumeDescriptor()
+	require.NoError(t, err)
+	require.Equal(t, 512, p.LogicalBlockSize())
+
+	entries, err := p.BuildFileSystemEntries(pvd.RootDirectoryRecord, false)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, uint32(77), entries[0].Location)
+
+	r, err := entries[0].ContentReader()
+	require.NoError(t, err)
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, int64(entries[0].Size)))
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+}
+
+func TestExtendedAttributeRecord(t *testing.T) {
+	const content = "data after the extended attribute record"
+	recorded := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ear := &xattr.ExtendedAttributeRecord{
+		OwnerIdentification:            1000,
+		GroupIdentification:            100,
+		Permissions:                    xattr.NewExtendedAttrPermissions(0o640),
+		FileCreationDateAndTime:        recorded,
+		FileModificationDateAndTime:    recorded,
+		ExtendedAttributeRecordVersion: 1,
+	}
+	p := NewParser(bytes.NewReader(blockSizeImage(t, content, ear)), &option.OpenOptions{Logger: logging.DefaultLogger()})
+
+	pvd, err := p.GetPrimaryVolumeDescriptor()
+	require.NoError(t, err)
+	records, err := p.ReadDirectoryRecords(pvd.RootDirectoryRecord.LocationOfExtent, pvd.RootDirectoryRecord.DataLength, false)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	dr := records[2]
+	require.Equal(t, uint32(77), dr.DataLocation())
+	require.NotNil(t, dr.ExtendedAttributeRecord)
+	require.Equal(t, uint16(1000), dr.ExtendedAttributeRecord.OwnerIdentification)
+	require.Equal(t, uint16(100), dr.ExtendedAttributeRecord.GroupIdentification)
+	require.Equal(t, ear.Permissions, dr.ExtendedAttributeRecord.Permissions)
+	require.Equal(t, int64(76*512), dr.ExtendedAttributeRecord.Offset())
+
+	read, err := p.readExtendedAttributeRecord(dr)
+	require.NoError(t, err)
+	require.Equal(t, dr.ExtendedAttributeRecord, read)
+
+	entries, err := p.BuildFileSystemEntries(pvd.RootDirectoryRecord, false)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	data, err := entries[0].GetBytes()
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+}
+
+func TestReadSystemUseContinuation(t *testing.T) {
+	image := make([]byte, 22*consts.ISO9660_SECTOR_SIZE)
+	marshal := func(rr *extensions.RockRidgeExtensions) []byte {
+		data, err := extensions.MarshalRockRidge(rr)
+		require.NoError(t, err)
+		return data
+	}
+
+	// The record holds PX and a CE pointing into block 20, that area continues in block 21
+	name := "a name recorded in a continuation area"
+	attrs := []extensions.ExtendedAttribute{{Name: "user.comment", Value: []byte("second continuation area")}}
+	last := marshal(&extensions.RockRidgeExtensions{ExtendedAttributes: attrs})
+	copy(image[21*consts.ISO9660_SECTOR_SIZE:], last)
+	first := marshal(&extensions.RockRidgeExtensions{
+		AlternateName: &name,
+		Continuation:  &extensions.ContinuationArea{Block: 21, Offset: 0, Length: uint32(len(last))},
+	})
+	copy(image[20*consts.ISO9660_SECTOR_SIZE+100:], first)
+
+	mode := fs.FileMode(0o644)
+	uid, gid := uint32(1000), uint32(100)
+	systemUse := marshal(&extensions.RockRidgeExtensions{
+		Permissions:  &mode,
+		UID:          &uid,
+		GID:          &gid,
+		Continuation: &extensions.ContinuationArea{Block: 20, Offset: 100, Length: uint32(len(first))},
+	})
+
+	p := NewParser(bytes.NewReader(image), &option.OpenOptions{Logger: logging.DefaultLogger()})
+	rr, err := p.readSystemUse(systemUse)
+	require.NoError(t, err)
+	require.NotNil(t, rr.Permissions)
+	require.Equal(t, mode, *rr.Permissions&fs.ModePerm)
+	require.NotNil(t, rr.AlternateName)
+	require.Equal(t, name, *rr.AlternateName)
+	require.Equal(t, attrs, rr.ExtendedAttributes)
+
+	// A continuation area extending past the end of its block is rejected
+	systemUse = marshal(&extensions.RockRidgeExtensions{
+		Continuation: &extensions.ContinuationArea{Block: 20, Offset: 2000, Length: 100},
+	})
+	_, err = p.readSystemUse(systemUse)
+	require.Error(t, err)
+}
@@ -8,9 +8,9 @@ import (
 	"io"
 )
 
-func NewPathTable(reader io.ReaderAt, location uint32, size int, source string, littleEndian bool) (*PathTable, error) {
+func NewPathTable(reader io.ReaderAt, location uint32, size int, blockSize int, source string, littleEndian bool) (*PathTable, error) {
 	data := make([]byte, size)
-	_, err := reader.ReadAt(data, int64(location)*consts.ISO9660_SECTOR_SIZE)
+	_, err := reader.ReadAt(data, int64(location)*int64(blockSize))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read path table: %w", err)
 	}
@@ -20,6 +20,7 @@ func NewPathTable(reader io.ReaderAt, location uint32, size int, source string,
 		littleEndian:   littleEndian,
 		ObjectLocation: int64(location),
 		ObjectSize:     uint32(size),
+		blockSize:      int64(blockSize),
 	}
 	offset := 0
 
@@ -43,6 +44,23 @@ func NewPathTable(reader io.ReaderAt, location uint32, size int, source string,
 	return pt, nil
 }
 
+// NewPathTableFromRecords creates a path table for writing from records in path table order, the table is recorded at
+// logical block location.
+func NewPathTableFromRecords(records []*PathTableRecord, location uint32, blockSize int, source string, littleEndian bool) *PathTable {
+	pt := &PathTable{
+		Records:        records,
+		source:         source,
+		littleEndian:   littleEndian,
+		ObjectLocation: int64(location),
+		blockSize:      int64(blockSize),
+	}
+	for _, record := range records {
+		record.littleEndian = littleEndian
+		pt.ObjectSize += uint32(record.Len())
+	}
+	return pt
+}
+
 // PathTable represents a full path table, containing multiple records.
 type PathTable struct {
 	Records      []*PathTableRecord
@@ -53,6 +71,8 @@ type PathTable struct {
 	ObjectLocation int64 `json:"object_location"`
 	// Object Size (in bytes)
 	ObjectSize uint32 `json:"object_size"`
+	// blockSize is the logical block size of the volume the table is recorded in
+	blockSize int64
 }
 
 func (pt *PathTable) Type() string {
@@ -85,7 +105,10 @@ func (pt *PathTable) Properties() map[string]interface{} {
 }
 
 func (pt *PathTable) Offset() int64 {
-	return pt.ObjectLocation * consts.ISO9660_SECTOR_SIZE
+	if pt.blockSize == 0 {
+		return pt.ObjectLocation * consts.ISO9660_SECTOR_SIZE
+	}
+	return pt.ObjectLocation * pt.blockSize
 }
 
 func (pt *PathTable) Size() int {
@@ -143,6 +166,8 @@ type PathTableRecord struct {
 	ObjectLocation int64 `json:"object_location"`
 	// Object Size (in bytes)
 	ObjectSize uint32 `json:"object_size"`
+	// blockSize is the logical block size of the volume the table is recorded in
+	blockSize int64
 }
 
 func (ptr *PathTableRecord) Type() string {
@@ -176,6 +201,11 @@ func (ptr *PathTableRecord) GetObjects() []info.ImageObject {
 	return []info.ImageObject{ptr}
 }
 
+// Len returns the recorded length of the record including the padding byte.
+func (ptr *PathTableRecord) Len() int {
+	return 8 + len(ptr.DirectoryIdentifier) + len(ptr.DirectoryIdentifier)%2
+}
+
 // Marshal converts a single PathTableRecord into a byte slice.
 func (ptr *PathTableRecord) Marshal() ([]byte, error) {
 	dirIDBytes := []byte(ptr.DirectoryIdentifier)
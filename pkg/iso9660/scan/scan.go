@@ -0,0 +1,96 @@
+package scan
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/rstms/iso-kit/pkg/consts"
+	"github.com/rstms/iso-kit/pkg/iso9660/descriptor"
+	"github.com/rstms/iso-kit/pkg/iso9660/parser"
+	"github.com/rstms/iso-kit/pkg/logging"
+	"github.com/rstms/iso-kit/pkg/option"
+)
+
+// ALIGNMENT is the granularity at which filesystems are searched for. Disk image partitions start on 512 byte
+// boundaries so this finds ISO9660 filesystems in partitions as well as ones placed after headers of whole sectors.
+const ALIGNMENT = 512
+
+// chunkSize is the number of bytes read at a time while scanning.
+const chunkSize = 1024 * 1024
+
+// pvdSignature starts a primary volume descriptor, the descriptor type followed by the standard identifier and version.
+var pvdSignature = append(append([]byte{byte(descriptor.TYPE_PRIMARY_DESCRIPTOR)}, consts.ISO9660_STD_IDENTIFIER...), 1)
+
+// Candidate is an ISO9660 filesystem found in a larger image.
+type Candidate struct {
+	// Offset is the byte offset of the start of the filesystem, its system area, within the image
+	Offset int64 `json:"offset"`
+	// VolumeIdentifier is the volume identifier of the primary volume descriptor
+	VolumeIdentifier string `json:"volume_identifier"`
+	// VolumeSpaceSize is the size of the filesystem in logical blocks
+	VolumeSpaceSize uint32 `json:"volume_space_size"`
+	// LogicalBlockSize is the logical block size of the filesystem in bytes
+	LogicalBlockSize uint16 `json:"logical_block_size"`
+	// Descriptors is the number of volume descriptors in the set, including the terminator
+	Descriptors int `json:"descriptors"`
+	// Terminated is set when the volume descriptor set ends with a volume descriptor set terminator
+	Terminated bool `json:"terminated"`
+	// Truncated is set when the filesystem extends past the end of the image, files in the missing part are lost
+	Truncated bool `json:"truncated"`
+	// Problems lists the validation checks the filesystem failed
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Size returns the size of the filesystem in bytes.
+func (c *Candidate) Size() int64 {
+	return int64(c.VolumeSpaceSize) * int64(c.LogicalBlockSize)
+}
+
+// Scan searches the first size bytes of the reader for primary volume descriptors at ALIGNMENT byte boundaries and
+// returns a candidate for every one that parses. Filesystems nested inside other filesystems, such as ISO images
+// stored as files, are reported too.
+func Scan(r io.ReaderAt, size int64) ([]*Candidate, error) {
+	var candidates []*Candidate
+	buf := make([]byte, chunkSize+len(pvdSignature))
+	for start := int64(0); start < size; start += chunkSize {
+		n, err := r.ReadAt(buf[:min(int64(len(buf)), size-start)], start)
+		if n == 0 && err != nil && err != io.EOF {
+			return candidates, err
+		}
+
+		for pos := 0; pos < min(n, chunkSize); pos += ALIGNMENT {
+			if !bytes.HasPrefix(buf[pos:n], pvdSignature) {
+				continue
+			}
+			offset := start + int64(pos) - consts.ISO9660_SYSTEM_AREA_SECTORS*consts.ISO9660_SECTOR_SIZE
+			if offset < 0 {
+				continue
+			}
+			if candidate := Probe(r, offset, size); candidate != nil {
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// Probe returns the candidate filesystem starting at the given offset, nil if no primary volume descriptor parses there.
+// The candidate is validated, see Candidate.Recoverable.
+func Probe(r io.ReaderAt, offset, size int64) *Candidate {
+	if offset < 0 || offset >= size {
+		return nil
+	}
+	p := parser.NewParser(io.NewSectionReader(r, offset, size-offset), &option.OpenOptions{Logger: logging.DefaultLogger()})
+	pvd, err := p.GetPrimaryVolumeDescriptor()
+	if err != nil {
+		return nil
+	}
+	candidate := &Candidate{
+		Offset:           offset,
+		VolumeIdentifier: pvd.VolumeIdentifier(),
+		VolumeSpaceSize:  pvd.VolumeSpaceSize,
+		LogicalBlockSize: pvd.LogicalBlockSize,
+	}
+	candidate.validate(r, pvd, size)
+	return candidate
+}
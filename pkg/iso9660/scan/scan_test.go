@@ -0,0 +1,58 @@
+package scan
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScan(t *testing.T) {
+	image, err := os.ReadFile("../../../assets/test-iso-001/output.iso")
+	require.NoError(t, err)
+
+	// Place the image after a header and follow it with a second copy
+	header := make([]byte, 16*ALIGNMENT)
+	data := append(append(append(header, image...), make([]byte, ALIGNMENT)...), image...)
+	r := bytes.NewReader(data)
+
+	candidates, err := Scan(r, int64(len(data)))
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+	require.Equal(t, int64(len(header)), candidates[0].Offset)
+	require.Equal(t, int64(len(header)+len(image)+ALIGNMENT), candidates[1].Offset)
+	require.Equal(t, "test-001", candidates[0].VolumeIdentifier)
+	require.Equal(t, uint16(2048), candidates[0].LogicalBlockSize)
+
+	require.Nil(t, Probe(r, 0, int64(len(data))))
+	require.NotNil(t, Probe(r, int64(len(header)), int64(len(data))))
+}
+
+func TestValidate(t *testing.T) {
+	image, err := os.ReadFile("../../../assets/test-iso-001/output.iso")
+	require.NoError(t, err)
+
+	candidate := Probe(bytes.NewReader(image), 0, int64(len(image)))
+	require.NotNil(t, candidate)
+	require.True(t, candidate.Recoverable(), candidate.Problems)
+	require.True(t, candidate.Terminated)
+	require.False(t, candidate.Truncated)
+
+	// Cut the image short of its end
+	truncated := image[:len(image)-4096]
+	candidate = Probe(bytes.NewReader(truncated), 0, int64(len(truncated)))
+	require.True(t, candidate.Recoverable(), candidate.Problems)
+	require.True(t, candidate.Truncated)
+	require.Equal(t, int64(len(truncated)), candidate.Section(bytes.NewReader(truncated), int64(len(truncated))).Size())
+
+	// Overwrite the descriptors following the primary volume descriptor, losing the terminator
+	damaged := bytes.Clone(image)
+	for sector := 17; sector < 17+candidate.Descriptors; sector++ {
+		clear(damaged[sector*2048 : (sector+1)*2048])
+	}
+	candidate = Probe(bytes.NewReader(damaged), 0, int64(len(damaged)))
+	require.NotNil(t, candidate)
+	require.False(t, candidate.Recoverable())
+	require.False(t, candidate.Terminated)
+}
@@ -0,0 +1,119 @@
+package scan
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/rstms/iso-kit/pkg/consts"
+	"github.com/rstms/iso-kit/pkg/iso9660/descriptor"
+	"github.com/rstms/iso-kit/pkg/iso9660/directory"
+	"github.com/rstms/iso-kit/pkg/iso9660/validation"
+)
+
+// MAX_DESCRIPTORS bounds the walk of a volume descriptor set, real images record a handful of descriptors so a longer
+// set is treated as missing its terminator.
+const MAX_DESCRIPTORS = 64
+
+// Recoverable reports whether the filesystem passed every validation check and can be opened by the parser. Truncated
+// filesystems are recoverable, only the files stored past the end of the image are lost.
+func (c *Candidate) Recoverable() bool {
+	return len(c.Problems) == 0
+}
+
+// Section returns a reader over the filesystem within the image, limited to the part of it present in the image.
+func (c *Candidate) Section(r io.ReaderAt, size int64) *io.SectionReader {
+	return io.NewSectionReader(r, c.Offset, min(c.Size(), size-c.Offset))
+}
+
+// validate checks the primary volume descriptor, the volume descriptor set terminator and the root directory record of
+// the candidate and records every problem found.
+func (c *Candidate) validate(r io.ReaderAt, pvd *descriptor.PrimaryVolumeDescriptor, size int64) {
+	c.checkPrimaryVolumeDescriptor(pvd)
+	c.checkTerminator(r, size)
+	c.checkRootDirectory(r, pvd, size)
+	c.Truncated = c.Offset+c.Size() > size
+}
+
+// problem records a failed validation check.
+func (c *Candidate) problem(format string, args ...any) {
+	c.Problems = append(c.Problems, fmt.Sprintf(format, args...))
+}
+
+// checkPrimaryVolumeDescriptor checks the fields of the primary volume descriptor for values a valid filesystem cannot
+// have.
+func (c *Candidate) checkPrimaryVolumeDescriptor(pvd *descriptor.PrimaryVolumeDescriptor) {
+	if err := validation.ValidateLogicalBlockSize(int(pvd.LogicalBlockSize)); err != nil {
+		c.problem("%v", err)
+	}
+	if c.Size() <= (consts.ISO9660_SYSTEM_AREA_SECTORS+1)*consts.ISO9660_SECTOR_SIZE {
+		c.problem("volume space size %d is smaller than the volume descriptor set", pvd.VolumeSpaceSize)
+	}
+	if pvd.VolumeSetSize == 0 || pvd.VolumeSequenceNumber == 0 || pvd.VolumeSequenceNumber > pvd.VolumeSetSize {
+		c.problem("invalid volume sequence number %d of %d", pvd.VolumeSequenceNumber, pvd.VolumeSetSize)
+	}
+	if pvd.FileStructureVersion != 1 {
+		c.problem("invalid file structure version %d", pvd.FileStructureVersion)
+	}
+}
+
+// checkTerminator walks the volume descriptor set and checks that it ends with a terminator.
+func (c *Candidate) checkTerminator(r io.ReaderAt, size int64) {
+	var buf [consts.ISO9660_SECTOR_SIZE]byte
+	for i := int64(0); i < MAX_DESCRIPTORS; i++ {
+		offset := c.Offset + (consts.ISO9660_SYSTEM_AREA_SECTORS+i)*consts.ISO9660_SECTOR_SIZE
+		if offset+consts.ISO9660_SECTOR_SIZE > size {
+			break
+		}
+		if _, err := r.ReadAt(buf[:], offset); err != nil {
+			break
+		}
+		if string(buf[1:6]) != consts.ISO9660_STD_IDENTIFIER {
+			c.problem("volume descriptor %d has no standard identifier", i)
+			return
+		}
+		c.Descriptors++
+		if descriptor.VolumeDescriptorType(buf[0]) == descriptor.TYPE_TERMINATOR_DESCRIPTOR {
+			c.Terminated = true
+			return
+		}
+	}
+	c.problem("volume descriptor set has no terminator")
+}
+
+// checkRootDirectory checks that the root directory record points into the volume at a directory whose first record
+// is its own "." entry.
+func (c *Candidate) checkRootDirectory(r io.ReaderAt, pvd *descriptor.PrimaryVolumeDescriptor, size int64) {
+	root := pvd.RootDirectoryRecord
+	if root == nil {
+		c.problem("missing root directory record")
+		return
+	}
+	if !root.FileFlags.Directory {
+		c.problem("root directory record is not a directory")
+	}
+	if validation.ValidateLogicalBlockSize(int(pvd.LogicalBlockSize)) != nil {
+		return
+	}
+	location := int64(root.LocationOfExtent) * int64(pvd.LogicalBlockSize)
+	if location <= consts.ISO9660_SYSTEM_AREA_SECTORS*consts.ISO9660_SECTOR_SIZE || root.LocationOfExtent >= pvd.VolumeSpaceSize ||
+		int64(root.DataLength) > c.Size() {
+		c.problem("root directory extent %d of %d bytes is outside of the volume", root.LocationOfExtent, root.DataLength)
+		return
+	}
+
+	offset := c.Offset + location
+	if offset >= size {
+		c.problem("root directory extent %d is past the end of the image", root.LocationOfExtent)
+		return
+	}
+	var buf [255]byte
+	n, _ := r.ReadAt(buf[:], offset)
+	self := &directory.DirectoryRecord{}
+	if n == 0 || self.Unmarshal(buf[:min(n, int(buf[0]))]) != nil {
+		c.problem("root directory extent %d does not start with a directory record", root.LocationOfExtent)
+		return
+	}
+	if self.FileIdentifier != "\x00" || self.LocationOfExtent != root.LocationOfExtent {
+		c.problem("root directory extent %d does not start with its own entry", root.LocationOfExtent)
+	}
+}
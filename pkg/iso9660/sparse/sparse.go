@@ -0,0 +1,277 @@
+package sparse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// A Rock Ridge sparse file (SF entry) records only the blocks of a file that contain data. The extent of the file
+// starts with a table of block pointers, one 32-bit little-endian logical block number per block of the file, where a
+// zero pointer marks a hole. A table occupies one logical block, so when a file has more blocks than fit into a single
+// table the tables are nested: with a table depth of N the top level table points to N-1 further levels of tables
+// and only the last level points to data blocks.
+const (
+	// POINTER_SIZE is the size of a table entry in bytes
+	POINTER_SIZE = 4
+)
+
+// TableDepth returns the number of table levels needed to describe a file of the given size.
+func TableDepth(size uint64, blockSize int) uint8 {
+	perTable := uint64(blockSize / POINTER_SIZE)
+	blocks := (size + uint64(blockSize) - 1) / uint64(blockSize)
+	depth := uint8(1)
+	for capacity := perTable; capacity < blocks; capacity *= perTable {
+		depth++
+	}
+	return depth
+}
+
+// NewReader creates a Reader for a sparse file whose top level table is recorded at tableLBA. The reader r must
+// provide access to the whole volume since the table entries are absolute logical block numbers.
+func NewReader(r io.ReaderAt, tableLBA uint32, depth uint8, size uint64, blockSize int) (*Reader, error) {
+	if depth == 0 {
+		return nil, errors.New("sparse: table depth must be at least 1")
+	}
+	if blockSize < POINTER_SIZE || blockSize%POINTER_SIZE != 0 {
+		return nil, fmt.Errorf("sparse: invalid block size %d", blockSize)
+	}
+	return &Reader{
+		reader:    r,
+		tableLBA:  tableLBA,
+		depth:     depth,
+		size:      int64(size),
+		blockSize: int64(blockSize),
+		tables:    make(map[uint32][]byte),
+	}, nil
+}
+
+// Reader provides random access to the content of a sparse file, holes read as zeros.
+type Reader struct {
+	reader    io.ReaderAt
+	tableLBA  uint32
+	depth     uint8
+	size      int64
+	blockSize int64
+	mu        sync.Mutex
+	tables    map[uint32][]byte
+}
+
+// Size returns the apparent size of the file.
+func (s *Reader) Size() int64 {
+	return s.size
+}
+
+// ReadAt reads len(p) bytes of the file starting at offset off.
+func (s *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("sparse: negative offset")
+	}
+	if off >= s.size {
+		return 0, io.EOF
+	}
+
+	n := 0
+	for n < len(p) && off < s.size {
+		blockIndex := off / s.blockSize
+		blockOffset := off % s.blockSize
+		length := min(int64(len(p)-n), s.blockSize-blockOffset, s.size-off)
+
+		lba, err := s.blockLBA(blockIndex)
+		if err != nil {
+			return n, err
+		}
+		chunk := p[n : n+int(length)]
+		if lba == 0 {
+			clear(chunk)
+		} else if _, err := s.reader.ReadAt(chunk, int64(lba)*s.blockSize+blockOffset); err != nil && !errors.Is(err, io.EOF) {
+			return n, fmt.Errorf("sparse: failed to read block %d: %w", blockIndex, err)
+		}
+
+		n += int(length)
+		off += length
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// IsHole reports whether the block containing offset off is not recorded.
+func (s *Reader) IsHole(off int64) (bool, error) {
+	lba, err := s.blockLBA(off / s.blockSize)
+	return lba == 0, err
+}
+
+// blockLBA walks the tables to find the logical block number holding a block of the file, zero means a hole.
+func (s *Reader) blockLBA(index int64) (uint32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	perTable := s.blockSize / POINTER_SIZE
+	span := int64(1)
+	for i := uint8(1); i < s.depth; i++ {
+		span *= perTable
+	}
+	if index/span >= perTable {
+		return 0, fmt.Errorf("sparse: block %d is beyond the table capacity", index)
+	}
+
+	lba := s.tableLBA
+	for level := uint8(0); level < s.depth; level++ {
+		table, err := s.table(lba)
+		if err != nil {
+			return 0, err
+		}
+		slot := index / span
+		index %= span
+		span /= perTable
+		lba = binary.LittleEndian.Uint32(table[slot*POINTER_SIZE:])
+		if lba == 0 {
+			return 0, nil
+		}
+	}
+	return lba, nil
+}
+
+func (s *Reader) table(lba uint32) ([]byte, error) {
+	if table, ok := s.tables[lba]; ok {
+		return table, nil
+	}
+	table := make([]byte, s.blockSize)
+	if _, err := s.reader.ReadAt(table, int64(lba)*s.blockSize); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("sparse: failed to read table at LBA %d: %w", lba, err)
+	}
+	s.tables[lba] = table
+	return table, nil
+}
+
+// Encode lays out data as a sparse file whose extent will be recorded at baseLBA. It returns the recorded bytes
+// (tables followed by the data blocks) and the table depth. The boolean is false when the data contains no holes or
+// recording it sparsely would not save any blocks, the data should then be recorded as a regular file.
+func Encode(data []byte, blockSize int, baseLBA uint32) ([]byte, uint8, bool) {
+	depth := TableDepth(uint64(len(data)), blockSize)
+	blocks := (len(data) + blockSize - 1) / blockSize
+	perTable := blockSize / POINTER_SIZE
+	zero := make([]byte, blockSize)
+
+	isHole := make([]bool, blocks)
+	dataBlocks := 0
+	for i := range isHole {
+		chunk := data[i*blockSize : min((i+1)*blockSize, len(data))]
+		isHole[i] = bytes.Equal(chunk, zero[:len(chunk)])
+		if !isHole[i] {
+			dataBlocks++
+		}
+	}
+	if dataBlocks == blocks {
+		return nil, 0, false
+	}
+
+	// Build the tables bottom up. Each table lists the index of its children (data blocks for the last level, tables
+	// of the level below otherwise) with -1 for holes. A table is a hole itself when all of its entries are holes.
+	levels := make([][][]int, depth)
+	empty := make([][]bool, depth)
+	holes := isHole
+	for level := int(depth) - 1; level >= 0; level-- {
+		for start := 0; start < len(holes) || start == 0; start += perTable {
+			var pointers []int
+			tableEmpty := true
+			for i := start; i < min(start+perTable, len(holes)); i++ {
+				if holes[i] {
+					pointers = append(pointers, -1)
+				} else {
+					pointers = append(pointers, i)
+					tableEmpty = false
+				}
+			}
+			levels[level] = append(levels[level], pointers)
+			empty[level] = append(empty[level], tableEmpty)
+		}
+		holes = empty[level]
+	}
+
+	// Assign block numbers: the top level table first, then the recorded tables of each level, then the data
+	tableLBAs := make([][]uint32, depth)
+	next := baseLBA
+	for level := range levels {
+		tableLBAs[level] = make([]uint32, len(levels[level]))
+		for i := range levels[level] {
+			if level == 0 || !empty[level][i] {
+				tableLBAs[level][i] = next
+				next++
+			}
+		}
+	}
+	recordedBlocks := int(next-baseLBA) + dataBlocks
+	if recordedBlocks >= blocks {
+		return nil, 0, false
+	}
+
+	out := make([]byte, recordedBlocks*blockSize)
+	dataLBA := next
+	for level, tables := range levels {
+		for i, pointers := range tables {
+			if level > 0 && empty[level][i] {
+				continue
+			}
+			tableOffset := int(tableLBAs[level][i]-baseLBA) * blockSize
+			for slot, child := range pointers {
+				if child < 0 {
+					continue
+				}
+				lba := dataLBA
+				if level == int(depth)-1 {
+					copy(out[int(dataLBA-baseLBA)*blockSize:], data[child*blockSize:min((child+1)*blockSize, len(data))])
+					dataLBA++
+				} else {
+					lba = tableLBAs[level+1][child]
+				}
+				binary.LittleEndian.PutUint32(out[tableOffset+slot*POINTER_SIZE:], lba)
+			}
+		}
+	}
+
+	return out, depth, true
+}
+
+// Relocate rewrites the tables of a sparse file recorded at from so the file can be recorded at to instead. recorded
+// holds the tables and data blocks as recorded in the extent, the tables are modified in place. Every pointer must
+// refer to a block of the extent.
+func Relocate(recorded []byte, depth uint8, blockSize int, from, to uint32) error {
+	if depth == 0 {
+		return errors.New("sparse: table depth must be at least 1")
+	}
+	if blockSize < POINTER_SIZE || blockSize%POINTER_SIZE != 0 {
+		return fmt.Errorf("sparse: invalid block size %d", blockSize)
+	}
+	blocks := uint32(len(recorded) / blockSize)
+
+	tables := []uint32{from}
+	for level := uint8(0); level < depth; level++ {
+		var next []uint32
+		for _, lba := range tables {
+			if lba < from || lba-from >= blocks {
+				return fmt.Errorf("sparse: table at LBA %d is outside of the extent", lba)
+			}
+			table := recorded[int(lba-from)*blockSize : int(lba-from+1)*blockSize]
+			for offset := 0; offset < blockSize; offset += POINTER_SIZE {
+				pointer := binary.LittleEndian.Uint32(table[offset:])
+				if pointer == 0 {
+					continue
+				}
+				if pointer < from || pointer-from >= blocks {
+					return fmt.Errorf("sparse: block pointer %d is outside of the extent", pointer)
+				}
+				binary.LittleEndian.PutUint32(table[offset:], pointer-from+to)
+				next = append(next, pointer)
+			}
+		}
+		tables = next
+	}
+	return nil
+}
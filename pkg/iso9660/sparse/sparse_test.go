@@ -0,0 +1,73 @@
+package sparse
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	const blockSize = 16 // 4 pointers per table to exercise nested tables
+	data := make([]byte, 50*blockSize+5)
+	copy(data[3*blockSize:], "first data block")
+	copy(data[40*blockSize+7:], "spans two blocks of the file")
+	copy(data[len(data)-5:], "tail!")
+
+	for _, base := range []uint32{0, 7} {
+		out, depth, ok := Encode(data, blockSize, base)
+		require.True(t, ok)
+		require.Equal(t, uint8(3), depth)
+		require.Less(t, len(out), len(data))
+
+		volume := append(make([]byte, int(base)*blockSize), out...)
+		sr, err := NewReader(bytes.NewReader(volume), base, depth, uint64(len(data)), blockSize)
+		require.NoError(t, err)
+
+		got, err := io.ReadAll(io.NewSectionReader(sr, 0, sr.Size()))
+		require.NoError(t, err)
+		require.Equal(t, data, got)
+
+		hole, err := sr.IsHole(0)
+		require.NoError(t, err)
+		require.True(t, hole)
+		hole, err = sr.IsHole(3 * blockSize)
+		require.NoError(t, err)
+		require.False(t, hole)
+	}
+}
+
+func TestEncodeWithoutHoles(t *testing.T) {
+	_, _, ok := Encode(bytes.Repeat([]byte{1}, 4096), 2048, 0)
+	require.False(t, ok)
+}
+
+func TestTableDepth(t *testing.T) {
+	require.Equal(t, uint8(1), TableDepth(512*2048, 2048))
+	require.Equal(t, uint8(2), TableDepth(512*2048+1, 2048))
+	require.Equal(t, uint8(3), TableDepth(512*512*2048+1, 2048))
+}
+
+func TestRelocate(t *testing.T) {
+	const blockSize = 16
+	data := make([]byte, 50*blockSize)
+	copy(data[3*blockSize:], "first data block")
+	copy(data[45*blockSize:], "last data block")
+
+	out, depth, ok := Encode(data, blockSize, 0)
+	require.True(t, ok)
+	require.NoError(t, Relocate(out, depth, blockSize, 0, 9))
+
+	volume := append(make([]byte, 9*blockSize), out...)
+	sr, err := NewReader(bytes.NewReader(volume), 9, depth, uint64(len(data)), blockSize)
+	require.NoError(t, err)
+	got, err := io.ReadAll(io.NewSectionReader(sr, 0, sr.Size()))
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+
+	// Pointers outside of the extent are rejected
+	out, depth, ok = Encode(data, blockSize, 5)
+	require.True(t, ok)
+	require.Error(t, Relocate(out, depth, blockSize, 0, 9))
+}
@@ -0,0 +1,15 @@
+package validation
+
+import (
+	"fmt"
+	"github.com/rstms/iso-kit/pkg/consts"
+)
+
+// ValidateLogicalBlockSize checks that size is a logical block size allowed by ECMA-119, a power of two from 512 bytes
+// up to the sector size.
+func ValidateLogicalBlockSize(size int) error {
+	if size < consts.ISO9660_MIN_LOGICAL_BLOCK_SIZE || size > consts.ISO9660_SECTOR_SIZE || size&(size-1) != 0 {
+		return fmt.Errorf("invalid logical block size %d", size)
+	}
+	return nil
+}
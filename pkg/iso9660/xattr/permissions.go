@@ -1,8 +1,11 @@
 package xattr
 
-import "fmt"
+import (
+	"fmt"
+	"io/fs"
+)
 
-// GroupReadPermission represents the two-bit value stored in bits 8-9.
+// GroupReadPermission represents the group read flag stored in bit 8, bit 9 is a fixed bit.
 // According to the spec:
 //
 //	0 means that any user in the group may read the file,
@@ -25,9 +28,8 @@ const (
 //	Bit 5:  Fixed; shall be 1
 //	Bit 6:  Owner execute denied (false allowed; true denied)
 //	Bit 7:  Fixed; shall be 1
-//	Bits 8-9: Group read permission, where allowed values are:
-//	          0 (GroupReadAllowed) means any group member may read,
-//	          2 (GroupReadRestricted) means only the owner may read.
+//	Bit 8:  Group read restricted, decoded as GroupReadAllowed (0) or GroupReadRestricted (2)
+//	Bit 9:  Fixed; shall be 1
 //	Bit 10: Group execute restricted (false means allowed; true means only owner may execute)
 //	Bit 11: Fixed; shall be 1
 //	Bit 12: Other (world) read denied (false allowed; true denied)
@@ -43,7 +45,7 @@ type ExtendedAttrPermissions struct {
 	OwnerReadDenied bool `json:"owner_read_denied"`
 	// Bit 6
 	OwnerExecuteDenied bool `json:"owner_execute_denied"`
-	// Bits 8-9; allowed values: 0 (allowed) or 2 (restricted)
+	// Bit 8; GroupReadAllowed or GroupReadRestricted
 	GroupReadPermission GroupReadPermission `json:"group_read_permission"`
 	// Bit 10
 	GroupExecuteRestricted bool `json:"group_execute_restricted"`
@@ -86,9 +88,12 @@ func (eap ExtendedAttrPermissions) Marshal() uint16 {
 	// Bit 7: fixed to 1.
 	flags |= 1 << 7
 
-	// Bits 8-9: Group Read Permission.
-	// We place the value (expected to be 0 or 2) in bits 8-9.
-	flags |= uint16(eap.GroupReadPermission) << 8
+	// Bit 8: Group Read Permission.
+	if eap.GroupReadPermission == GroupReadRestricted {
+		flags |= 1 << 8
+	}
+	// Bit 9: fixed to 1.
+	flags |= 1 << 9
 
 	// Bit 10: GroupExecuteRestricted.
 	if eap.GroupExecuteRestricted {
@@ -115,10 +120,10 @@ func (eap ExtendedAttrPermissions) Marshal() uint16 {
 }
 
 // UnmarshalExtendedAttrPermissions decodes a 16-bit permission field into an ExtendedAttrPermissions struct.
-// It verifies that the fixed bits (positions 1,3,5,7,11,13,15) are set.
+// It verifies that the fixed bits (positions 1,3,5,7,9,11,13,15) are set.
 func UnmarshalExtendedAttrPermissions(flags uint16) (ExtendedAttrPermissions, error) {
 	// Verify fixed bits.
-	fixedMask := uint16((1 << 1) | (1 << 3) | (1 << 5) | (1 << 7) | (1 << 11) | (1 << 13) | (1 << 15))
+	fixedMask := uint16((1 << 1) | (1 << 3) | (1 << 5) | (1 << 7) | (1 << 9) | (1 << 11) | (1 << 13) | (1 << 15))
 	if flags&fixedMask != fixedMask {
 		return ExtendedAttrPermissions{}, fmt.Errorf("invalid permissions: fixed bits not all set in 0x%04X", flags)
 	}
@@ -133,12 +138,53 @@ func UnmarshalExtendedAttrPermissions(flags uint16) (ExtendedAttrPermissions, er
 		OtherReadDenied:        flags&(1<<12) != 0,
 		OtherExecuteDenied:     flags&(1<<14) != 0,
 	}
-	// Bits 8-9: extract the 2-bit value.
-	groupRead := uint8((flags >> 8) & 0x03)
-	if groupRead != 0 && groupRead != 2 {
-		return ExtendedAttrPermissions{}, fmt.Errorf("invalid group read permission value: %d (expected 0 or 2)", groupRead)
+	// Bit 8: group read restricted.
+	if flags&(1<<8) != 0 {
+		eap.GroupReadPermission = GroupReadRestricted
 	}
-	eap.GroupReadPermission = GroupReadPermission(groupRead)
 
 	return eap, nil
 }
+
+// NewExtendedAttrPermissions converts POSIX permission bits into Extended Attribute Record permissions. The ISO9660
+// permissions only describe read and execute access, the System class is given the same access as the owner.
+func NewExtendedAttrPermissions(mode fs.FileMode) ExtendedAttrPermissions {
+	eap := ExtendedAttrPermissions{
+		SystemReadDenied:       mode&0400 == 0,
+		SystemExecuteDenied:    mode&0100 == 0,
+		OwnerReadDenied:        mode&0400 == 0,
+		OwnerExecuteDenied:     mode&0100 == 0,
+		GroupExecuteRestricted: mode&0010 == 0,
+		OtherReadDenied:        mode&0004 == 0,
+		OtherExecuteDenied:     mode&0001 == 0,
+	}
+	if mode&0040 == 0 {
+		eap.GroupReadPermission = GroupReadRestricted
+	}
+	return eap
+}
+
+// FileMode converts the permissions into POSIX permission bits. Files on ISO9660 are read-only so no write bits are
+// set.
+func (eap ExtendedAttrPermissions) FileMode() fs.FileMode {
+	var mode fs.FileMode
+	if !eap.OwnerReadDenied {
+		mode |= 0400
+	}
+	if !eap.OwnerExecuteDenied {
+		mode |= 0100
+	}
+	if eap.GroupReadPermission != GroupReadRestricted {
+		mode |= 0040
+	}
+	if !eap.GroupExecuteRestricted {
+		mode |= 0010
+	}
+	if !eap.OtherReadDenied {
+		mode |= 0004
+	}
+	if !eap.OtherExecuteDenied {
+		mode |= 0001
+	}
+	return mode
+}
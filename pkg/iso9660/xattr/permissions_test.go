@@ -0,0 +1,35 @@
+package xattr
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtendedAttrPermissionsFileMode(t *testing.T) {
+	for _, mode := range []fs.FileMode{0555, 0444, 0500, 0550, 0404, 0111, 0} {
+		eap := NewExtendedAttrPermissions(mode)
+		decoded, err := UnmarshalExtendedAttrPermissions(eap.Marshal())
+		require.NoError(t, err)
+		require.Equal(t, eap, decoded)
+		require.Equal(t, mode, decoded.FileMode(), "mode %o", mode)
+	}
+
+	// Write bits cannot be represented and are dropped
+	require.Equal(t, fs.FileMode(0444), NewExtendedAttrPermissions(0644).FileMode())
+}
+
+func TestUnmarshalExtendedAttrPermissionsGroupRead(t *testing.T) {
+	// All fixed bits set, all access allowed
+	eap, err := UnmarshalExtendedAttrPermissions(0xAAAA)
+	require.NoError(t, err)
+	require.Equal(t, GroupReadAllowed, eap.GroupReadPermission)
+
+	eap, err = UnmarshalExtendedAttrPermissions(0xAAAA | 1<<8)
+	require.NoError(t, err)
+	require.Equal(t, GroupReadRestricted, eap.GroupReadPermission)
+
+	_, err = UnmarshalExtendedAttrPermissions(0xAAAA &^ (1 << 9))
+	require.Error(t, err)
+}
@@ -147,7 +147,7 @@ func (ear *ExtendedAttributeRecord) Offset() int64 {
 }
 
 func (ear *ExtendedAttributeRecord) Size() int {
-	return int(ear.RecordLength)
+	return int(ear.ObjectSize)
 }
 
 func (ear *ExtendedAttributeRecord) GetObjects() []info.ImageObject {
@@ -0,0 +1,357 @@
+package zisofs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// zisofs stores a file as a header, a table of block pointers and a sequence of independently compressed blocks.
+// Version 1 (written by mkzftree and xorriso) uses a 16-byte header, 32-bit block pointers and zlib. Version 2
+// (zisofs2, libisofs >= 1.5.4) uses a 24-byte header, 64-bit block pointers and an algorithm byte.
+//
+// Version 1 header layout:
+//
+//	BP 1 - 8:   Magic number (37 E4 53 96 C9 DB D6 07)
+//	BP 9 - 12:  Uncompressed size (little-endian)
+//	BP 13:      Header size / 4
+//	BP 14:      Log2 of the block size
+//	BP 15 - 16: Reserved
+//
+// Version 2 header layout:
+//
+//	BP 1 - 8:   Magic number (EF 22 55 A1 BC 1B 95 A0)
+//	BP 9:       Header size / 4
+//	BP 10:      Algorithm (1 = zlib)
+//	BP 11:      Log2 of the block size
+//	BP 12:      Reserved
+//	BP 13 - 20: Uncompressed size (little-endian)
+//	BP 21 - 24: Reserved
+const (
+	// HEADER_SIZE_V1 is the size of a zisofs version 1 file header in bytes
+	HEADER_SIZE_V1 = 16
+	// HEADER_SIZE_V2 is the size of a zisofs2 file header in bytes
+	HEADER_SIZE_V2 = 24
+	// MIN_LOG2_BLOCK_SIZE is the smallest block size allowed by the format (32 KiB)
+	MIN_LOG2_BLOCK_SIZE = 15
+	// MAX_LOG2_BLOCK_SIZE_V1 is the largest block size allowed by version 1 (128 KiB)
+	MAX_LOG2_BLOCK_SIZE_V1 = 17
+	// MAX_LOG2_BLOCK_SIZE_V2 is the largest block size allowed by zisofs2 (1 MiB)
+	MAX_LOG2_BLOCK_SIZE_V2 = 20
+	// DEFAULT_LOG2_BLOCK_SIZE is the block size used by mkisofs/xorriso (32 KiB)
+	DEFAULT_LOG2_BLOCK_SIZE = 15
+	// ALGORITHM_ZLIB is the zisofs2 header algorithm byte for zlib
+	ALGORITHM_ZLIB = 1
+)
+
+var (
+	// MagicV1 identifies a zisofs version 1 compressed file
+	MagicV1 = [8]byte{0x37, 0xE4, 0x53, 0x96, 0xC9, 0xDB, 0xD6, 0x07}
+	// MagicV2 identifies a zisofs2 compressed file
+	MagicV2 = [8]byte{0xEF, 0x22, 0x55, 0xA1, 0xBC, 0x1B, 0x95, 0xA0}
+)
+
+// Header holds the decoded zisofs file header.
+type Header struct {
+	// Version is 1 for zisofs and 2 for zisofs2
+	Version int
+	// HeaderSize is the size of the header in bytes, the block pointer table follows immediately after it
+	HeaderSize int
+	// Algorithm is the compression algorithm (always ALGORITHM_ZLIB for version 1)
+	Algorithm uint8
+	// Log2BlockSize is the binary logarithm of the uncompressed block size
+	Log2BlockSize uint8
+	// UncompressedSize is the logical size of the file
+	UncompressedSize uint64
+}
+
+// BlockSize returns the uncompressed size of each block.
+func (h Header) BlockSize() int64 {
+	return int64(1) << h.Log2BlockSize
+}
+
+// BlockCount returns the number of compressed blocks in the file.
+func (h Header) BlockCount() int64 {
+	count := int64(h.UncompressedSize >> h.Log2BlockSize)
+	if h.UncompressedSize&uint64(h.BlockSize()-1) != 0 {
+		count++
+	}
+	return count
+}
+
+// pointerSize returns the size in bytes of an entry in the block pointer table.
+func (h Header) pointerSize() int {
+	if h.Version == 2 {
+		return 8
+	}
+	return 4
+}
+
+// maxLog2BlockSize returns the binary logarithm of the largest block size allowed by the header's version.
+func (h Header) maxLog2BlockSize() uint8 {
+	if h.Version == 2 {
+		return MAX_LOG2_BLOCK_SIZE_V2
+	}
+	return MAX_LOG2_BLOCK_SIZE_V1
+}
+
+// ParseHeader decodes a zisofs header from the start of a file's stored data.
+func ParseHeader(data []byte) (Header, error) {
+	if len(data) < HEADER_SIZE_V1 {
+		return Header{}, errors.New("zisofs: data too short for header")
+	}
+
+	var h Header
+	switch {
+	case bytes.Equal(data[0:8], MagicV1[:]):
+		h = Header{
+			Version:          1,
+			UncompressedSize: uint64(binary.LittleEndian.Uint32(data[8:12])),
+			HeaderSize:       int(data[12]) * 4,
+			Log2BlockSize:    data[13],
+			Algorithm:        ALGORITHM_ZLIB,
+		}
+	case bytes.Equal(data[0:8], MagicV2[:]):
+		if len(data) < HEADER_SIZE_V2 {
+			return Header{}, errors.New("zisofs: data too short for zisofs2 header")
+		}
+		h = Header{
+			Version:          2,
+			HeaderSize:       int(data[8]) * 4,
+			Algorithm:        data[9],
+			Log2BlockSize:    data[10],
+			UncompressedSize: binary.LittleEndian.Uint64(data[12:20]),
+		}
+	default:
+		return Header{}, errors.New("zisofs: invalid magic number")
+	}
+
+	if h.Log2BlockSize < MIN_LOG2_BLOCK_SIZE || h.Log2BlockSize > h.maxLog2BlockSize() {
+		return Header{}, fmt.Errorf("zisofs: unsupported block size 2^%d", h.Log2BlockSize)
+	}
+	if h.HeaderSize < HEADER_SIZE_V1 {
+		return Header{}, fmt.Errorf("zisofs: invalid header size %d", h.HeaderSize)
+	}
+	if h.Algorithm != ALGORITHM_ZLIB {
+		return Header{}, fmt.Errorf("zisofs: unsupported compression algorithm %d", h.Algorithm)
+	}
+
+	return h, nil
+}
+
+// NewReader creates a Reader which inflates the zisofs compressed data stored in r. The storedSize is the size of the
+// compressed data on disk (the directory record's data length).
+func NewReader(r io.ReaderAt, storedSize int64) (*Reader, error) {
+	var hdrBuf [HEADER_SIZE_V2]byte
+	n, err := r.ReadAt(hdrBuf[:], 0)
+	if err != nil && !(errors.Is(err, io.EOF) && n >= HEADER_SIZE_V1) {
+		return nil, fmt.Errorf("zisofs: failed to read header: %w", err)
+	}
+	header, err := ParseHeader(hdrBuf[:n])
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the block pointer table, there is one more pointer than there are blocks so that the end of the last
+	// block is known. The table has to fit into the stored data, the uncompressed size is not trusted to size it.
+	count := header.BlockCount() + 1
+	ptrSize := header.pointerSize()
+	if count > (storedSize-int64(header.HeaderSize))/int64(ptrSize) {
+		return nil, fmt.Errorf("zisofs: block pointer table for %d blocks exceeds the stored size %d", count-1, storedSize)
+	}
+	table := make([]byte, count*int64(ptrSize))
+	n, err = r.ReadAt(table, int64(header.HeaderSize))
+	if n < len(table) {
+		if err == nil || errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("zisofs: failed to read block pointers: %w", err)
+	}
+
+	pointers := make([]int64, count)
+	for i := range pointers {
+		if ptrSize == 8 {
+			ptr := binary.LittleEndian.Uint64(table[i*8:])
+			if ptr > uint64(storedSize) {
+				return nil, fmt.Errorf("zisofs: invalid block pointer %d at index %d", ptr, i)
+			}
+			pointers[i] = int64(ptr)
+		} else {
+			pointers[i] = int64(binary.LittleEndian.Uint32(table[i*4:]))
+		}
+		if pointers[i] > storedSize || (i > 0 && pointers[i] < pointers[i-1]) {
+			return nil, fmt.Errorf("zisofs: invalid block pointer %d at index %d", pointers[i], i)
+		}
+	}
+
+	return &Reader{
+		reader:     r,
+		header:     header,
+		pointers:   pointers,
+		cacheBlock: -1,
+	}, nil
+}
+
+// Reader provides random access to the uncompressed content of a zisofs compressed file.
+type Reader struct {
+	reader     io.ReaderAt
+	header     Header
+	pointers   []int64
+	mu         sync.Mutex
+	cacheBlock int64
+	cache      []byte
+}
+
+// Header returns the decoded zisofs header.
+func (z *Reader) Header() Header {
+	return z.header
+}
+
+// Size returns the uncompressed size of the file.
+func (z *Reader) Size() int64 {
+	return int64(z.header.UncompressedSize)
+}
+
+// ReadAt reads len(p) bytes of uncompressed content starting at offset off.
+func (z *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("zisofs: negative offset")
+	}
+	size := z.Size()
+	if off >= size {
+		return 0, io.EOF
+	}
+
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	n := 0
+	for n < len(p) && off < size {
+		blockIndex := off >> z.header.Log2BlockSize
+		block, err := z.block(blockIndex)
+		if err != nil {
+			return n, err
+		}
+		blockOffset := off - blockIndex*z.header.BlockSize()
+		copied := copy(p[n:], block[blockOffset:])
+		n += copied
+		off += int64(copied)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// block returns the uncompressed contents of the block at the given index. The most recently used block is cached
+// since callers usually read sequentially in chunks smaller than the block size.
+func (z *Reader) block(index int64) ([]byte, error) {
+	if index == z.cacheBlock {
+		return z.cache, nil
+	}
+
+	// The last block may be shorter than the block size
+	blockSize := z.header.BlockSize()
+	length := blockSize
+	if remaining := z.Size() - index*blockSize; remaining < length {
+		length = remaining
+	}
+
+	out := make([]byte, length)
+	start, end := z.pointers[index], z.pointers[index+1]
+
+	// A zero length block represents a block of zero bytes. The pointers were checked against the stored size when
+	// the reader was created, so the compressed block is never larger than the stored data.
+	if end > start {
+		compressed := make([]byte, end-start)
+		if n, err := z.reader.ReadAt(compressed, start); n < len(compressed) {
+			if err == nil || errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("zisofs: failed to read block %d: %w", index, err)
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, fmt.Errorf("zisofs: failed to inflate block %d: %w", index, err)
+		}
+		_, err = io.ReadFull(zr, out)
+		zr.Close()
+		if err != nil {
+			return nil, fmt.Errorf("zisofs: failed to inflate block %d: %w", index, err)
+		}
+	}
+
+	z.cacheBlock = index
+	z.cache = out
+	return out, nil
+}
+
+// Compress encodes data as a zisofs version 1 file using blocks of 2^log2BlockSize bytes. Blocks consisting entirely
+// of zero bytes are stored with a length of zero.
+func Compress(data []byte, log2BlockSize uint8) ([]byte, error) {
+	if log2BlockSize < MIN_LOG2_BLOCK_SIZE || log2BlockSize > MAX_LOG2_BLOCK_SIZE_V1 {
+		return nil, fmt.Errorf("zisofs: unsupported block size 2^%d", log2BlockSize)
+	}
+	if uint64(len(data)) > uint64(^uint32(0)) {
+		return nil, errors.New("zisofs: version 1 cannot compress files larger than 4 GiB")
+	}
+
+	blockSize := 1 << log2BlockSize
+	blocks := (len(data) + blockSize - 1) / blockSize
+
+	var out bytes.Buffer
+	out.Write(MagicV1[:])
+	if err := binary.Write(&out, binary.LittleEndian, uint32(len(data))); err != nil {
+		return nil, err
+	}
+	out.WriteByte(HEADER_SIZE_V1 / 4)
+	out.WriteByte(log2BlockSize)
+	out.Write([]byte{0, 0})
+
+	// Reserve the block pointer table, it is filled in once the compressed block sizes are known
+	tableOffset := out.Len()
+	out.Write(make([]byte, (blocks+1)*4))
+
+	pointers := make([]uint32, 0, blocks+1)
+	pointers = append(pointers, uint32(out.Len()))
+	zero := make([]byte, blockSize)
+	zw, err := zlib.NewWriterLevel(&out, zlib.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < blocks; i++ {
+		chunk := data[i*blockSize : min((i+1)*blockSize, len(data))]
+		if !bytes.Equal(chunk, zero[:len(chunk)]) {
+			zw.Reset(&out)
+			if _, err := zw.Write(chunk); err != nil {
+				return nil, fmt.Errorf("zisofs: failed to compress block %d: %w", i, err)
+			}
+			if err := zw.Close(); err != nil {
+				return nil, fmt.Errorf("zisofs: failed to compress block %d: %w", i, err)
+			}
+		}
+		pointers = append(pointers, uint32(out.Len()))
+	}
+
+	buf := out.Bytes()
+	for i, ptr := range pointers {
+		binary.LittleEndian.PutUint32(buf[tableOffset+i*4:], ptr)
+	}
+
+	return buf, nil
+}
+
+// Log2BlockSize converts a block size in bytes to the binary logarithm used by the zisofs headers.
+func Log2BlockSize(blockSize int) (uint8, error) {
+	for log2 := uint8(MIN_LOG2_BLOCK_SIZE); log2 <= MAX_LOG2_BLOCK_SIZE_V1; log2++ {
+		if blockSize == 1<<log2 {
+			return log2, nil
+		}
+	}
+	return 0, fmt.Errorf("zisofs: block size %d is not one of 32768, 65536 or 131072", blockSize)
+}
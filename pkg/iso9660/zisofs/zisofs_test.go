@@ -0,0 +1,200 @@
+package zisofs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// buildV1 creates a zisofs version 1 stream from data. Blocks that are entirely zero are stored with a zero length
+// just like mkzftree does.
+func buildV1(t *testing.T, data []byte, log2BlockSize uint8) []byte {
+	t.Helper()
+	blockSize := 1 << log2BlockSize
+	blocks := (len(data) + blockSize - 1) / blockSize
+
+	var out bytes.Buffer
+	out.Write(MagicV1[:])
+	binary.Write(&out, binary.LittleEndian, uint32(len(data)))
+	out.WriteByte(HEADER_SIZE_V1 / 4)
+	out.WriteByte(log2BlockSize)
+	out.Write([]byte{0, 0})
+
+	tableOffset := out.Len()
+	out.Write(make([]byte, (blocks+1)*4))
+	pointers := []uint32{uint32(out.Len())}
+	for i := 0; i < blocks; i++ {
+		end := (i + 1) * blockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		chunk := data[i*blockSize : end]
+		if !bytes.Equal(chunk, make([]byte, len(chunk))) {
+			zw := zlib.NewWriter(&out)
+			_, err := zw.Write(chunk)
+			require.NoError(t, err)
+			require.NoError(t, zw.Close())
+		}
+		pointers = append(pointers, uint32(out.Len()))
+	}
+
+	buf := out.Bytes()
+	for i, ptr := range pointers {
+		binary.LittleEndian.PutUint32(buf[tableOffset+i*4:], ptr)
+	}
+	return buf
+}
+
+func testData() []byte {
+	data := make([]byte, 100000)
+	for i := range data[:40000] {
+		data[i] = byte(i % 251)
+	}
+	// Leave a block sized hole of zeros in the middle and finish with a partial block of text
+	copy(data[70000:], bytes.Repeat([]byte("zisofs "), 4000))
+	return data
+}
+
+func TestReaderV1(t *testing.T) {
+	data := testData()
+	stored := buildV1(t, data, 15)
+
+	zr, err := NewReader(bytes.NewReader(stored), int64(len(stored)))
+	require.NoError(t, err)
+	require.Equal(t, 1, zr.Header().Version)
+	require.Equal(t, int64(len(data)), zr.Size())
+
+	out := make([]byte, len(data))
+	n, err := zr.ReadAt(out, 0)
+	require.NoError(t, err)
+	require.Equal(t, len(data), n)
+	require.Equal(t, data, out)
+
+	// Reads spanning a block boundary and past the end
+	part := make([]byte, 1000)
+	n, err = zr.ReadAt(part, 32768-500)
+	require.NoError(t, err)
+	require.Equal(t, data[32768-500:32768+500], part[:n])
+
+	n, err = zr.ReadAt(part, int64(len(data)-10))
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, 10, n)
+	require.Equal(t, data[len(data)-10:], part[:n])
+}
+
+// buildV2 creates a zisofs2 stream from data.
+func buildV2(t *testing.T, data []byte, log2BlockSize uint8) []byte {
+	t.Helper()
+	blockSize := 1 << log2BlockSize
+	blocks := (len(data) + blockSize - 1) / blockSize
+
+	var out bytes.Buffer
+	out.Write(MagicV2[:])
+	out.WriteByte(HEADER_SIZE_V2 / 4)
+	out.WriteByte(ALGORITHM_ZLIB)
+	out.WriteByte(log2BlockSize)
+	out.WriteByte(0)
+	binary.Write(&out, binary.LittleEndian, uint64(len(data)))
+	out.Write(make([]byte, 4))
+	tableOffset := out.Len()
+	out.Write(make([]byte, (blocks+1)*8))
+	pointers := []uint64{uint64(out.Len())}
+	for i := 0; i < blocks; i++ {
+		end := min((i+1)*blockSize, len(data))
+		zw := zlib.NewWriter(&out)
+		_, err := zw.Write(data[i*blockSize : end])
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		pointers = append(pointers, uint64(out.Len()))
+	}
+	stored := out.Bytes()
+	for i, ptr := range pointers {
+		binary.LittleEndian.PutUint64(stored[tableOffset+i*8:], ptr)
+	}
+	return stored
+}
+
+func TestReaderV2(t *testing.T) {
+	data := testData()
+	// zisofs2 allows blocks of up to 1 MiB, larger than version 1 does
+	for _, log2 := range []uint8{16, 20} {
+		stored := buildV2(t, data, log2)
+
+		zr, err := NewReader(bytes.NewReader(stored), int64(len(stored)))
+		require.NoError(t, err)
+		require.Equal(t, 2, zr.Header().Version)
+
+		got, err := io.ReadAll(io.NewSectionReader(zr, 0, zr.Size()))
+		require.NoError(t, err)
+		require.Equal(t, data, got)
+	}
+
+	stored := buildV2(t, data, 21)
+	_, err := NewReader(bytes.NewReader(stored), int64(len(stored)))
+	require.Error(t, err)
+}
+
+func TestNewReaderInvalid(t *testing.T) {
+	data := testData()
+
+	// An uncompressed size whose block pointer table does not fit into the stored data
+	stored := buildV2(t, data, 16)
+	binary.LittleEndian.PutUint64(stored[12:], 1<<62)
+	_, err := NewReader(bytes.NewReader(stored), int64(len(stored)))
+	require.Error(t, err)
+
+	// A block pointer past the end of the stored data
+	stored = buildV2(t, data, 16)
+	binary.LittleEndian.PutUint64(stored[HEADER_SIZE_V2+8:], ^uint64(0))
+	_, err = NewReader(bytes.NewReader(stored), int64(len(stored)))
+	require.Error(t, err)
+
+	// Stored data that ends inside the block pointer table
+	stored = buildV1(t, data, 15)
+	_, err = NewReader(bytes.NewReader(stored[:HEADER_SIZE_V1+4]), int64(len(stored)))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// A block that inflates to less than the uncompressed size recorded in the header
+	stored = buildV1(t, data[:1000], 15)
+	binary.LittleEndian.PutUint32(stored[8:], 2000)
+	zr, err := NewReader(bytes.NewReader(stored), int64(len(stored)))
+	require.NoError(t, err)
+	_, err = zr.ReadAt(make([]byte, 100), 0)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestParseHeaderInvalid(t *testing.T) {
+	_, err := ParseHeader(make([]byte, HEADER_SIZE_V1))
+	require.Error(t, err)
+
+	hdr := make([]byte, HEADER_SIZE_V1)
+	copy(hdr, MagicV1[:])
+	hdr[12] = HEADER_SIZE_V1 / 4
+	hdr[13] = 12
+	_, err = ParseHeader(hdr)
+	require.Error(t, err, "block sizes below 32 KiB are not valid")
+}
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := testData()
+	for _, log2 := range []uint8{15, 16, 17} {
+		stored, err := Compress(data, log2)
+		require.NoError(t, err)
+		require.Less(t, len(stored), len(data))
+
+		zr, err := NewReader(bytes.NewReader(stored), int64(len(stored)))
+		require.NoError(t, err)
+		require.Equal(t, log2, zr.Header().Log2BlockSize)
+
+		got, err := io.ReadAll(io.NewSectionReader(zr, 0, zr.Size()))
+		require.NoError(t, err)
+		require.Equal(t, data, got)
+	}
+
+	_, err := Compress(data, 14)
+	require.Error(t, err)
+}
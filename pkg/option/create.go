@@ -1,6 +1,9 @@
 package option
 
-import "github.com/rstms/iso-kit/pkg/logging"
+import (
+	"github.com/rstms/iso-kit/pkg/compressed"
+	"github.com/rstms/iso-kit/pkg/logging"
+)
 
 // ISOType represents the type of ISO image
 type ISOType int
@@ -18,6 +21,29 @@ type CreateOptions struct {
 	RockRidgeEnabled bool
 	ElToritoEnabled  bool
 	Logger           *logging.Logger
+	// ZisofsEnabled compresses files added to the image with zisofs
+	ZisofsEnabled bool
+	// ZisofsBlockSize is the zisofs block size in bytes (32768, 65536 or 131072)
+	ZisofsBlockSize int
+	// ZisofsPatterns limits compression to files whose name or path matches one of the glob patterns, all files are
+	// considered when it is empty
+	ZisofsPatterns []string
+	// CaptureXattrs records the extended attributes and ACLs of files added from the source filesystem
+	CaptureXattrs bool
+	// ExtendedAttributeRecords writes an ECMA-119 Extended Attribute Record carrying the permissions of each file
+	ExtendedAttributeRecords bool
+	// SparseFiles records files containing holes (blocks of zeros) as Rock Ridge sparse files
+	SparseFiles bool
+	// EnhancedVolumeDescriptor records an ISO 9660:1999 enhanced volume descriptor for long names without Rock Ridge
+	EnhancedVolumeDescriptor bool
+	// MaxVolumeSize splits the file data across a multi-volume set of volumes of at most this many bytes, zero records
+	// a single volume
+	MaxVolumeSize int64
+	// Compression writes the image as a compressed container, compressed.FORMAT_CSO or compressed.FORMAT_ZSTD (the
+	// zstd seekable format), instead of a plain image
+	Compression compressed.Format
+	// LogicalBlockSize is the logical block size of the volume in bytes (512, 1024 or 2048)
+	LogicalBlockSize int
 }
 
 type CreateOption func(*CreateOptions)
@@ -58,9 +84,83 @@ func WithCreateElToritoEnabled(elToritoEnabled bool) CreateOption {
 	}
 }
 
+// WithZisofsCompression enables zisofs compression of added files. When patterns are given (for example "*.txt" or
+// "*.ko") only files whose name or path matches one of them are compressed. Files that would not shrink are always
+// stored uncompressed.
+func WithZisofsCompression(patterns ...string) CreateOption {
+	return func(o *CreateOptions) {
+		o.ZisofsEnabled = true
+		o.ZisofsPatterns = patterns
+	}
+}
+
+// WithZisofsBlockSize sets the zisofs block size in bytes. Valid sizes are 32768 (the default), 65536 and 131072.
+func WithZisofsBlockSize(blockSize int) CreateOption {
+	return func(o *CreateOptions) {
+		o.ZisofsBlockSize = blockSize
+	}
+}
+
+// WithCaptureXattrs controls whether AddDirectory records the extended attributes and POSIX ACLs of the source files
+// in AAIP "AL" entries.
+func WithCaptureXattrs(captureXattrs bool) CreateOption {
+	return func(o *CreateOptions) {
+		o.CaptureXattrs = captureXattrs
+	}
+}
+
+// WithExtendedAttributeRecords controls whether an ECMA-119 Extended Attribute Record carrying the file permissions is
+// recorded ahead of the data of each added file. Readers without Rock Ridge support use it for the file permissions.
+func WithExtendedAttributeRecords(extendedAttributeRecords bool) CreateOption {
+	return func(o *CreateOptions) {
+		o.ExtendedAttributeRecords = extendedAttributeRecords
+	}
+}
+
+// WithSparseFiles controls whether added files containing blocks of zeros are recorded as Rock Ridge sparse files so
+// the holes take no space in the image. Files selected for zisofs compression are compressed instead.
+func WithSparseFiles(sparseFiles bool) CreateOption {
+	return func(o *CreateOptions) {
+		o.SparseFiles = sparseFiles
+	}
+}
+
+// WithMaxVolumeSize splits the added files across a multi-volume set whose volumes hold at most maxVolumeSize bytes,
+// e.g. consts.DVD_5_CAPACITY. Files are placed on the first volume with room for them and the directory hierarchy
+// describing all volumes is recorded on the last one, so leave room for it.
+func WithMaxVolumeSize(maxVolumeSize int64) CreateOption {
+	return func(o *CreateOptions) {
+		o.MaxVolumeSize = maxVolumeSize
+	}
+}
+
+// WithCompression writes the image compressed in the given format, compressed.FORMAT_CSO or compressed.FORMAT_ZSTD.
+// Both keep an index of independently compressed blocks so the image can be opened without decompressing it first.
+func WithCompression(format compressed.Format) CreateOption {
+	return func(o *CreateOptions) {
+		o.Compression = format
+	}
+}
+
+// WithEnhancedVolumeDescriptor records an ISO 9660:1999 enhanced volume descriptor (a version 2 supplementary volume
+// descriptor) whose names may be up to 207 bytes of any value, without version suffixes or a directory depth limit.
+func WithEnhancedVolumeDescriptor(enhanced bool) CreateOption {
+	return func(o *CreateOptions) {
+		o.EnhancedVolumeDescriptor = enhanced
+	}
+}
+
 // WithEnableLogging is a temp fix for the fact that we have separate options with helper functions in the same package
 func WithEnableLogging(logger *logging.Logger) CreateOption {
 	return func(o *CreateOptions) {
 		o.Logger = logger
 	}
 }
+
+// WithLogicalBlockSize sets the logical block size in which extents are allocated and addressed. Valid sizes are 512,
+// 1024 and 2048 (the default), volume descriptors and directories still occupy whole 2048 byte sectors.
+func WithLogicalBlockSize(blockSize int) CreateOption {
+	return func(o *CreateOptions) {
+		o.LogicalBlockSize = blockSize
+	}
+}
@@ -2,6 +2,8 @@ package option
 
 import (
 	"github.com/rstms/iso-kit/pkg/logging"
+	"io"
+	"net/http"
 )
 
 type ExtractionProgressCallback func(
@@ -17,9 +19,21 @@ type OpenOptions struct {
 	ReadOnly                   bool
 	PreloadDir                 bool
 	PreferJoliet               bool
+	PreferEnhanced             bool
+	PreferHFS                  bool
 	StripVersionInfo           bool
 	RockRidgeEnabled           bool
 	ElToritoEnabled            bool
+	ZisofsEnabled              bool
+	RestoreXattrs              bool
+	AppleDouble                bool
+	VolumeSet                  []io.ReaderAt
+	BaseOffset                 int64
+	AutoScan                   bool
+	HTTPClient                 *http.Client
+	HTTPBlockSize              int64
+	HTTPReadAhead              int
+	HTTPCacheBlocks            int
 	BootFileExtractLocation    string
 	ExtractionProgressCallback ExtractionProgressCallback
 	Logger                     *logging.Logger
@@ -82,6 +96,22 @@ func WithPreferJoliet(preferJoliet bool) OpenOption {
 	}
 }
 
+// WithPreferEnhanced lists and extracts files from the ISO 9660:1999 enhanced volume descriptor when the image has
+// one. Its identifiers are up to 207 bytes long and carry no version suffix. It takes precedence over WithPreferJoliet.
+func WithPreferEnhanced(preferEnhanced bool) OpenOption {
+	return func(o *OpenOptions) {
+		o.PreferEnhanced = preferEnhanced
+	}
+}
+
+// WithPreferHFS exposes the HFS or HFS+ volume of hybrid Mac/PC discs through ListFiles, ReadFile and Extract instead
+// of the ISO9660 tree. Resource forks are linked as associated files and can be extracted with WithAppleDouble.
+func WithPreferHFS(preferHFS bool) OpenOption {
+	return func(o *OpenOptions) {
+		o.PreferHFS = preferHFS
+	}
+}
+
 func WithRockRidgeEnabled(rockRidgeEnabled bool) OpenOption {
 	return func(o *OpenOptions) {
 		o.RockRidgeEnabled = rockRidgeEnabled
@@ -93,3 +123,80 @@ func WithElToritoEnabled(elToritoEnabled bool) OpenOption {
 		o.ElToritoEnabled = elToritoEnabled
 	}
 }
+
+// WithZisofsEnabled controls whether zisofs compressed files are transparently decompressed when read or extracted.
+func WithZisofsEnabled(zisofsEnabled bool) OpenOption {
+	return func(o *OpenOptions) {
+		o.ZisofsEnabled = zisofsEnabled
+	}
+}
+
+// WithRestoreXattrs controls whether Extract restores AAIP extended attributes and ACLs on the extracted files.
+// Restoring attributes outside the user namespace (e.g. "security.selinux") usually requires root.
+func WithRestoreXattrs(restoreXattrs bool) OpenOption {
+	return func(o *OpenOptions) {
+		o.RestoreXattrs = restoreXattrs
+	}
+}
+
+// WithAppleDouble controls whether Extract writes the Finder info and resource fork of files from Mac-authored discs
+// to AppleDouble "._name" files next to the extracted file.
+func WithAppleDouble(appleDouble bool) OpenOption {
+	return func(o *OpenOptions) {
+		o.AppleDouble = appleDouble
+	}
+}
+
+// WithVolumeSet adds the other volumes of a multi-volume set. File extents are read from the volume named by the
+// volume sequence number of their directory record. Volumes that do not share the volume set identifier of the opened
+// volume are rejected.
+func WithVolumeSet(volumes ...io.ReaderAt) OpenOption {
+	return func(o *OpenOptions) {
+		o.VolumeSet = append(o.VolumeSet, volumes...)
+	}
+}
+
+// WithBaseOffset opens the filesystem starting at the given byte offset of the image instead of its start, e.g. the
+// start of a disk image partition or the end of a firmware header.
+func WithBaseOffset(offset int64) OpenOption {
+	return func(o *OpenOptions) {
+		o.BaseOffset = offset
+	}
+}
+
+// WithAutoScan searches the image for an ISO9660 filesystem at 512 byte boundaries when none is found at the base
+// offset, the first one found is opened and every candidate is logged.
+func WithAutoScan(autoScan bool) OpenOption {
+	return func(o *OpenOptions) {
+		o.AutoScan = autoScan
+	}
+}
+
+// WithHTTPClient sets the client used to fetch images opened from a URL, e.g. one carrying credentials or a timeout.
+func WithHTTPClient(client *http.Client) OpenOption {
+	return func(o *OpenOptions) {
+		o.HTTPClient = client
+	}
+}
+
+// WithHTTPBlockSize sets the size of the blocks in which images opened from a URL are fetched and cached.
+func WithHTTPBlockSize(blockSize int64) OpenOption {
+	return func(o *OpenOptions) {
+		o.HTTPBlockSize = blockSize
+	}
+}
+
+// WithHTTPReadAhead sets the number of blocks following a missed block that are fetched along with it when reading an
+// image opened from a URL, zero fetches only the missed block.
+func WithHTTPReadAhead(blocks int) OpenOption {
+	return func(o *OpenOptions) {
+		o.HTTPReadAhead = blocks
+	}
+}
+
+// WithHTTPCacheBlocks sets the number of blocks of an image opened from a URL that are kept in memory.
+func WithHTTPCacheBlocks(blocks int) OpenOption {
+	return func(o *OpenOptions) {
+		o.HTTPCacheBlocks = blocks
+	}
+}
@@ -0,0 +1,216 @@
+package remote
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DEFAULT_BLOCK_SIZE is the size of the blocks the image is fetched and cached in.
+	DEFAULT_BLOCK_SIZE = 64 * 1024
+	// DEFAULT_READ_AHEAD is the number of blocks following a missed block that are fetched by the same request.
+	// Parsers read directories and file data sequentially so this saves most round trips.
+	DEFAULT_READ_AHEAD = 4
+	// DEFAULT_CACHE_BLOCKS is the number of blocks kept in the cache, the least recently used block is evicted first.
+	DEFAULT_CACHE_BLOCKS = 256
+)
+
+// IsURL reports whether name is an http or https URL rather than a file path.
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// HTTPReader is an io.ReaderAt over a file served by an HTTP server supporting Range requests. The file is fetched in
+// blocks that are cached, a missed block is fetched together with the blocks following it.
+type HTTPReader struct {
+	url         string
+	client      *http.Client
+	size        int64
+	blockSize   int64
+	readAhead   int
+	cacheBlocks int
+
+	mu     sync.Mutex
+	blocks map[int64]*list.Element
+	lru    *list.List
+}
+
+// cachedBlock is a block of the file held in the cache.
+type cachedBlock struct {
+	index int64
+	data  []byte
+}
+
+// NewHTTPReader returns a reader over the file at url. The size of the file is read from the response to a single
+// byte Range request, servers that do not answer it with a partial content response are rejected. A missed block is
+// fetched together with up to readAhead following blocks. A nil client uses http.DefaultClient and a zero block size or
+// cache size uses the default.
+func NewHTTPReader(url string, client *http.Client, blockSize int64, readAhead int, cacheBlocks int) (*HTTPReader, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if blockSize <= 0 {
+		blockSize = DEFAULT_BLOCK_SIZE
+	}
+	if readAhead < 0 {
+		readAhead = 0
+	}
+	if cacheBlocks <= 0 {
+		cacheBlocks = DEFAULT_CACHE_BLOCKS
+	}
+	r := &HTTPReader{
+		url:         url,
+		client:      client,
+		blockSize:   blockSize,
+		readAhead:   min(readAhead, cacheBlocks-1),
+		cacheBlocks: cacheBlocks,
+		blocks:      make(map[int64]*list.Element),
+		lru:         list.New(),
+	}
+
+	resp, err := r.get(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if r.size, err = contentRangeSize(resp.Header.Get("Content-Range")); err != nil {
+		return nil, fmt.Errorf("%s: %w", url, err)
+	}
+	return r, nil
+}
+
+// URL returns the URL the reader fetches from.
+func (r *HTTPReader) URL() string {
+	return r.url
+}
+
+// Size returns the size of the remote file.
+func (r *HTTPReader) Size() int64 {
+	return r.size
+}
+
+func (r *HTTPReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("remote: negative offset")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for n < len(p) && off < r.size {
+		index := off / r.blockSize
+		data, err := r.block(index)
+		if err != nil {
+			return n, err
+		}
+		m := copy(p[n:], data[off-index*r.blockSize:])
+		n += m
+		off += int64(m)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close drops the cached blocks.
+func (r *HTTPReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.blocks = make(map[int64]*list.Element)
+	r.lru.Init()
+	return nil
+}
+
+// block returns a block from the cache, fetching it along with the read ahead blocks that are not cached yet when it
+// is missing.
+func (r *HTTPReader) block(index int64) ([]byte, error) {
+	if e, ok := r.blocks[index]; ok {
+		r.lru.MoveToFront(e)
+		return e.Value.(*cachedBlock).data, nil
+	}
+
+	last := index
+	lastBlock := (r.size - 1) / r.blockSize
+	for last < lastBlock && last-index < int64(r.readAhead) {
+		if _, ok := r.blocks[last+1]; ok {
+			break
+		}
+		last++
+	}
+
+	start := index * r.blockSize
+	end := min((last+1)*r.blockSize, r.size) - 1
+	resp, err := r.get(start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data := make([]byte, end-start+1)
+	if _, err = io.ReadFull(resp.Body, data); err != nil {
+		return nil, fmt.Errorf("failed to read bytes %d-%d of %s: %w", start, end, r.url, err)
+	}
+
+	// Cache the blocks in reverse so the requested block ends up most recently used
+	for i := last; i >= index; i-- {
+		offset := (i - index) * r.blockSize
+		r.insert(i, data[offset:min(offset+r.blockSize, int64(len(data)))])
+	}
+	return r.blocks[index].Value.(*cachedBlock).data, nil
+}
+
+// insert adds a block to the cache, evicting the least recently used block when the cache is full.
+func (r *HTTPReader) insert(index int64, data []byte) {
+	for r.lru.Len() >= r.cacheBlocks {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.blocks, oldest.Value.(*cachedBlock).index)
+	}
+	r.blocks[index] = r.lru.PushFront(&cachedBlock{index: index, data: data})
+}
+
+// get requests the bytes from start to end inclusive and checks that the server answered with exactly that range.
+func (r *HTTPReader) get(start, end int64) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return nil, fmt.Errorf("%s: server does not support range requests", r.url)
+		}
+		return nil, fmt.Errorf("%s: %s", r.url, resp.Status)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-%d/", start, end)) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: unexpected content range %q for bytes %d-%d", r.url, resp.Header.Get("Content-Range"), start, end)
+	}
+	return resp, nil
+}
+
+// contentRangeSize returns the complete length of a Content-Range header value such as "bytes 0-0/1234".
+func contentRangeSize(contentRange string) (int64, error) {
+	_, total, ok := strings.Cut(contentRange, "/")
+	if !ok || total == "*" {
+		return 0, fmt.Errorf("missing file size in content range %q", contentRange)
+	}
+	size, err := strconv.ParseInt(total, 10, 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid file size in content range %q", contentRange)
+	}
+	return size, nil
+}
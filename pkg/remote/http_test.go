@@ -0,0 +1,64 @@
+package remote
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPReader(t *testing.T) {
+	content := make([]byte, 10*1024+123)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.ServeContent(w, r, "image.iso", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	r, err := NewHTTPReader(server.URL, nil, 1024, 2, 4)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(content)), r.Size())
+
+	// A read spanning blocks is served by one request fetching the read ahead blocks too
+	buf := make([]byte, 1500)
+	n, err := r.ReadAt(buf, 1000)
+	require.NoError(t, err)
+	require.Equal(t, 1500, n)
+	require.Equal(t, content[1000:2500], buf)
+	require.Equal(t, int32(2), requests.Load())
+
+	// Read ahead blocks are cached
+	_, err = r.ReadAt(buf[:100], 2900)
+	require.NoError(t, err)
+	require.Equal(t, content[2900:3000], buf[:100])
+	require.Equal(t, int32(2), requests.Load())
+
+	// Reads at the end of the file are short
+	n, err = r.ReadAt(buf, int64(len(content))-100)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, 100, n)
+	require.Equal(t, content[len(content)-100:], buf[:n])
+
+	all, err := io.ReadAll(io.NewSectionReader(r, 0, r.Size()))
+	require.NoError(t, err)
+	require.Equal(t, content, all)
+}
+
+func TestHTTPReader_NoRanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("no ranges here"))
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPReader(server.URL, nil, 0, DEFAULT_READ_AHEAD, 0)
+	require.ErrorContains(t, err, "range requests")
+}
@@ -87,11 +87,21 @@ func (U UDF) HasJoliet() bool {
 	panic("implement me")
 }
 
+func (U UDF) HasEnhanced() bool {
+	//TODO implement me
+	panic("implement me")
+}
+
 func (U UDF) HasRockRidge() bool {
 	//TODO implement me
 	panic("implement me")
 }
 
+func (U UDF) RockRidgeVersion() string {
+	//TODO implement me
+	panic("implement me")
+}
+
 func (U UDF) HasElTorito() bool {
 	//TODO implement me
 	panic("implement me")
@@ -107,6 +117,16 @@ func (U UDF) GetSystemID() string {
 	panic("implement me")
 }
 
+func (U UDF) GetVolumeSetSize() uint16 {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (U UDF) GetVolumeSequenceNumber() uint16 {
+	//TODO implement me
+	panic("implement me")
+}
+
 func (U UDF) GetVolumeSize() uint32 {
 	//TODO implement me
 	panic("implement me")
@@ -132,6 +152,16 @@ func (U UDF) ReadFile(path string) ([]byte, error) {
 	panic("implement me")
 }
 
+func (U UDF) OpenFile(path string) (io.ReadSeekCloser, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (U UDF) FS() (*filesystem.FS, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
 func (U UDF) AddFile(path string, data []byte) error {
 	//TODO implement me
 	panic("implement me")
@@ -146,6 +176,11 @@ func (U UDF) CreateDirectories(path string) error {
 	panic("implement me")
 }
 
+func (U UDF) AddPartition(systemID, partitionID string, location, size uint32, systemUse []byte) error {
+	//TODO implement me
+	panic("implement me")
+}
+
 func (U UDF) Extract(path string) error {
 	panic("implement me")
 }